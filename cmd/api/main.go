@@ -3,20 +3,28 @@ package main
 import (
 	"log"
 	"os"
+	"regexp"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/joho/godotenv"
+	"github.com/valyala/fasthttp"
 
 	"estepage_backend/internal/controller"
 	"estepage_backend/internal/middleware"
 	"estepage_backend/internal/model"
+	"estepage_backend/pkg/config"
 	"estepage_backend/pkg/cron"
 	"estepage_backend/pkg/database"
 	"estepage_backend/pkg/email"
+	"estepage_backend/pkg/subscription"
+	"estepage_backend/pkg/utils/antispam"
 	"estepage_backend/pkg/utils/cloudflare"
 	"estepage_backend/pkg/utils/location"
+	"estepage_backend/pkg/utils/storage"
+	"estepage_backend/pkg/utils/validation"
 )
 
 func setupRoutes(app *fiber.App) {
@@ -29,17 +37,30 @@ func setupRoutes(app *fiber.App) {
 	auth.Post("/request-reset", controller.RequestPasswordReset)
 	auth.Post("/reset-password", controller.ResetPassword)
 
+	// Herkese açık formların spam koruma token'ı
+	api.Get("/forms/token", controller.GetFormToken)
+
 	// Leads
-	api.Post("/properties/:property_id/leads", controller.CreatePropertyLead)
-	api.Post("/agents/:user_id/leads", controller.CreateProfileLead)
+	api.Post("/properties/:property_id/leads",
+		middleware.FormIPRateLimit(), middleware.FormTargetRateLimit("property_id"), controller.CreatePropertyLead)
+	api.Post("/agents/:user_id/leads",
+		middleware.FormIPRateLimit(), middleware.FormTargetRateLimit("user_id"), controller.CreateProfileLead)
 
 	// Public Properties Routes
 	publicProps := api.Group("/p")
 	publicProps.Get("/:username", controller.ListUserProperties)
+	publicProps.Get("/:username/feed.xml", controller.GetUserPropertyFeed("xml"))
+	publicProps.Get("/:username/feed.json", controller.GetUserPropertyFeed("json"))
+	publicProps.Get("/:username/feed.rss", controller.GetUserPropertyFeed("rss"))
 	publicProps.Get("/:username/:property_slug", controller.GetPropertyBySlug)
 
+	// Public search (tüm emlakçıların ilanları)
+	search := api.Group("/search")
+	search.Get("/properties", controller.SearchProperties)
+
 	// Public newsletter subscription
-	api.Post("/agents/:user_id/subscribe", controller.PublicSubscribe)
+	api.Post("/agents/:user_id/subscribe",
+		middleware.FormIPRateLimit(), controller.PublicSubscribe)
 
 	// Protected newsletter routes (emlakçı kendi abonelerini görüntüler)
 	protectedNewsletter := api.Group("/newsletter", middleware.AuthMiddleware())
@@ -53,10 +74,22 @@ func setupRoutes(app *fiber.App) {
 	// Protected Property Routes with subscription checks
 	properties := protected.Group("/properties")
 	properties.Get("/my", controller.ListMyProperties)
+	properties.Post("/imports", controller.ImportProperties)
+	properties.Get("/imports/:id", controller.GetPropertyImport)
 	properties.Post("/", middleware.CheckSubscriptionLimit(), controller.CreateProperty)
 	properties.Put("/:id", middleware.CheckPropertyOwnership(), controller.UpdateProperty)
+	properties.Put("/:id/publication", middleware.CheckPropertyOwnership(), controller.UpdatePropertyPublication)
+	properties.Get("/:id/revisions", middleware.CheckPropertyOwnership(), controller.ListPropertyRevisions)
+	properties.Post("/:id/revisions/:rev/restore", middleware.CheckPropertyOwnership(), controller.RestorePropertyRevision)
 	properties.Delete("/:id", middleware.CheckPropertyOwnership(), controller.DeleteProperty)
+	properties.Patch("/:id/images", middleware.CheckPropertyOwnership(), controller.UpdatePropertyImages)
 	properties.Post("/:property_id/images", middleware.CheckImageLimit(), controller.UploadPropertyImage)
+	properties.Post("/:property_id/images/uploads", middleware.CheckImageLimit(), controller.RequestImageUploads)
+	properties.Post("/:property_id/images/uploads/confirm", controller.ConfirmImageUploads)
+	properties.Get("/:id/attachments", middleware.CheckPropertyOwnership(), controller.ListPropertyAttachments)
+	properties.Post("/:id/attachments", middleware.CheckPropertyOwnership(), controller.CreatePropertyAttachment)
+	properties.Patch("/:id/attachments", middleware.CheckPropertyOwnership(), controller.UpdatePropertyAttachments)
+	properties.Delete("/:id/attachments/:attachment_id", middleware.CheckPropertyOwnership(), controller.DeletePropertyAttachment)
 	properties.Delete("/images/:image_id", middleware.CheckPropertyOwnership(), controller.DeletePropertyImage)
 
 	// Dashboard routes
@@ -71,6 +104,9 @@ func setupRoutes(app *fiber.App) {
 	settings.Get("/profile", controller.GetProfile)
 	settings.Put("/profile", controller.UpdateProfile)
 	settings.Post("/avatar", cloudflare.UploadAvatarHandler)
+	settings.Get("/watermark", controller.GetWatermarkSettings)
+	settings.Put("/watermark", controller.UpdateWatermarkSettings)
+	settings.Post("/watermark/logo", controller.UploadWatermarkLogo)
 	settings.Post("/change-password", controller.ChangePassword)
 	settings.Get("/login-history", controller.GetLoginHistory)
 	settings.Get("/invoices", controller.GetInvoices)
@@ -78,8 +114,36 @@ func setupRoutes(app *fiber.App) {
 	// Protected lead routes
 	leads := protected.Group("/leads")
 	leads.Get("/", controller.GetMyLeads)
+	leads.Get("/board", controller.GetLeadBoard)
+	leads.Get("/pipeline", controller.GetLeadPipeline)
+	leads.Post("/pipeline/stages", controller.CreateLeadStage)
+	leads.Put("/pipeline/stages/:stage_id", controller.UpdateLeadStage)
+	leads.Delete("/pipeline/stages/:stage_id", controller.DeleteLeadStage)
+	leads.Put("/pipeline/order", controller.ReorderLeadStages)
+
+	// Ziyaretçilere otomatik yanıt şablonları (genel ve ilana özel)
+	autoReplies := leads.Group("/auto-replies", middleware.CheckSubscriptionFeature(subscription.LeadForm))
+	autoReplies.Get("/", controller.GetLeadAutoReplies)
+	autoReplies.Put("/global", controller.SaveLeadAutoReply)
+	autoReplies.Put("/properties/:property_id", controller.SaveLeadAutoReply)
+	autoReplies.Post("/preview", controller.PreviewLeadAutoReply)
+	autoReplies.Delete("/:id", controller.DeleteLeadAutoReply)
+
 	leads.Put("/:id/status", controller.UpdateLeadStatus)
 	leads.Put("/:id/read", controller.MarkLeadAsRead)
+	leads.Get("/reminders", controller.GetMyLeadReminders)
+	leads.Put("/reminders/:reminder_id", controller.UpdateLeadReminder)
+	leads.Delete("/reminders/:reminder_id", controller.DeleteLeadReminder)
+	leads.Get("/:id/timeline", controller.GetLeadTimeline)
+	leads.Post("/:id/activities", controller.CreateLeadActivity)
+	leads.Delete("/:id/activities/:activity_id", controller.DeleteLeadActivity)
+	leads.Post("/:id/reminders", controller.CreateLeadReminder)
+
+	contacts := protected.Group("/contacts")
+	contacts.Get("/", controller.ListContacts)
+	contacts.Get("/:id", controller.GetContact)
+	contacts.Post("/:id/merge/:duplicate_id", controller.MergeContact)
+	contacts.Delete("/:id/merge-candidates/:duplicate_id", controller.DismissContactMergeCandidate)
 
 	// Location routes
 	api.Get("/locations/countries", controller.GetLocationData)
@@ -101,6 +165,46 @@ func setupRoutes(app *fiber.App) {
 
 	// Stripe webhook
 	api.Post("/webhook", controller.HandleStripeWebhook)
+
+	// Admin routes
+	admin := api.Group("/admin", middleware.AuthMiddleware(), middleware.AdminOnly())
+	admin.Get("/storage/orphans", controller.GetOrphanedObjects)
+	admin.Get("/images/duplicates", controller.GetCrossAccountDuplicateImages)
+
+	// Local depolama kullanılıyorsa dosyaları API üzerinden sun
+	if local, ok := storage.GlobalStorage.(*storage.LocalStorage); ok {
+		app.Get(storage.LocalRoutePrefix+"/*", local.ServeObject)
+		app.Put(storage.LocalRoutePrefix+"/*", local.UploadObject)
+	}
+	if private, ok := storage.PrivateStorage.(*storage.LocalStorage); ok {
+		app.Get(storage.LocalPrivateRoutePrefix+"/*", private.ServeSignedObject)
+	}
+}
+
+var (
+	attachmentUploadPath = regexp.MustCompile(`^/api/properties/[^/]+/attachments/?$`)
+	imageUploadPath      = regexp.MustCompile(`^/api/properties/[^/]+/images/?$`)
+)
+
+// requestBodyLimit gövde okunmadan önce route'a göre gövde limitini belirler. Sıfır
+// değer sunucunun varsayılan limitini (fiber.DefaultBodyLimit) kullanır.
+func requestBodyLimit(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+	path, _, _ := strings.Cut(string(header.RequestURI()), "?")
+	method := string(header.Method())
+
+	switch {
+	case method == fiber.MethodPost && attachmentUploadPath.MatchString(path):
+		// Kullanıcının kendi plan limiti handler'da uygulanır
+		limit := subscription.MaxAttachmentSizeAnyPlan() + controller.AttachmentFormOverhead
+		return fasthttp.RequestConfig{MaxRequestBodySize: int(limit)}
+	case method == fiber.MethodPost && imageUploadPath.MatchString(path):
+		// Resim boyutu pipeline'da ayrıca doğrulanır
+		return fasthttp.RequestConfig{MaxRequestBodySize: validation.MaxImageSize + controller.ImageFormOverhead}
+	case method == fiber.MethodPut && strings.HasPrefix(path, storage.LocalRoutePrefix+"/"):
+		// Local depolamaya imzalı URL ile doğrudan resim yükleme
+		return fasthttp.RequestConfig{MaxRequestBodySize: validation.MaxImageSize}
+	}
+	return fasthttp.RequestConfig{}
 }
 
 func main() {
@@ -113,11 +217,35 @@ func main() {
 	}
 	log.Printf("Email service initialized with API key: %s", os.Getenv("RESEND_API_KEY"))
 
+	cfg := config.Load()
+	if cfg.Storage.SigningSecret != "" && cfg.Storage.SigningSecret == cfg.JWT.Secret {
+		log.Fatal("STORAGE_SIGNING_SECRET must be different from JWT_SECRET")
+	}
+	if err := storage.Init(cfg.Storage); err != nil {
+		log.Fatal("Could not initialize storage:", err)
+	}
+	if cfg.Spam.TokenSecret == cfg.JWT.Secret {
+		log.Fatal("FORM_TOKEN_SECRET must be different from JWT_SECRET")
+	}
+	if err := antispam.Init(cfg.Spam); err != nil {
+		log.Fatal("Could not initialize spam protection:", err)
+	}
+	// Güvenilmeyen kaynaktan gelen proxy başlığı IP taklidine izin verir
+	if cfg.Server.ProxyHeader != "" && len(cfg.Server.TrustedProxies) == 0 {
+		log.Fatal("TRUSTED_PROXIES must be set when PROXY_HEADER is used")
+	}
+
 	controller.InitAuthController()
 	controller.InitLeadController()
 	cron.InitNewsletterCron()
 	controller.InitSubscriptionController()
 	cron.InitSubscriptionExpiryCron()
+	cron.InitPropertyPublicationCron()
+	cron.InitPriceDropCron()
+	cron.InitPendingUploadCron()
+	cron.InitOrphanObjectCron()
+	cron.InitWatermarkCron()
+	cron.InitLeadReminderCron()
 
 	if err := location.Init(); err != nil {
 		log.Fatal("Could not initialize location data:", err)
@@ -137,16 +265,79 @@ func main() {
 		&model.PropertyImage{},
 		&model.PropertyView{},
 		&model.PropertyStats{},
+		&model.LeadStage{},
+		&model.Contact{},
+		&model.ContactMergeCandidate{},
 		&model.Lead{},
+		&model.LeadActivity{},
+		&model.LeadReminder{},
+		&model.LeadAutoReply{},
 		&model.NewsletterSubscriber{},
 		&model.LoginHistory{},
 		&model.PropertyFeature{},
+		&model.PropertyRevision{},
+		&model.PropertyPriceHistory{},
+		&model.PropertyImport{},
+		&model.PendingUpload{},
+		&model.PropertyAttachment{},
 	)
 	if err != nil {
 		log.Printf("Migration warning: %v", err)
 	}
 
+	if err := model.EnsurePropertySearchIndex(database.GetDB()); err != nil {
+		log.Printf("Search index warning: %v", err)
+	}
+
+	if err := model.FailInterruptedImports(database.GetDB()); err != nil {
+		log.Printf("Import cleanup warning: %v", err)
+	}
+
+	// Aşaması olmayan (pipeline öncesi) lead'leri varsayılan pipeline'a taşı
+	if err := model.AssignUnstagedLeads(database.GetDB(), 0); err != nil {
+		log.Printf("Lead pipeline migration warning: %v", err)
+	}
+
+	// Herkese açık bucket'ta kalmış eski filigransız kopyaları özel depolamaya taşı
+	go func() {
+		moved, err := cloudflare.MoveLegacyOriginals()
+		if err != nil {
+			log.Printf("Original image migration warning: %v", err)
+		}
+		if moved > 0 {
+			log.Printf("Moved %d unwatermarked originals to private storage", moved)
+		}
+	}()
+
+	// Eski sürümlerde herkese açık bucket'a yazılmış gizli ekleri özel depolamaya taşı
+	go func() {
+		attachments, err := model.LegacyPrivateAttachmentFiles(database.GetDB())
+		if err != nil {
+			log.Printf("Private attachment migration warning: %v", err)
+			return
+		}
+		moved, err := cloudflare.MoveLegacyPrivateAttachments(attachments)
+		if err != nil {
+			log.Printf("Private attachment migration warning: %v", err)
+		}
+		if moved > 0 {
+			log.Printf("Moved %d private attachments to private storage", moved)
+		}
+	}()
+
+	// Kişiye bağlı olmayan (eski) lead'leri arka planda kişileriyle eşleştir
+	go func() {
+		if err := model.LinkLeadsToContacts(database.GetDB()); err != nil {
+			log.Printf("Contact migration warning: %v", err)
+		}
+	}()
+
 	app := fiber.New(fiber.Config{
+		// Proxy arkasında gerçek istemci IP'si (rate limit için); başlık sadece
+		// güvenilen proxy'lerden gelen isteklerde okunur
+		ProxyHeader:             cfg.Server.ProxyHeader,
+		EnableTrustedProxyCheck: cfg.Server.ProxyHeader != "",
+		TrustedProxies:          cfg.Server.TrustedProxies,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": err.Error(),
@@ -154,6 +345,9 @@ func main() {
 		},
 	})
 
+	// Varsayılan 4MB gövde limiti sadece büyük dosya kabul eden route'larda yükseltilir
+	app.Server().HeaderReceived = requestBodyLimit
+
 	app.Use(logger.New())
 	app.Use(cors.New())
 
@@ -0,0 +1,266 @@
+// cmd/backfill-images/main.go
+//
+// Mevcut ilan resimlerini güncel pipeline ile yeniden işler (varyantlar, algısal hash,
+// yer tutucular, filigran). Varsayılan olarak sadece eksik bilgisi olan resimler işlenir:
+//
+//	go run ./cmd/backfill-images -batch 100 -concurrency 4
+//
+// İlerleme her batch sonunda checkpoint dosyasına yazılır; komut yarıda kesilirse
+// aynı komutla kaldığı yerden devam eder. Baştan başlamak (örn. başarısız resimleri
+// tekrar denemek) için -reset kullanılır.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/config"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/utils/cloudflare"
+	imageutil "estepage_backend/pkg/utils/image"
+	"estepage_backend/pkg/utils/storage"
+)
+
+type checkpoint struct {
+	LastID    uint      `json:"last_id"` // Bu ID'ye kadar (dahil) tüm resimler ele alındı
+	Processed int       `json:"processed"`
+	Skipped   int       `json:"skipped"` // Depolamada olmayan (harici) resimler
+	Failed    []uint    `json:"failed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func loadCheckpoint(file string) (*checkpoint, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return &checkpoint{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// save dosyayı önce geçici dosyaya yazar, yarım kalan yazma checkpoint'i bozmasın
+func (cp *checkpoint) save(file string) error {
+	cp.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+type backfiller struct {
+	db      *gorm.DB
+	dryRun  bool
+	mu      sync.Mutex
+	cp      *checkpoint
+	loaded  map[uint]bool
+	marks   map[uint]*imageutil.Watermark
+	markErr map[uint]error
+}
+
+// watermark kullanıcının filigranını bir kere yükler
+func (b *backfiller) watermark(user *model.User) (*imageutil.Watermark, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.loaded[user.ID] {
+		b.marks[user.ID], b.markErr[user.ID] = cloudflare.UserWatermark(user)
+		b.loaded[user.ID] = true
+	}
+	return b.marks[user.ID], b.markErr[user.ID]
+}
+
+func (b *backfiller) record(img *model.PropertyImage, err error, skipped bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case skipped:
+		b.cp.Skipped++
+	case err != nil:
+		log.Printf("Error reprocessing image %d: %v", img.ID, err)
+		b.cp.Failed = append(b.cp.Failed, img.ID)
+	default:
+		b.cp.Processed++
+	}
+}
+
+func (b *backfiller) process(img *model.PropertyImage) {
+	if !cloudflare.IsHostedURL(img.URL) {
+		b.record(img, nil, true)
+		return
+	}
+	if b.dryRun {
+		log.Printf("Would reprocess image %d (%s)", img.ID, img.URL)
+		b.record(img, nil, false)
+		return
+	}
+
+	user := &img.Property.User
+	watermark, err := b.watermark(user)
+	if err != nil {
+		b.record(img, err, false)
+		return
+	}
+
+	result, err := cloudflare.ReprocessImage(img, user.Username, img.Property.Slug, watermark)
+	if err != nil {
+		b.record(img, err, false)
+		return
+	}
+
+	variants, err := json.Marshal(result.Variants)
+	if err != nil {
+		b.record(img, err, false)
+		return
+	}
+
+	update := b.db.Model(&model.PropertyImage{}).Where("id = ?", img.ID).Updates(map[string]interface{}{
+		"url":               result.URL,
+		"thumbnail_url":     result.ThumbnailURL,
+		"cloudflare_id":     result.CloudflareID,
+		"size":              result.Size,
+		"width":             result.Width,
+		"height":            result.Height,
+		"variants":          variants,
+		"perceptual_hash":   result.PerceptualHash,
+		"blur_hash":         result.Placeholder.BlurHash,
+		"lqip":              result.Placeholder.LQIP,
+		"dominant_color":    result.Placeholder.DominantColor,
+		"watermark_version": user.WatermarkVersion,
+	})
+	if update.Error == nil && update.RowsAffected == 0 {
+		// Resim bu arada silinmiş/ilan güncellenmiş; yeni dosyalar depolama temizliğinde raporlanır
+		update.Error = errors.New("image no longer exists")
+	}
+	b.record(img, update.Error, false)
+}
+
+func main() {
+	batchSize := flag.Int("batch", 100, "images fetched per batch")
+	concurrency := flag.Int("concurrency", 4, "images processed in parallel")
+	checkpointFile := flag.String("checkpoint", "backfill-images.checkpoint.json", "progress file used to resume")
+	all := flag.Bool("all", false, "reprocess every image, not only those missing variants or placeholders")
+	dryRun := flag.Bool("dry-run", false, "list images that would be reprocessed without changing anything")
+	reset := flag.Bool("reset", false, "ignore the existing checkpoint and start from the first image")
+	flag.Parse()
+
+	if *batchSize < 1 || *concurrency < 1 {
+		log.Fatal("batch and concurrency must be at least 1")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("No .env file loaded: %v", err)
+	}
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL is not set")
+	}
+	database.InitDB(dbURL)
+
+	if err := storage.Init(config.Load().Storage); err != nil {
+		log.Fatal("Could not initialize storage:", err)
+	}
+
+	cp := &checkpoint{}
+	if !*reset {
+		var err error
+		if cp, err = loadCheckpoint(*checkpointFile); err != nil {
+			log.Fatal("Could not read checkpoint:", err)
+		}
+		if cp.LastID > 0 {
+			log.Printf("Resuming after image %d (%d processed, %d skipped, %d failed)",
+				cp.LastID, cp.Processed, cp.Skipped, len(cp.Failed))
+		}
+	}
+
+	// Ctrl+C mevcut batch bittikten sonra durdurur
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	b := &backfiller{
+		db:      database.GetDB(),
+		dryRun:  *dryRun,
+		cp:      cp,
+		loaded:  map[uint]bool{},
+		marks:   map[uint]*imageutil.Watermark{},
+		markErr: map[uint]error{},
+	}
+
+	for ctx.Err() == nil {
+		query := b.db.Select("property_images.*").
+			Joins("JOIN properties ON properties.id = property_images.property_id AND properties.deleted_at IS NULL").
+			Where("property_images.id > ?", cp.LastID)
+		if !*all {
+			query = query.Where("property_images.variants IS NULL OR property_images.blur_hash IS NULL OR property_images.blur_hash = ''")
+		}
+
+		var images []model.PropertyImage
+		if err := query.Order("property_images.id ASC").
+			Limit(*batchSize).
+			Preload("Property.User").
+			Find(&images).Error; err != nil {
+			log.Fatal("Could not fetch images:", err)
+		}
+		if len(images) == 0 {
+			break
+		}
+
+		sem := make(chan struct{}, *concurrency)
+		var wg sync.WaitGroup
+		for i := range images {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(img *model.PropertyImage) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				b.process(img)
+			}(&images[i])
+		}
+		wg.Wait()
+
+		cp.LastID = images[len(images)-1].ID
+		if *dryRun {
+			continue
+		}
+		if err := cp.save(*checkpointFile); err != nil {
+			log.Fatal("Could not write checkpoint:", err)
+		}
+		log.Printf("Checkpoint at image %d: %d processed, %d skipped, %d failed",
+			cp.LastID, cp.Processed, cp.Skipped, len(cp.Failed))
+	}
+
+	if ctx.Err() != nil {
+		log.Printf("Interrupted; run again to resume after image %d", cp.LastID)
+		return
+	}
+	log.Printf("Backfill complete: %d processed, %d skipped, %d failed", cp.Processed, cp.Skipped, len(cp.Failed))
+	if len(cp.Failed) > 0 {
+		log.Printf("Failed images: %v", cp.Failed)
+	}
+}
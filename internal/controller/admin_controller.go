@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/utils/storage"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetOrphanedObjects temizlik cron'unun sileceği dosyaları silmeden raporlar (dry-run).
+// ?username= ile tek bir kullanıcı taranabilir.
+func GetOrphanedObjects(c *fiber.Ctx) error {
+	if storage.GlobalStorage == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Storage is not configured",
+		})
+	}
+
+	report, err := model.FindOrphanedObjects(database.GetDB(), storage.GlobalStorage, c.Query("username"), time.Now())
+	if err != nil {
+		log.Printf("Error building orphan report: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not build orphaned object report",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"report":       report,
+		"grace_period": model.OrphanGracePeriod.String(),
+		"dry_run":      true,
+	})
+}
+
+// GetCrossAccountDuplicateImages farklı hesapların ilanlarında tekrar eden fotoğrafları listeler
+// (kopyalanmış ilanların incelenmesi için)
+func GetCrossAccountDuplicateImages(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	duplicates, err := model.FindCrossAccountDuplicates(database.GetDB(), limit, offset)
+	if err != nil {
+		log.Printf("Error finding duplicate images: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not build duplicate image report",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"duplicates": duplicates,
+		"limit":      limit,
+		"offset":     offset,
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"estepage_backend/pkg/database"
 	"estepage_backend/pkg/email"
 	"estepage_backend/pkg/utils/jwt"
+	"estepage_backend/pkg/utils/pagination"
 	"log"
 	"strings"
 	"time"
@@ -290,24 +291,47 @@ func ResetPassword(c *fiber.Ctx) error {
 	})
 }
 
+// Login history listesinde izin verilen sıralamalar
+var loginHistoryListOptions = pagination.Options{
+	IDColumn:     "login_histories.id",
+	DefaultSort:  "-created_at",
+	DefaultLimit: 10, // Varsayılan olarak son 10 giriş
+	SortKeys: map[string]string{
+		"created_at": "login_histories.created_at",
+	},
+}
+
 // Yeni endpoint: Login history'yi getir
 func GetLoginHistory(c *fiber.Ctx) error {
 	claims := c.Locals("user").(*jwt.Claims)
 
-	var loginHistory []LoginHistoryResponse
-	if err := database.GetDB().Model(&model.LoginHistory{}).
-		Select("device, location, created_at").
-		Where("user_id = ?", claims.UserID).
-		Order("created_at DESC").
-		Limit(10). // Son 10 giriş
-		Find(&loginHistory).Error; err != nil {
+	params, err := pagination.Parse(c, loginHistoryListOptions)
+	if err != nil {
+		return respondListParamError(c, err)
+	}
+
+	ids, meta, err := params.Paginate(database.GetDB().Model(&model.LoginHistory{}).
+		Where("user_id = ?", claims.UserID))
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not fetch login history",
 		})
 	}
 
+	loginHistory := []LoginHistoryResponse{}
+	if len(ids) > 0 {
+		if err := params.Ordered(database.GetDB().Model(&model.LoginHistory{}), ids).
+			Select("device, location, created_at").
+			Find(&loginHistory).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not fetch login history",
+			})
+		}
+	}
+
 	return c.JSON(fiber.Map{
 		"login_history": loginHistory,
+		"pagination":    meta,
 	})
 }
 
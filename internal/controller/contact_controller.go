@@ -0,0 +1,264 @@
+package controller
+
+import (
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/utils/jwt"
+	"estepage_backend/pkg/utils/pagination"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Kişi listesinde izin verilen sıralama ve alan seçimi
+var contactListOptions = pagination.Options{
+	IDColumn:    "contacts.id",
+	DefaultSort: "-last_seen_at",
+	SortKeys: map[string]string{
+		"created_at":   "contacts.created_at",
+		"last_seen_at": "contacts.last_seen_at",
+		"name":         "contacts.name",
+	},
+	Fields: []string{
+		"CreatedAt", "UpdatedAt", "user_id", "name", "email", "phone", "last_seen_at", "lead_count",
+	},
+}
+
+// fillContactLeadCounts kişilerin lead sayılarını tek sorguyla doldurur
+func fillContactLeadCounts(db *gorm.DB, contacts []model.Contact) error {
+	if len(contacts) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(contacts))
+	for i, contact := range contacts {
+		ids[i] = contact.ID
+	}
+
+	var counts []struct {
+		ContactID uint
+		Count     int64
+	}
+	if err := db.Model(&model.Lead{}).
+		Select("contact_id, COUNT(*) AS count").
+		Where("contact_id IN ?", ids).
+		Group("contact_id").
+		Scan(&counts).Error; err != nil {
+		return err
+	}
+
+	byID := make(map[uint]int64, len(counts))
+	for _, row := range counts {
+		byID[row.ContactID] = row.Count
+	}
+	for i := range contacts {
+		contacts[i].LeadCount = byID[contacts[i].ID]
+	}
+	return nil
+}
+
+// ListContacts emlakçının kişilerini listeler. q parametresi ad, e-posta ve telefonda arar.
+func ListContacts(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	db := database.GetDB()
+
+	params, err := pagination.Parse(c, contactListOptions)
+	if err != nil {
+		return respondListParamError(c, err)
+	}
+
+	query := db.Model(&model.Contact{}).Where("user_id = ?", claims.UserID)
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("name ILIKE ? OR email ILIKE ? OR phone ILIKE ?", like, like, like)
+	}
+
+	ids, meta, err := params.Paginate(query)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch contacts",
+		})
+	}
+
+	contacts := []model.Contact{}
+	if len(ids) > 0 {
+		if err := params.Ordered(db, ids).Find(&contacts).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not fetch contacts",
+			})
+		}
+	}
+
+	if err := fillContactLeadCounts(db, contacts); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch contacts",
+		})
+	}
+
+	projected, err := params.Project(contacts)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch contacts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"contacts":   projected,
+		"pagination": meta,
+	})
+}
+
+// GetContact kişinin tüm başvurularını, ilgilendiği ilanları ve bülten üyeliğini döner
+func GetContact(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	db := database.GetDB()
+
+	var contact model.Contact
+	if err := db.Where("id = ? AND user_id = ?", c.Params("id"), claims.UserID).First(&contact).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Contact not found",
+		})
+	}
+
+	leads := []model.Lead{}
+	if err := db.Where("contact_id = ? AND user_id = ?", contact.ID, claims.UserID).
+		Preload("Stage").
+		Order("created_at DESC").
+		Find(&leads).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch contact leads",
+		})
+	}
+	contact.LeadCount = int64(len(leads))
+
+	propertyIDs := []uint{}
+	seen := map[uint]bool{}
+	for _, lead := range leads {
+		if lead.PropertyID != 0 && !seen[lead.PropertyID] {
+			seen[lead.PropertyID] = true
+			propertyIDs = append(propertyIDs, lead.PropertyID)
+		}
+	}
+
+	properties := []model.Property{}
+	if len(propertyIDs) > 0 {
+		if err := db.Where("id IN ? AND user_id = ?", propertyIDs, claims.UserID).
+			Preload("Images", func(db *gorm.DB) *gorm.DB {
+				return db.Order("property_images.order ASC")
+			}).
+			Find(&properties).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not fetch contact properties",
+			})
+		}
+	}
+
+	newsletter := fiber.Map{"subscribed": false}
+	if contact.Email != "" {
+		var subscriber model.NewsletterSubscriber
+		err := db.Where("user_id = ? AND LOWER(email) = ?", claims.UserID, contact.Email).
+			Order("subscribed_at ASC").
+			First(&subscriber).Error
+		switch {
+		case err == nil:
+			newsletter = fiber.Map{
+				"subscribed":    true,
+				"subscribed_at": subscriber.SubscribedAt,
+				"source":        subscriber.Source,
+			}
+		case err != gorm.ErrRecordNotFound:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not fetch newsletter membership",
+			})
+		}
+	}
+
+	mergeCandidates, err := model.ContactMergeCandidates(db, claims.UserID, contact.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch merge candidates",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"contact":          contact,
+		"leads":            leads,
+		"properties":       properties,
+		"newsletter":       newsletter,
+		"merge_candidates": mergeCandidates,
+	})
+}
+
+// MergeContact duplicate_id kişisini bu kişiyle birleştirir; lead'leri bu kişiye taşınır
+func MergeContact(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	contactID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid contact ID",
+		})
+	}
+	duplicateID, err := c.ParamsInt("duplicate_id")
+	if err != nil || duplicateID == contactID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid contact ID",
+		})
+	}
+
+	contact, err := model.MergeContacts(database.GetDB(), claims.UserID, uint(contactID), uint(duplicateID))
+	if err == gorm.ErrRecordNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Contact not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not merge contacts",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Contacts merged successfully",
+		"contact": contact,
+	})
+}
+
+// DismissContactMergeCandidate birleştirme önerisini reddeder; kişiler ayrı kalır
+func DismissContactMergeCandidate(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	contactID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid contact ID",
+		})
+	}
+	duplicateID, err := c.ParamsInt("duplicate_id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid contact ID",
+		})
+	}
+
+	// Reddedilen öneri soft delete ile tutulur; aynı çift tekrar önerilmez
+	result := database.GetDB().
+		Where("user_id = ? AND contact_id = ? AND duplicate_id = ?",
+			claims.UserID, min(contactID, duplicateID), max(contactID, duplicateID)).
+		Delete(&model.ContactMergeCandidate{})
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not dismiss merge candidate",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Merge candidate not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Merge candidate dismissed",
+	})
+}
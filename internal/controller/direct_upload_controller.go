@@ -0,0 +1,426 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/subscription"
+	"estepage_backend/pkg/utils/cloudflare"
+	"estepage_backend/pkg/utils/jwt"
+	"estepage_backend/pkg/utils/storage"
+	"estepage_backend/pkg/utils/validation"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// UploadURLExpiry imzalı PUT URL'inin geçerlilik süresi
+	UploadURLExpiry = 15 * time.Minute
+	// PendingUploadExpiry onaylanmayan yüklemelerin silinmeden önce bekletildiği süre
+	PendingUploadExpiry = time.Hour
+)
+
+type UploadRequestFile struct {
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+type UploadRequestInput struct {
+	Files []UploadRequestFile `json:"files"`
+}
+
+type ConfirmUploadsInput struct {
+	UploadIDs []uint `json:"upload_ids"`
+}
+
+type PresignedUpload struct {
+	ID        uint              `json:"id"`
+	FileName  string            `json:"file_name"`
+	UploadURL string            `json:"upload_url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"` // İmzaya dahil; istemci aynen göndermeli
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+type UploadConfirmError struct {
+	UploadID uint   `json:"upload_id"`
+	Error    string `json:"error"`
+}
+
+// remainingImageSlots plan limitine göre ilana eklenebilecek resim sayısı (onay bekleyenler dahil)
+func remainingImageSlots(db *gorm.DB, userID, propertyID uint, includePending bool) (int, error) {
+	var imageCount int64
+	if err := db.Model(&model.PropertyImage{}).
+		Where("property_id = ?", propertyID).Count(&imageCount).Error; err != nil {
+		return 0, err
+	}
+
+	used := imageCount
+	if includePending {
+		pending, err := model.CountPendingUploads(db, propertyID, time.Now())
+		if err != nil {
+			return 0, err
+		}
+		used += pending
+	}
+
+	limits := subscription.GetPlanLimits(model.GetUserPlanType(db, userID))
+	return limits.MaxImagesPerList - int(used), nil
+}
+
+// RequestImageUploads doğrudan depolamaya yükleme için imzalı PUT URL'leri üretir
+func RequestImageUploads(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var property model.Property
+	if err := database.GetDB().First(&property, c.Params("property_id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Property not found",
+		})
+	}
+
+	if property.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Not authorized",
+		})
+	}
+
+	input := new(UploadRequestInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	if len(input.Files) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "At least one file is required",
+		})
+	}
+
+	for _, file := range input.Files {
+		ext := strings.ToLower(filepath.Ext(file.FileName))
+		if !validation.AllowedImageTypes[ext] || file.ContentType == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": validation.ErrFileType.Error(),
+				"file":  file.FileName,
+			})
+		}
+		if file.Size <= 0 || file.Size > validation.MaxImageSize {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": validation.ErrFileSize.Error(),
+				"file":  file.FileName,
+			})
+		}
+	}
+
+	db := database.GetDB()
+
+	remaining, err := remainingImageSlots(db, claims.UserID, property.ID, true)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not check image count",
+		})
+	}
+	if len(input.Files) > remaining {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":     "You have reached the maximum image limit for this listing",
+			"requested": len(input.Files),
+			"remaining": max(remaining, 0),
+		})
+	}
+
+	var user model.User
+	if err := db.First(&user, claims.UserID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch user details",
+		})
+	}
+
+	if storage.GlobalStorage == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Storage is not configured",
+		})
+	}
+
+	now := time.Now()
+	uploads := make([]PresignedUpload, 0, len(input.Files))
+
+	tx := db.Begin()
+	for _, file := range input.Files {
+		key := cloudflare.PropertyUploadKey(user.Username, property.Slug, strings.ToLower(filepath.Ext(file.FileName)))
+
+		// Boyut imzaya bağlanır; bildirilenden farklı boyutta yükleme kabul edilmez
+		uploadURL, err := storage.GlobalStorage.SignedUploadURL(context.TODO(), key, file.ContentType, file.Size, UploadURLExpiry)
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Error signing upload URL: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not create upload URL",
+			})
+		}
+
+		pending := model.PendingUpload{
+			UserID:      claims.UserID,
+			PropertyID:  property.ID,
+			ObjectKey:   key,
+			FileName:    file.FileName,
+			ContentType: file.ContentType,
+			Size:        file.Size,
+			ExpiresAt:   now.Add(PendingUploadExpiry),
+		}
+		if err := tx.Create(&pending).Error; err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not save pending upload",
+			})
+		}
+
+		uploads = append(uploads, PresignedUpload{
+			ID:        pending.ID,
+			FileName:  file.FileName,
+			UploadURL: uploadURL,
+			Method:    http.MethodPut,
+			Headers:   map[string]string{fiber.HeaderContentType: file.ContentType},
+			ExpiresAt: now.Add(UploadURLExpiry),
+		})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not save pending uploads",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"uploads": uploads,
+	})
+}
+
+var errImageLimitReached = errors.New("maximum image limit for this listing reached")
+
+// confirmPendingUpload yüklenen dosyayı doğrular, işler ve resim kaydını oluşturur
+func confirmPendingUpload(db *gorm.DB, pending *model.PendingUpload, user *model.User, property *model.Property) (*model.PropertyImage, error) {
+	// Dosya okunmadan önce boyutu kontrol edilir
+	object, err := storage.GlobalStorage.Stat(context.TODO(), pending.ObjectKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("file has not been uploaded")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if object.Size > validation.MaxImageSize {
+		return nil, validation.ErrFileSize
+	}
+	if object.Size != pending.Size {
+		return nil, fmt.Errorf("uploaded file size does not match the requested size")
+	}
+
+	data, err := storage.GlobalStorage.Get(context.TODO(), pending.ObjectKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("file has not been uploaded")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validation.ValidateImageData(data); err != nil {
+		return nil, err
+	}
+
+	// Decode edilemeyen dosyalar burada reddedilir
+	watermark, watermarkVersion := userWatermark(user)
+	result, err := cloudflare.UploadImageData(data, user.Username, property.Slug, watermark)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := json.Marshal(result.Variants)
+	if err != nil {
+		return nil, err
+	}
+
+	var image model.PropertyImage
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Eşzamanlı onaylar ilanın resim limitini birlikte aşamasın
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&model.Property{}, property.ID).Error; err != nil {
+			return err
+		}
+		remaining, err := remainingImageSlots(tx, property.UserID, property.ID, false)
+		if err != nil {
+			return err
+		}
+		if remaining <= 0 {
+			return errImageLimitReached
+		}
+
+		var imageCount, coverCount int64
+		if err := tx.Model(&model.PropertyImage{}).
+			Where("property_id = ?", property.ID).Count(&imageCount).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.PropertyImage{}).
+			Where("property_id = ? AND is_cover = ?", property.ID, true).Count(&coverCount).Error; err != nil {
+			return err
+		}
+
+		image = model.PropertyImage{
+			PropertyID:   property.ID,
+			URL:          result.URL,
+			ThumbnailURL: result.ThumbnailURL,
+			CloudflareID: result.CloudflareID,
+			Size:         result.Size,
+			Width:        result.Width,
+			Height:       result.Height,
+			Variants:     variants,
+			Order:        int(imageCount),
+			IsCover:      coverCount == 0,
+
+			PerceptualHash:   result.PerceptualHash,
+			BlurHash:         result.Placeholder.BlurHash,
+			LQIP:             result.Placeholder.LQIP,
+			DominantColor:    result.Placeholder.DominantColor,
+			WatermarkVersion: watermarkVersion,
+		}
+		return tx.Create(&image).Error
+	})
+	if err != nil {
+		processedImage := model.PropertyImage{URL: result.URL, CloudflareID: result.CloudflareID, Variants: variants}
+		if delErr := cloudflare.DeleteImageObjects(&processedImage); delErr != nil {
+			log.Printf("Error deleting processed image %s: %v", result.URL, delErr)
+		}
+		return nil, err
+	}
+
+	duplicates, err := model.FindDuplicateImages(db, property.ID, image.ID, image.PerceptualHash)
+	if err != nil {
+		log.Printf("Error checking duplicate images: %v", err)
+	}
+	image.DuplicateImageIDs = duplicates
+
+	return &image, nil
+}
+
+// ConfirmImageUploads doğrudan yüklenen dosyaları doğrular ve ilana ekler
+func ConfirmImageUploads(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var property model.Property
+	if err := database.GetDB().First(&property, c.Params("property_id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Property not found",
+		})
+	}
+
+	if property.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Not authorized",
+		})
+	}
+
+	input := new(ConfirmUploadsInput)
+	if err := c.BodyParser(input); err != nil || len(input.UploadIDs) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	db := database.GetDB()
+
+	var pendingUploads []model.PendingUpload
+	if err := db.Where("id IN ? AND user_id = ? AND property_id = ? AND expires_at > ?",
+		input.UploadIDs, claims.UserID, property.ID, time.Now()).
+		Order("id ASC").
+		Find(&pendingUploads).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch pending uploads",
+		})
+	}
+
+	var user model.User
+	if err := db.First(&user, claims.UserID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch user details",
+		})
+	}
+
+	if storage.GlobalStorage == nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Storage is not configured",
+		})
+	}
+
+	found := make(map[uint]bool, len(pendingUploads))
+	images := []model.PropertyImage{}
+	failures := []UploadConfirmError{}
+
+	for i := range pendingUploads {
+		pending := &pendingUploads[i]
+		found[pending.ID] = true
+
+		// Kayıt silinerek sahiplenilir; aynı yüklemeyi eşzamanlı onaylayan istek 0 satır siler
+		// ve dosyayı işlemez. Onaylanan ya da başarısız olan yüklemeler tekrar denenmez.
+		claim := db.Unscoped().Where("id = ? AND expires_at > ?", pending.ID, time.Now()).Delete(&model.PendingUpload{})
+		if claim.Error != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not claim pending upload",
+			})
+		}
+		if claim.RowsAffected == 0 {
+			failures = append(failures, UploadConfirmError{UploadID: pending.ID, Error: "upload not found or expired"})
+			continue
+		}
+
+		remaining, err := remainingImageSlots(db, claims.UserID, property.ID, false)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not check image count",
+			})
+		}
+
+		var image *model.PropertyImage
+		if remaining <= 0 {
+			err = errImageLimitReached
+		} else {
+			image, err = confirmPendingUpload(db, pending, &user, &property)
+		}
+
+		// İşlenmemiş dosyaya artık ihtiyaç yok; başarısız yüklemeler de tekrar denenmez
+		if delErr := storage.GlobalStorage.Delete(context.TODO(), pending.ObjectKey); delErr != nil {
+			log.Printf("Error deleting raw upload %s: %v", pending.ObjectKey, delErr)
+		}
+
+		if err != nil {
+			failures = append(failures, UploadConfirmError{UploadID: pending.ID, Error: err.Error()})
+			continue
+		}
+		images = append(images, *image)
+	}
+
+	for _, id := range input.UploadIDs {
+		if !found[id] {
+			failures = append(failures, UploadConfirmError{UploadID: id, Error: "upload not found or expired"})
+		}
+	}
+
+	status := fiber.StatusCreated
+	if len(images) == 0 {
+		status = fiber.StatusBadRequest
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"images": images,
+		"errors": failures,
+	})
+}
@@ -0,0 +1,475 @@
+package controller
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxFeedListings bir feed'de yer alan en fazla ilan sayısı
+	MaxFeedListings = 500
+
+	// RSS feed'inde gösterilen en yeni ilan sayısı
+	rssFeedItems = 50
+)
+
+// feedVersion feed'in son değişiklik zamanı ve içerik imzası
+type feedVersion struct {
+	LastModified time.Time
+	ETag         string
+}
+
+// getFeedVersion ilanların, resimlerin ve özelliklerin son güncellenme/silinme zamanından ETag üretir.
+// Böylece feed içeriği oluşturulmadan 304 dönülebilir.
+func getFeedVersion(userID uint, format string) (feedVersion, error) {
+	var row struct {
+		Count           int64
+		PropertyUpdated *time.Time
+		ImageUpdated    *time.Time
+		FeatureUpdated  *time.Time
+	}
+
+	err := publicPropertiesQuery(userID).
+		Select(`COUNT(*) AS count,
+			MAX(properties.updated_at) AS property_updated,
+			(SELECT MAX(GREATEST(pi.updated_at, pi.deleted_at)) FROM property_images pi
+				WHERE pi.property_id IN (SELECT id FROM properties p WHERE p.user_id = ?)) AS image_updated,
+			(SELECT MAX(GREATEST(pf.updated_at, pf.deleted_at)) FROM property_features pf
+				WHERE pf.property_id IN (SELECT id FROM properties p WHERE p.user_id = ?)) AS feature_updated`,
+			userID, userID).
+		Scan(&row).Error
+	if err != nil {
+		return feedVersion{}, err
+	}
+
+	var lastModified time.Time
+	for _, t := range []*time.Time{row.PropertyUpdated, row.ImageUpdated, row.FeatureUpdated} {
+		if t != nil && t.After(lastModified) {
+			lastModified = *t
+		}
+	}
+
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s:%d:%d:%d", format, userID, row.Count, lastModified.UnixNano())))
+	return feedVersion{
+		LastModified: lastModified.UTC().Truncate(time.Second),
+		ETag:         `W/"` + hex.EncodeToString(sum[:8]) + `"`,
+	}, nil
+}
+
+// checkFeedCache cache başlıklarını ayarlar; istemcideki sürüm güncelse true döner
+func checkFeedCache(c *fiber.Ctx, version feedVersion) bool {
+	c.Set(fiber.HeaderETag, version.ETag)
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	if !version.LastModified.IsZero() {
+		c.Set(fiber.HeaderLastModified, version.LastModified.Format(http.TimeFormat))
+	}
+
+	if match := c.Get(fiber.HeaderIfNoneMatch); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == version.ETag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if since := c.Get(fiber.HeaderIfModifiedSince); since != "" && !version.LastModified.IsZero() {
+		if t, err := http.ParseTime(since); err == nil && !version.LastModified.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadFeedProperties ListUserProperties ile aynı sorgudan ilanları resim ve özellikleriyle yükler
+func loadFeedProperties(userID uint, order string, limit int) ([]model.Property, error) {
+	var properties []model.Property
+	err := publicPropertiesQuery(userID).
+		Preload("Images", func(db *gorm.DB) *gorm.DB {
+			return db.Order("property_images.order ASC")
+		}).
+		Preload("Features", func(db *gorm.DB) *gorm.DB {
+			return db.Order("property_features.order ASC")
+		}).
+		Order(order).
+		Limit(limit).
+		Find(&properties).Error
+	return properties, err
+}
+
+// featureText özellik değerlerini ("Balkon: 2" ya da "Manzara: Deniz, Orman") metne çevirir
+func featureText(feature model.PropertyFeature) string {
+	var values interface{}
+	if err := json.Unmarshal(feature.Values, &values); err != nil || values == nil {
+		return feature.Title
+	}
+
+	switch v := values.(type) {
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return feature.Title + ": " + strings.Join(parts, ", ")
+	case bool:
+		if v {
+			return feature.Title
+		}
+		return feature.Title + ": no"
+	default:
+		return fmt.Sprintf("%s: %v", feature.Title, v)
+	}
+}
+
+// Kyero v3 şemasına uygun XML feed (import tarafında da aynı şema okunuyor)
+type kyeroFeed struct {
+	XMLName    xml.Name        `xml:"root"`
+	Kyero      kyeroHeader     `xml:"kyero"`
+	Properties []kyeroProperty `xml:"property"`
+}
+
+type kyeroHeader struct {
+	FeedVersion   int    `xml:"feed_version"`
+	FeedGenerated string `xml:"feed_generated"`
+}
+
+type kyeroProperty struct {
+	ID          uint           `xml:"id"`
+	Date        string         `xml:"date"`
+	Ref         string         `xml:"ref"`
+	Price       int64          `xml:"price"`
+	Currency    model.Currency `xml:"currency"`
+	PriceFreq   string         `xml:"price_freq"`
+	Type        string         `xml:"type"`
+	Town        string         `xml:"town"`
+	Province    string         `xml:"province"`
+	Country     string         `xml:"country"`
+	Location    *kyeroLocation `xml:"location,omitempty"`
+	Beds        int            `xml:"beds"`
+	Baths       int            `xml:"baths"`
+	Pool        int            `xml:"pool"`
+	SurfaceArea kyeroSurface   `xml:"surface_area"`
+	URL         kyeroText      `xml:"url"`
+	Desc        kyeroText      `xml:"desc"`
+	Features    []string       `xml:"features>feature"`
+	Images      []kyeroImage   `xml:"images>image"`
+}
+
+type kyeroLocation struct {
+	Latitude  float64 `xml:"latitude"`
+	Longitude float64 `xml:"longitude"`
+}
+
+type kyeroSurface struct {
+	Built int `xml:"built"` // m²
+}
+
+type kyeroText struct {
+	En string `xml:"en"`
+}
+
+type kyeroImage struct {
+	ID  int    `xml:"id,attr"`
+	URL string `xml:"url"`
+}
+
+func toKyeroProperty(username string, p model.Property) kyeroProperty {
+	priceFreq := "sale"
+	if p.Status == model.PropertyStatusForRent || p.Status == model.PropertyStatusRented {
+		priceFreq = "month"
+	}
+
+	item := kyeroProperty{
+		ID:          p.ID,
+		Date:        p.UpdatedAt.Format("2006-01-02 15:04:05"),
+		Ref:         p.Slug,
+		Price:       int64(math.Round(p.Price)),
+		Currency:    p.Currency,
+		PriceFreq:   priceFreq,
+		Type:        string(p.Type),
+		Town:        p.City,
+		Province:    p.StateName,
+		Country:     p.CountryName,
+		Beds:        p.Bedrooms,
+		Baths:       p.Bathrooms,
+		SurfaceArea: kyeroSurface{Built: int(math.Round(float64(p.AreaSqFt) / sqFtPerSquareMeter))},
+		URL:         kyeroText{En: model.PropertyPublicURL(username, p.Slug)},
+		Desc:        kyeroText{En: p.Description},
+	}
+	if p.SwimmingPool {
+		item.Pool = 1
+	}
+	if p.Latitude != nil && p.Longitude != nil {
+		item.Location = &kyeroLocation{Latitude: *p.Latitude, Longitude: *p.Longitude}
+	}
+	for _, f := range p.Features {
+		item.Features = append(item.Features, featureText(f))
+	}
+	for i, img := range p.Images {
+		item.Images = append(item.Images, kyeroImage{ID: i + 1, URL: img.URL})
+	}
+	return item
+}
+
+// JSON feed kaydı
+type jsonFeedListing struct {
+	ID          uint                   `json:"id"`
+	URL         string                 `json:"url"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Type        model.PropertyType     `json:"type"`
+	Status      model.PropertyStatus   `json:"status"`
+	Price       float64                `json:"price"`
+	Currency    model.Currency         `json:"currency"`
+	Location    jsonFeedLocation       `json:"location"`
+	Details     map[string]interface{} `json:"details"`
+	Features    []jsonFeedFeature      `json:"features"`
+	Images      []string               `json:"images"`
+	PublishedAt *time.Time             `json:"published_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+type jsonFeedLocation struct {
+	CountryCode string   `json:"country_code"`
+	CountryName string   `json:"country_name"`
+	StateCode   string   `json:"state_code"`
+	StateName   string   `json:"state_name"`
+	City        string   `json:"city"`
+	District    string   `json:"district"`
+	Address     string   `json:"address"`
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	Approximate bool     `json:"approximate"`
+}
+
+type jsonFeedFeature struct {
+	Title  string      `json:"title"`
+	Values interface{} `json:"values"`
+}
+
+func toJSONFeedListing(username string, p model.Property) jsonFeedListing {
+	item := jsonFeedListing{
+		ID:          p.ID,
+		URL:         model.PropertyPublicURL(username, p.Slug),
+		Title:       p.Title,
+		Description: p.Description,
+		Type:        p.Type,
+		Status:      p.Status,
+		Price:       p.Price,
+		Currency:    p.Currency,
+		Location: jsonFeedLocation{
+			CountryCode: p.CountryCode,
+			CountryName: p.CountryName,
+			StateCode:   p.StateCode,
+			StateName:   p.StateName,
+			City:        p.City,
+			District:    p.District,
+			Address:     p.FullAddress,
+			Latitude:    p.Latitude,
+			Longitude:   p.Longitude,
+			Approximate: p.LocationApproximate,
+		},
+		Details: map[string]interface{}{
+			"bedrooms":         p.Bedrooms,
+			"bathrooms":        p.Bathrooms,
+			"garage_spaces":    p.GarageSpaces,
+			"area_sq_ft":       p.AreaSqFt,
+			"year_built":       p.YearBuilt,
+			"swimming_pool":    p.SwimmingPool,
+			"garden":           p.Garden,
+			"air_conditioning": p.AirConditioning,
+			"central_heating":  p.CentralHeating,
+			"security_system":  p.SecuritySystem,
+		},
+		Features:    []jsonFeedFeature{},
+		Images:      []string{},
+		PublishedAt: p.PublishedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+	for _, f := range p.Features {
+		var values interface{}
+		json.Unmarshal(f.Values, &values)
+		item.Features = append(item.Features, jsonFeedFeature{Title: f.Title, Values: values})
+	}
+	for _, img := range p.Images {
+		item.Images = append(item.Images, img.URL)
+	}
+	return item
+}
+
+// RSS 2.0 feed'i (yeni ilanlar)
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        string        `xml:"guid"`
+	Description string        `xml:"description"`
+	PubDate     string        `xml:"pubDate"`
+	Category    string        `xml:"category"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
+}
+
+func toRSSItem(username string, p model.Property) rssItem {
+	published := p.CreatedAt
+	if p.PublishedAt != nil {
+		published = *p.PublishedAt
+	}
+
+	link := model.PropertyPublicURL(username, p.Slug)
+	item := rssItem{
+		Title:       p.Title,
+		Link:        link,
+		GUID:        link,
+		Description: fmt.Sprintf("%s · %.0f %s · %s, %s", p.Type, p.Price, p.Currency, p.City, p.CountryName),
+		PubDate:     published.UTC().Format(time.RFC1123Z),
+		Category:    string(p.Status),
+	}
+
+	if cover := p.CoverImage(); cover != nil {
+		item.Enclosure = rssImageEnclosure(cover)
+	}
+	return item
+}
+
+// rssImageEnclosure enclosure tipini ve boyutunu barındırılan dosyanın kendisinden alır.
+// İşlenmemiş eski resimlerde dosya yüklenen orijinaldir; tip uzantıdan bulunur.
+func rssImageEnclosure(img *model.PropertyImage) *rssEnclosure {
+	if variant, ok := img.HostedVariant(); ok {
+		return &rssEnclosure{URL: img.URL, Type: "image/" + variant.Format, Length: variant.Size}
+	}
+
+	contentType := mime.TypeByExtension(strings.ToLower(path.Ext(img.URL)))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &rssEnclosure{URL: img.URL, Type: contentType, Length: img.Size}
+}
+
+// GetUserPropertyFeed kullanıcının yayındaki ilanlarını xml (Kyero), json ya da rss olarak döner
+func GetUserPropertyFeed(format string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		username := c.Params("username")
+
+		var user model.User
+		if err := database.GetDB().Where("username = ?", username).First(&user).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": "User not found",
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not fetch user",
+			})
+		}
+
+		version, err := getFeedVersion(user.ID, format)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not build feed",
+			})
+		}
+		if checkFeedCache(c, version) {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+
+		order, limit := "properties.updated_at DESC", MaxFeedListings
+		if format == "rss" {
+			order, limit = "properties.published_at DESC NULLS LAST, properties.id DESC", rssFeedItems
+		}
+
+		properties, err := loadFeedProperties(user.ID, order, limit)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not build feed",
+			})
+		}
+
+		switch format {
+		case "json":
+			listings := make([]jsonFeedListing, 0, len(properties))
+			for _, p := range properties {
+				listings = append(listings, toJSONFeedListing(user.Username, p))
+			}
+			return c.JSON(fiber.Map{
+				"agent": fiber.Map{
+					"username":     user.Username,
+					"company_name": user.CompanyName,
+					"url":          "https://estapage.com/" + user.Username,
+				},
+				"generated_at": time.Now().UTC(),
+				"listings":     listings,
+			})
+
+		case "rss":
+			feed := rssFeed{
+				Version: "2.0",
+				Channel: rssChannel{
+					Title:       user.CompanyName + " - New Listings",
+					Link:        "https://estapage.com/" + user.Username,
+					Description: "Latest listings from " + user.CompanyName,
+				},
+			}
+			if !version.LastModified.IsZero() {
+				feed.Channel.LastBuildDate = version.LastModified.Format(time.RFC1123Z)
+			}
+			for _, p := range properties {
+				feed.Channel.Items = append(feed.Channel.Items, toRSSItem(user.Username, p))
+			}
+			return sendXML(c, "application/rss+xml; charset=utf-8", feed)
+
+		default:
+			feed := kyeroFeed{
+				Kyero: kyeroHeader{FeedVersion: 3, FeedGenerated: time.Now().Format("2006-01-02 15:04:05")},
+			}
+			for _, p := range properties {
+				feed.Properties = append(feed.Properties, toKyeroProperty(user.Username, p))
+			}
+			return sendXML(c, "application/xml; charset=utf-8", feed)
+		}
+	}
+}
+
+func sendXML(c *fiber.Ctx, contentType string, v interface{}) error {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not build feed",
+		})
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Send(append([]byte(xml.Header), data...))
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"estepage_backend/internal/middleware"
+	"estepage_backend/pkg/utils/antispam"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FormProtection herkese açık formların (lead, bülten) spam koruma alanları.
+// Website gizli honeypot alanıdır; form_token GET /api/forms/token ile alınır.
+type FormProtection struct {
+	Website   string `json:"website"`
+	FormToken string `json:"form_token"`
+	PowNonce  string `json:"pow_nonce"`
+}
+
+// submission form alanlarını ve isteğin hedef limiti durumunu spam değerlendirmesi için birleştirir
+func (f FormProtection) submission(c *fiber.Ctx, name, emailAddress, message string) antispam.Submission {
+	return antispam.Submission{
+		Name:          name,
+		Email:         emailAddress,
+		Message:       message,
+		Honeypot:      f.Website,
+		Token:         f.FormToken,
+		PowNonce:      f.PowNonce,
+		TargetLimited: middleware.FormTargetLimited(c),
+	}
+}
+
+// GetFormToken form açılırken alınacak imzalı token'ı ve proof-of-work görevini döner
+func GetFormToken(c *fiber.Ctx) error {
+	challenge, err := antispam.NewChallenge()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not create form token",
+		})
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(challenge)
+}
+
+// respondDisposableEmail tek kullanımlık e-posta adreslerini reddeder
+func respondDisposableEmail(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "Please use a permanent email address",
+	})
+}
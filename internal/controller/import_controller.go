@@ -0,0 +1,565 @@
+package controller
+
+import (
+	"encoding/json"
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/subscription"
+	"estepage_backend/pkg/utils/currency"
+	"estepage_backend/pkg/utils/importer"
+	"estepage_backend/pkg/utils/jwt"
+	"estepage_backend/pkg/utils/location"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	sqFtPerSquareMeter = 10.7639
+
+	// Aktarım ilerlemesi her N satırda bir kaydedilir
+	importProgressInterval = 10
+)
+
+// Yaygın ilan tipi isimlerinin bizim tiplere karşılıkları
+var importTypeAliases = map[string]model.PropertyType{
+	"flat":         model.PropertyTypeApartment,
+	"penthouse":    model.PropertyTypeApartment,
+	"duplex":       model.PropertyTypeApartment,
+	"studio":       model.PropertyTypeApartment,
+	"wohnung":      model.PropertyTypeApartment,
+	"detached":     model.PropertyTypeHouse,
+	"bungalow":     model.PropertyTypeHouse,
+	"haus":         model.PropertyTypeHouse,
+	"countryhouse": model.PropertyTypeVilla,
+	"finca":        model.PropertyTypeVilla,
+	"townhouse":    model.PropertyTypeTownhouse,
+	"plot":         model.PropertyTypeLand,
+	"grundstueck":  model.PropertyTypeLand,
+	"office":       model.PropertyTypeCommercial,
+	"shop":         model.PropertyTypeCommercial,
+	"buero_praxen": model.PropertyTypeCommercial,
+	"warehouse":    model.PropertyTypeIndustrial,
+}
+
+// Kyero price_freq ve benzeri değerler
+var importStatusAliases = map[string]model.PropertyStatus{
+	"sale":  model.PropertyStatusForSale,
+	"kauf":  model.PropertyStatusForSale,
+	"rent":  model.PropertyStatusForRent,
+	"month": model.PropertyStatusForRent,
+	"week":  model.PropertyStatusForRent,
+	"miete": model.PropertyStatusForRent,
+}
+
+func normalizeImportValue(value string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(value)))
+}
+
+func parseImportType(value string) (model.PropertyType, bool) {
+	normalized := normalizeImportValue(value)
+	for _, t := range model.PropertyTypes {
+		if normalizeImportValue(string(t)) == normalized {
+			return t, true
+		}
+	}
+	if t, ok := importTypeAliases[normalized]; ok {
+		return t, true
+	}
+	if t, ok := importTypeAliases[strings.ToLower(strings.TrimSpace(value))]; ok {
+		return t, true
+	}
+	return "", false
+}
+
+func parseImportStatus(value string) (model.PropertyStatus, bool) {
+	if value == "" {
+		return model.PropertyStatusForSale, true
+	}
+	normalized := normalizeImportValue(value)
+	for _, s := range model.PropertyStatuses {
+		if normalizeImportValue(string(s)) == normalized {
+			return s, true
+		}
+	}
+	s, ok := importStatusAliases[normalized]
+	return s, ok
+}
+
+// parseImportNumber "1.250.000", "1,250,000", "100,000" ve "1250000.50" gibi değerleri okur
+func parseImportNumber(value string) (float64, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	if strings.Count(value, ",") > 0 && strings.Count(value, ".") > 0 {
+		if strings.LastIndex(value, ",") > strings.LastIndex(value, ".") {
+			value = strings.ReplaceAll(value, ".", "")
+			value = strings.ReplaceAll(value, ",", ".")
+		} else {
+			value = strings.ReplaceAll(value, ",", "")
+		}
+	} else if strings.Count(value, ",") > 1 || strings.Count(value, ".") > 1 {
+		value = strings.NewReplacer(",", "", ".", "").Replace(value)
+	} else if i := strings.IndexAny(value, ",."); i >= 0 && len(value)-i-1 == 3 {
+		// Tek ayraç ve ardından 3 hane: binlik ayracı kabul edilir (100,000)
+		value = value[:i] + value[i+1:]
+	} else {
+		value = strings.ReplaceAll(value, ",", ".")
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func parseImportBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "0", "false", "no", "n", "hayır", "nein":
+		return false, nil
+	case "1", "true", "yes", "y", "evet", "ja", "si", "sí":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid boolean value %q", value)
+}
+
+// mapImportRecord kaydı PropertyInput'a çevirip doğrular. foreign başka bir hesaba ait
+// resim URL'leridir, atlanır. Hata varsa input nil döner;
+// uyarılar (Warning) satırın aktarılmasını engellemez.
+func mapImportRecord(record importer.Record, foreign map[string]bool) (*PropertyInput, []model.ImportRowError) {
+	var problems []model.ImportRowError
+	fail := func(field, message string) {
+		problems = append(problems, model.ImportRowError{Row: record.Row, Field: field, Message: message})
+	}
+	warn := func(field, message string) {
+		problems = append(problems, model.ImportRowError{Row: record.Row, Field: field, Message: message, Warning: true})
+	}
+
+	input := &PropertyInput{
+		Title:       record.Get("title"),
+		Description: record.Get("description"),
+		City:        record.Get("city"),
+		District:    record.Get("district"),
+		FullAddress: record.Get("full_address"),
+	}
+
+	propertyType, ok := parseImportType(record.Get("type"))
+	if !ok {
+		fail("type", fmt.Sprintf("Unknown property type %q", record.Get("type")))
+	}
+	input.Type = propertyType
+
+	status, ok := parseImportStatus(record.Get("status"))
+	if !ok {
+		fail("status", fmt.Sprintf("Unknown property status %q", record.Get("status")))
+	}
+	input.Status = status
+
+	if price, err := parseImportNumber(record.Get("price")); err != nil || price <= 0 {
+		fail("price", "Price must be a positive number")
+	} else {
+		input.Price = price
+	}
+
+	input.Currency = model.Currency(strings.ToUpper(record.Get("currency")))
+	if !currency.IsSupported(string(input.Currency)) {
+		fail("currency", fmt.Sprintf("Unsupported currency %q", record.Get("currency")))
+	}
+
+	// Konum: ülke ve eyalet/il location verisinde bulunmalı
+	countryValue := record.Get("country_code")
+	if countryValue == "" {
+		countryValue = record.Get(importer.FieldCountry)
+	}
+	country, ok := location.FindCountry(countryValue)
+	if !ok {
+		fail("country_code", fmt.Sprintf("Unknown country %q", countryValue))
+	} else {
+		input.CountryCode, input.CountryName = country.ISO2, country.Name
+
+		stateValue := record.Get("state_code")
+		if stateValue == "" {
+			stateValue = record.Get(importer.FieldState)
+		}
+		if state, ok := location.FindState(country.ISO2, stateValue); ok {
+			input.StateCode, input.StateName = state.StateCode, state.Name
+		} else {
+			fail("state_code", fmt.Sprintf("Unknown state %q for country %s", stateValue, country.ISO2))
+		}
+	}
+
+	if input.City == "" {
+		fail("city", "City is required")
+	}
+
+	if input.Title == "" && input.City != "" && input.Type != "" {
+		input.Title = fmt.Sprintf("%s in %s", input.Type, input.City)
+	}
+	if input.Title == "" {
+		fail("title", "Title is required")
+	}
+
+	if input.FullAddress == "" {
+		parts := []string{}
+		for _, part := range []string{input.District, input.City, input.StateName, input.CountryName} {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+		input.FullAddress = strings.Join(parts, ", ")
+	}
+
+	intFields := map[string]*int{
+		"bedrooms":      &input.Bedrooms,
+		"bathrooms":     &input.Bathrooms,
+		"garage_spaces": &input.GarageSpaces,
+		"area_sq_ft":    &input.AreaSqFt,
+		"year_built":    &input.YearBuilt,
+	}
+	for field, target := range intFields {
+		raw := record.Get(field)
+		if raw == "" {
+			continue
+		}
+		value, err := parseImportNumber(raw)
+		if err != nil || value < 0 {
+			fail(field, fmt.Sprintf("Invalid number %q", raw))
+			continue
+		}
+		*target = int(math.Round(value))
+	}
+
+	if raw := record.Get(importer.FieldAreaM2); raw != "" && input.AreaSqFt == 0 {
+		if value, err := parseImportNumber(raw); err == nil && value > 0 {
+			input.AreaSqFt = int(math.Round(value * sqFtPerSquareMeter))
+		} else {
+			fail("area_sq_ft", fmt.Sprintf("Invalid area %q", raw))
+		}
+	}
+
+	boolFields := map[string]*bool{
+		"swimming_pool":    &input.SwimmingPool,
+		"garden":           &input.Garden,
+		"air_conditioning": &input.AirConditioning,
+		"central_heating":  &input.CentralHeating,
+		"security_system":  &input.SecuritySystem,
+	}
+	for field, target := range boolFields {
+		value, err := parseImportBool(record.Get(field))
+		if err != nil {
+			fail(field, err.Error())
+			continue
+		}
+		*target = value
+	}
+
+	for field, target := range map[string]**float64{"latitude": &input.Latitude, "longitude": &input.Longitude} {
+		raw := record.Get(field)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			fail(field, fmt.Sprintf("Invalid coordinate %q", raw))
+			continue
+		}
+		*target = &value
+	}
+
+	// Dışarıdaki resimler indirilmez; sadece bucket'a yüklenmiş resimler eklenir
+	for _, imageURL := range record.Fields["images"] {
+		if !isHostedImageURL(imageURL) {
+			warn("images", fmt.Sprintf("External image skipped: %s", imageURL))
+			continue
+		}
+		if foreign[imageURL] {
+			warn("images", fmt.Sprintf("Image of another account skipped: %s", imageURL))
+			continue
+		}
+		if len(input.Images) >= MaxPropertyImages {
+			warn("images", fmt.Sprintf("Only the first %d images were imported", MaxPropertyImages))
+			break
+		}
+		input.Images = append(input.Images, imageURL)
+	}
+
+	for i, feature := range record.Features {
+		var values interface{} = feature.Values
+		if len(feature.Values) == 1 {
+			values = feature.Values[0]
+		}
+		input.Features = append(input.Features, PropertyFeatureInput{
+			Title:  feature.Title,
+			Values: values,
+			Order:  i,
+		})
+	}
+
+	for _, p := range problems {
+		if !p.Warning {
+			return nil, problems
+		}
+	}
+	return input, problems
+}
+
+// createImportedProperty tek bir satırı ilan, resim ve özellikleriyle birlikte kaydeder
+func createImportedProperty(tx *gorm.DB, userID uint, input *PropertyInput, state model.PublicationState) error {
+	latitude, longitude, approximate, err := resolvePropertyCoordinates(input)
+	if err != nil {
+		return err
+	}
+
+	property := newPropertyFromInput(userID, input, latitude, longitude, approximate)
+	property.ApplyPublicationState(state, nil, time.Now())
+
+	if err := tx.Create(&property).Error; err != nil {
+		return fmt.Errorf("could not create property")
+	}
+
+	for i, imageURL := range input.Images {
+		image := model.PropertyImage{
+			PropertyID:   property.ID,
+			URL:          imageURL,
+			CloudflareID: cloudflareIDFromURL(imageURL),
+			Order:        i,
+			IsCover:      i == 0,
+		}
+		if err := tx.Create(&image).Error; err != nil {
+			return fmt.Errorf("could not save images")
+		}
+	}
+
+	for _, f := range input.Features {
+		values, err := json.Marshal(f.Values)
+		if err != nil {
+			return fmt.Errorf("invalid feature %q", f.Title)
+		}
+		feature := model.PropertyFeature{
+			PropertyID: property.ID,
+			Title:      f.Title,
+			Values:     datatypes.JSON(values),
+			Order:      f.Order,
+		}
+		if err := tx.Create(&feature).Error; err != nil {
+			return fmt.Errorf("could not save features")
+		}
+	}
+
+	if _, err := model.RecordPriceChange(tx, property.ID, 0, "", property.Price, property.Currency); err != nil {
+		return fmt.Errorf("could not record price history")
+	}
+	if _, err := model.RecordPropertyRevision(tx, property.ID, userID, "imported"); err != nil {
+		return fmt.Errorf("could not record property revision")
+	}
+	return nil
+}
+
+// runPropertyImport aktarımı arka planda satır satır çalıştırır ve ilerlemeyi kaydeder
+func runPropertyImport(jobID, userID uint, records []importer.Record, state model.PublicationState) {
+	db := database.GetDB()
+	job := model.PropertyImport{}
+	job.ID = jobID
+
+	// Beklenmeyen bir hata sunucuyu düşürmesin; aktarım başarısız olarak işaretlenir
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovered from panic in import %d: %v", jobID, r)
+			if err := db.Model(&job).Updates(map[string]interface{}{
+				"status":      model.ImportStatusFailed,
+				"finished_at": time.Now(),
+			}).Error; err != nil {
+				log.Printf("Error marking import %d as failed: %v", jobID, err)
+			}
+		}
+	}()
+
+	started := time.Now()
+	db.Model(&job).Updates(map[string]interface{}{
+		"status":     model.ImportStatusRunning,
+		"started_at": started,
+	})
+
+	// Başka bir hesabın resimleri eklenmez; aksi halde ilandan silinirken dosyaları da silinirdi
+	var hostedURLs []string
+	for _, record := range records {
+		for _, imageURL := range record.Fields["images"] {
+			if isHostedImageURL(imageURL) {
+				hostedURLs = append(hostedURLs, imageURL)
+			}
+		}
+	}
+	foreign, err := foreignImageURLs(db, userID, hostedURLs)
+	if err != nil {
+		log.Printf("Error checking images of import %d: %v", jobID, err)
+		db.Model(&job).Updates(map[string]interface{}{
+			"status":      model.ImportStatusFailed,
+			"finished_at": time.Now(),
+		})
+		return
+	}
+
+	planType := model.GetUserPlanType(db, userID)
+	maxListings := subscription.GetPlanLimits(planType).MaxListings
+
+	var (
+		rowErrors []model.ImportRowError
+		imported  int
+		failed    int
+	)
+
+	saveProgress := func(processed int, extra map[string]interface{}) {
+		errorsJSON, _ := json.Marshal(rowErrors)
+		updates := map[string]interface{}{
+			"processed_rows": processed,
+			"imported_rows":  imported,
+			"failed_rows":    failed,
+			"errors":         datatypes.JSON(errorsJSON),
+		}
+		for k, v := range extra {
+			updates[k] = v
+		}
+		if err := db.Model(&job).Updates(updates).Error; err != nil {
+			log.Printf("Error saving import %d progress: %v", jobID, err)
+		}
+	}
+
+	for i, record := range records {
+		input, problems := mapImportRecord(record, foreign)
+		rowErrors = append(rowErrors, problems...)
+
+		if input == nil {
+			failed++
+		} else {
+			// Plan limiti her satırda yeniden kontrol edilir (aynı anda elle eklenen ilanlar için)
+			var propertyCount int64
+			db.Model(&model.Property{}).Where("user_id = ?", userID).Count(&propertyCount)
+
+			if int(propertyCount) >= maxListings {
+				failed++
+				rowErrors = append(rowErrors, model.ImportRowError{
+					Row:     record.Row,
+					Message: fmt.Sprintf("Listing limit of your %s plan (%d) reached", planType, maxListings),
+				})
+			} else if err := db.Transaction(func(tx *gorm.DB) error {
+				return createImportedProperty(tx, userID, input, state)
+			}); err != nil {
+				failed++
+				rowErrors = append(rowErrors, model.ImportRowError{Row: record.Row, Message: err.Error()})
+			} else {
+				imported++
+			}
+		}
+
+		if (i+1)%importProgressInterval == 0 {
+			saveProgress(i+1, nil)
+		}
+	}
+
+	finished := time.Now()
+	saveProgress(len(records), map[string]interface{}{
+		"status":      model.ImportStatusCompleted,
+		"finished_at": finished,
+	})
+
+	log.Printf("Import %d finished: %d imported, %d failed", jobID, imported, failed)
+}
+
+// ImportProperties CSV ya da XML dosyasından toplu ilan aktarımı başlatır.
+// Dosya hemen ayrıştırılır; satırlar arka planda işlenir ve ilerleme GetPropertyImport ile izlenir.
+func ImportProperties(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "No file provided",
+		})
+	}
+
+	format := strings.ToLower(c.FormValue("format"))
+	if format == "" {
+		format = importer.DetectFormat(file.Filename)
+	}
+	if format != importer.FormatCSV && format != importer.FormatXML {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Format must be csv or xml",
+		})
+	}
+
+	// Aktarılan ilanlar varsayılan olarak taslak oluşturulur
+	state := model.PublicationState(c.FormValue("publication_state", string(model.PublicationDraft)))
+	if state != model.PublicationDraft && state != model.PublicationPublished {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "publication_state must be draft or published",
+		})
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Could not read file",
+		})
+	}
+	defer f.Close()
+
+	records, err := importer.Parse(format, f)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var running int64
+	database.GetDB().Model(&model.PropertyImport{}).
+		Where("user_id = ? AND status IN ?", claims.UserID, []model.ImportStatus{model.ImportStatusPending, model.ImportStatusRunning}).
+		Count(&running)
+	if running > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Another import is already running",
+		})
+	}
+
+	job := model.PropertyImport{
+		UserID:    claims.UserID,
+		FileName:  file.Filename,
+		Format:    format,
+		Status:    model.ImportStatusPending,
+		TotalRows: len(records),
+		Errors:    datatypes.JSON("[]"),
+	}
+	if err := database.GetDB().Create(&job).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not start import",
+		})
+	}
+
+	go runPropertyImport(job.ID, claims.UserID, records, state)
+
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// GetPropertyImport aktarımın ilerlemesini ve satır bazlı hata raporunu döner
+func GetPropertyImport(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var job model.PropertyImport
+	if err := database.GetDB().
+		Where("id = ? AND user_id = ?", c.Params("id"), claims.UserID).
+		First(&job).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Import not found",
+		})
+	}
+
+	progress := 0.0
+	if job.TotalRows > 0 {
+		progress = math.Round(float64(job.ProcessedRows)/float64(job.TotalRows)*1000) / 10
+	}
+
+	return c.JSON(fiber.Map{
+		"import":   job,
+		"progress": progress,
+	})
+}
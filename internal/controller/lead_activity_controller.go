@@ -0,0 +1,329 @@
+package controller
+
+import (
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/utils/jwt"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const MaxLeadActivityBodyLength = 5000
+
+type LeadActivityInput struct {
+	Type       string     `json:"type"`
+	Body       string     `json:"body"`
+	OccurredAt *time.Time `json:"occurred_at"` // Boşsa şimdi
+}
+
+type LeadReminderInput struct {
+	DueAt     *time.Time `json:"due_at"`
+	Note      *string    `json:"note"`
+	Completed *bool      `json:"completed"` // Sadece güncellemede
+}
+
+// GetLeadTimeline lead'in notlarını, görüşmelerini ve durum değişikliklerini
+// en yeniden eskiye, hatırlatmalarıyla birlikte döner
+func GetLeadTimeline(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	db := database.GetDB()
+
+	var lead model.Lead
+	if err := db.Where("id = ? AND user_id = ?", c.Params("id"), claims.UserID).First(&lead).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Lead not found",
+		})
+	}
+
+	activities := []model.LeadActivity{}
+	if err := db.Where("lead_id = ?", lead.ID).
+		Order("occurred_at DESC, id DESC").
+		Find(&activities).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch lead activities",
+		})
+	}
+
+	reminders := []model.LeadReminder{}
+	if err := db.Where("lead_id = ?", lead.ID).
+		Order("due_at ASC").
+		Find(&reminders).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch lead reminders",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"lead":       lead,
+		"activities": activities,
+		"reminders":  reminders,
+	})
+}
+
+// CreateLeadActivity lead'e not ya da görüşme (call, email, meeting) kaydı ekler
+func CreateLeadActivity(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	db := database.GetDB()
+
+	var lead model.Lead
+	if err := db.Where("id = ? AND user_id = ?", c.Params("id"), claims.UserID).First(&lead).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Lead not found",
+		})
+	}
+
+	input := new(LeadActivityInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	activityType := model.LeadActivityType(input.Type)
+	if !model.LoggableLeadActivityTypes[activityType] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid activity type",
+			"valid_types": []model.LeadActivityType{
+				model.LeadActivityNote,
+				model.LeadActivityCall,
+				model.LeadActivityEmail,
+				model.LeadActivityMeeting,
+			},
+		})
+	}
+
+	body := strings.TrimSpace(input.Body)
+	if activityType == model.LeadActivityNote && body == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Note body is required",
+		})
+	}
+	if utf8.RuneCountInString(body) > MaxLeadActivityBodyLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Body must be at most %d characters", MaxLeadActivityBodyLength),
+		})
+	}
+
+	occurredAt := time.Now()
+	if input.OccurredAt != nil {
+		if input.OccurredAt.After(occurredAt) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "occurred_at cannot be in the future; use a reminder instead",
+			})
+		}
+		occurredAt = *input.OccurredAt
+	}
+
+	activity := model.LeadActivity{
+		LeadID:     lead.ID,
+		UserID:     claims.UserID,
+		Type:       activityType,
+		Body:       body,
+		OccurredAt: occurredAt,
+	}
+	if err := db.Create(&activity).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not save activity",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(activity)
+}
+
+// DeleteLeadActivity elle eklenen bir kaydı siler; durum değişiklikleri silinemez
+func DeleteLeadActivity(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	db := database.GetDB()
+
+	var activity model.LeadActivity
+	if err := db.Joins("JOIN leads ON leads.id = lead_activities.lead_id AND leads.deleted_at IS NULL").
+		Where("lead_activities.id = ? AND lead_activities.lead_id = ? AND leads.user_id = ?",
+			c.Params("activity_id"), c.Params("id"), claims.UserID).
+		First(&activity).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Activity not found",
+		})
+	}
+
+	if activity.Type == model.LeadActivityStatusChange {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Status changes cannot be deleted",
+		})
+	}
+
+	if err := db.Delete(&activity).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not delete activity",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// CreateLeadReminder lead için takip hatırlatması oluşturur
+func CreateLeadReminder(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	db := database.GetDB()
+
+	var lead model.Lead
+	if err := db.Where("id = ? AND user_id = ?", c.Params("id"), claims.UserID).First(&lead).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Lead not found",
+		})
+	}
+
+	input := new(LeadReminderInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	if input.DueAt == nil || !input.DueAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "due_at must be in the future",
+		})
+	}
+
+	reminder := model.LeadReminder{
+		LeadID: lead.ID,
+		UserID: claims.UserID,
+		DueAt:  *input.DueAt,
+	}
+	if input.Note != nil {
+		reminder.Note = strings.TrimSpace(*input.Note)
+	}
+	if utf8.RuneCountInString(reminder.Note) > MaxLeadActivityBodyLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Note must be at most %d characters", MaxLeadActivityBodyLength),
+		})
+	}
+
+	if err := db.Create(&reminder).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not create reminder",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(reminder)
+}
+
+// GetMyLeadReminders emlakçının hatırlatmalarını listeler.
+// status: open (varsayılan), overdue, completed
+func GetMyLeadReminders(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	query := database.GetDB().
+		Joins("JOIN leads ON leads.id = lead_reminders.lead_id AND leads.deleted_at IS NULL").
+		Where("lead_reminders.user_id = ?", claims.UserID)
+
+	order := "lead_reminders.due_at ASC"
+	switch c.Query("status", "open") {
+	case "open":
+		query = query.Where("lead_reminders.completed_at IS NULL")
+	case "overdue":
+		query = query.Where("lead_reminders.completed_at IS NULL AND lead_reminders.due_at <= ?", time.Now())
+	case "completed":
+		query = query.Where("lead_reminders.completed_at IS NOT NULL")
+		order = "lead_reminders.completed_at DESC"
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":          "Invalid status value",
+			"valid_statuses": []string{"open", "overdue", "completed"},
+		})
+	}
+
+	reminders := []model.LeadReminder{}
+	if err := query.Preload("Lead").Order(order).Limit(200).Find(&reminders).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch reminders",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"reminders": reminders,
+	})
+}
+
+// UpdateLeadReminder hatırlatmanın zamanını/notunu değiştirir ya da tamamlandı işaretler.
+// Zamanı değişen hatırlatma tekrar bildirilir.
+func UpdateLeadReminder(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	db := database.GetDB()
+
+	var reminder model.LeadReminder
+	if err := db.Where("id = ? AND user_id = ?", c.Params("reminder_id"), claims.UserID).First(&reminder).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Reminder not found",
+		})
+	}
+
+	input := new(LeadReminderInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	if input.DueAt != nil {
+		if !input.DueAt.After(time.Now()) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "due_at must be in the future",
+			})
+		}
+		reminder.DueAt = *input.DueAt
+		reminder.NotifiedAt = nil
+	}
+
+	if input.Note != nil {
+		note := strings.TrimSpace(*input.Note)
+		if utf8.RuneCountInString(note) > MaxLeadActivityBodyLength {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Note must be at most %d characters", MaxLeadActivityBodyLength),
+			})
+		}
+		reminder.Note = note
+	}
+
+	if input.Completed != nil {
+		if *input.Completed && reminder.CompletedAt == nil {
+			now := time.Now()
+			reminder.CompletedAt = &now
+		} else if !*input.Completed {
+			reminder.CompletedAt = nil
+		}
+	}
+
+	if err := db.Model(&reminder).
+		Select("due_at", "note", "completed_at", "notified_at").
+		Updates(&reminder).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not update reminder",
+		})
+	}
+
+	return c.JSON(reminder)
+}
+
+func DeleteLeadReminder(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	result := database.GetDB().Where("id = ? AND user_id = ?", c.Params("reminder_id"), claims.UserID).
+		Delete(&model.LeadReminder{})
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not delete reminder",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Reminder not found",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,281 @@
+package controller
+
+import (
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/email"
+	"estepage_backend/pkg/subscription"
+	"estepage_backend/pkg/utils/jwt"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm/clause"
+)
+
+type LeadAutoReplyInput struct {
+	Enabled bool   `json:"enabled"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// queueLeadAutoReply otomatik yanıtı isteği bekletmeden arka planda gönderir. Kopyalar
+// üzerinde çalışılır; property profil lead'lerinde nil'dir.
+func queueLeadAutoReply(lead model.Lead, agent model.User, property *model.Property) {
+	if property != nil {
+		propertyCopy := *property
+		property = &propertyCopy
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Recovered from panic while sending lead auto-reply for lead %d: %v", lead.ID, r)
+			}
+		}()
+		sendLeadAutoReply(&lead, &agent, property)
+	}()
+}
+
+// sendLeadAutoReply lead gönderen ziyaretçiye emlakçının otomatik yanıtını gönderir.
+// Spam olarak işaretli lead'lere, LeadForm özelliği olmayan planlarda ve alıcı/emlakçı
+// gönderim limitleri aşıldığında gönderilmez.
+func sendLeadAutoReply(lead *model.Lead, agent *model.User, property *model.Property) {
+	if email.GlobalEmailService == nil || lead.SpamFlagged || lead.Email == "" {
+		return
+	}
+
+	db := database.GetDB()
+	if !subscription.CanUseFeature(model.GetUserPlanType(db, agent.ID), subscription.LeadForm) {
+		return
+	}
+
+	reply, err := model.ResolveLeadAutoReply(db, agent.ID, lead.PropertyID)
+	if err != nil {
+		log.Printf("Could not load lead auto-reply for user %d: %v", agent.ID, err)
+		return
+	}
+	if reply == nil {
+		return
+	}
+
+	allowed, err := model.AutoReplyAllowed(db, agent.ID, lead.Email, time.Now())
+	if err != nil {
+		log.Printf("Could not check lead auto-reply limits for user %d: %v", agent.ID, err)
+		return
+	}
+	if !allowed {
+		log.Printf("Lead auto-reply limit reached for lead %d, skipping", lead.ID)
+		return
+	}
+
+	// Gönderimden önce işaretlenir; eşzamanlı gönderimler de limite sayılır
+	if err := db.Model(lead).Update("auto_replied_at", time.Now()).Error; err != nil {
+		log.Printf("Could not mark lead %d as auto-replied: %v", lead.ID, err)
+		return
+	}
+
+	message := reply.Render(model.AutoReplyContext{Lead: lead, Agent: agent, Property: property})
+	data := email.LeadAutoReplyData{
+		LeadName:     model.SanitizeLeadName(lead.Name),
+		AgentName:    message.AgentName,
+		PropertyURL:  message.PropertyURL,
+		WhatsAppLink: message.WhatsAppLink,
+	}
+	if property != nil {
+		data.PropertyTitle = property.Title
+		if cover := property.CoverImage(); cover != nil {
+			data.PropertyImage = cover.URL
+		}
+	}
+
+	replyTo := agent.BusinessEmail
+	if replyTo == "" {
+		replyTo = agent.Email
+	}
+
+	if err := email.GlobalEmailService.SendLeadAutoReplyEmail(lead.Email, replyTo, message.Subject, message.Body, data); err != nil {
+		log.Printf("Could not send lead auto-reply email: %v", err)
+		return
+	}
+
+	// Gönderilen yanıt lead'in zaman akışında görünsün
+	if err := db.Create(&model.LeadActivity{
+		LeadID:     lead.ID,
+		UserID:     agent.ID,
+		Type:       model.LeadActivityEmail,
+		Body:       "Auto-reply sent: " + message.Subject,
+		OccurredAt: time.Now(),
+	}).Error; err != nil {
+		log.Printf("Could not record lead auto-reply activity: %v", err)
+	}
+}
+
+// validateLeadAutoReply şablonun uzunluklarını kontrol eder
+func validateLeadAutoReply(input *LeadAutoReplyInput) error {
+	input.Subject = strings.TrimSpace(input.Subject)
+	input.Body = strings.TrimSpace(input.Body)
+
+	if input.Body == "" {
+		return fmt.Errorf("body is required")
+	}
+	if len(input.Subject) > model.MaxLeadAutoReplySubjectLength {
+		return fmt.Errorf("subject can be at most %d characters", model.MaxLeadAutoReplySubjectLength)
+	}
+	if len(input.Body) > model.MaxLeadAutoReplyBodyLength {
+		return fmt.Errorf("body can be at most %d characters", model.MaxLeadAutoReplyBodyLength)
+	}
+	return nil
+}
+
+// GetLeadAutoReplies emlakçının genel ve ilana özel otomatik yanıt şablonlarını döner
+func GetLeadAutoReplies(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	replies := []model.LeadAutoReply{}
+	if err := database.GetDB().Where("user_id = ?", claims.UserID).
+		Order("property_id ASC").
+		Find(&replies).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch auto-replies",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"auto_replies": replies,
+		"variables":    model.LeadAutoReplyVariables,
+	})
+}
+
+// SaveLeadAutoReply genel (property_id parametresi yoksa) ya da ilana özel şablonu oluşturur veya günceller
+func SaveLeadAutoReply(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	db := database.GetDB()
+
+	var propertyID uint
+	if c.Params("property_id") != "" {
+		var property model.Property
+		if err := db.Select("id").
+			Where("id = ? AND user_id = ?", c.Params("property_id"), claims.UserID).
+			First(&property).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Property not found",
+			})
+		}
+		propertyID = property.ID
+	}
+
+	var input LeadAutoReplyInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+	if err := validateLeadAutoReply(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	reply := model.LeadAutoReply{
+		UserID:     claims.UserID,
+		PropertyID: propertyID,
+		Enabled:    input.Enabled,
+		Subject:    input.Subject,
+		Body:       input.Body,
+	}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "property_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "subject", "body", "updated_at"}),
+	}).Create(&reply).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not save auto-reply",
+		})
+	}
+
+	if err := db.Where("user_id = ? AND property_id = ?", claims.UserID, propertyID).First(&reply).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not save auto-reply",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":    "Auto-reply saved successfully",
+		"auto_reply": reply,
+	})
+}
+
+// DeleteLeadAutoReply şablonu siler; ilana özel şablon silinince genel şablon geçerli olur
+func DeleteLeadAutoReply(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	// Aynı hedef için yeniden oluşturulabilmesi için kalıcı silinir (benzersiz index)
+	result := database.GetDB().Unscoped().
+		Where("id = ? AND user_id = ?", c.Params("id"), claims.UserID).
+		Delete(&model.LeadAutoReply{})
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not delete auto-reply",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Auto-reply not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Auto-reply deleted successfully",
+	})
+}
+
+// PreviewLeadAutoReply şablonu örnek bir lead ile (property_id verilirse o ilanla) doldurur
+func PreviewLeadAutoReply(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	db := database.GetDB()
+
+	input := struct {
+		LeadAutoReplyInput
+		PropertyID uint `json:"property_id"`
+	}{}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+	if err := validateLeadAutoReply(&input.LeadAutoReplyInput); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var agent model.User
+	if err := db.First(&agent, claims.UserID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	var property *model.Property
+	if input.PropertyID != 0 {
+		property = &model.Property{}
+		if err := db.Where("id = ? AND user_id = ?", input.PropertyID, claims.UserID).First(property).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Property not found",
+			})
+		}
+	}
+
+	reply := model.LeadAutoReply{Subject: input.Subject, Body: input.Body}
+	message := reply.Render(model.AutoReplyContext{
+		Lead:     &model.Lead{Name: "Jane Doe"},
+		Agent:    &agent,
+		Property: property,
+	})
+
+	return c.JSON(fiber.Map{
+		"preview": message,
+	})
+}
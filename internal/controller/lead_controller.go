@@ -1,14 +1,19 @@
 package controller
 
 import (
+	"encoding/json"
 	"estepage_backend/internal/model"
 	"estepage_backend/pkg/database"
 	"estepage_backend/pkg/email"
+	"estepage_backend/pkg/utils/antispam"
+	"estepage_backend/pkg/utils/filter"
 	"estepage_backend/pkg/utils/jwt"
+	"estepage_backend/pkg/utils/pagination"
 	"log"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 type LeadInput struct {
@@ -16,10 +21,20 @@ type LeadInput struct {
 	Email   string `json:"email" validate:"required,email"`
 	Phone   string `json:"phone" validate:"required"`
 	Message string `json:"message"`
+	FormProtection
 }
 
 func InitLeadController() {}
 
+// applySpamResult spam değerlendirmesini lead'e işler
+func applySpamResult(lead *model.Lead, result antispam.Result) {
+	lead.SpamScore = result.Score
+	lead.SpamFlagged = result.Flagged
+	if reasons, err := json.Marshal(result.Reasons); err == nil {
+		lead.SpamReasons = reasons
+	}
+}
+
 func CreatePropertyLead(c *fiber.Ctx) error {
 	propertyIDStr := c.Params("property_id")
 	propertyID, err := strconv.ParseUint(propertyIDStr, 10, 32)
@@ -30,7 +45,8 @@ func CreatePropertyLead(c *fiber.Ctx) error {
 	}
 
 	var property model.Property
-	if err := database.GetDB().Preload("Images").Preload("User").First(&property, propertyID).Error; err != nil {
+	if err := database.GetDB().Scopes(model.PublishedProperties).
+		Preload("Images").Preload("User").First(&property, propertyID).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Property not found",
 		})
@@ -43,6 +59,11 @@ func CreatePropertyLead(c *fiber.Ctx) error {
 		})
 	}
 
+	if antispam.IsDisposableEmail(input.Email) {
+		return respondDisposableEmail(c)
+	}
+	spam := antispam.Evaluate(input.submission(c, input.Name, input.Email, input.Message))
+
 	// Property detaylarını pointer olarak set et
 	title := property.Title
 	price := property.Price
@@ -59,21 +80,24 @@ func CreatePropertyLead(c *fiber.Ctx) error {
 		Email:            input.Email,
 		Phone:            input.Phone,
 		Message:          input.Message,
-		Status:           "new",
 		Source:           model.LeadSourceProperty,
 		PropertyTitle:    &title,
 		PropertyPrice:    &price,
 		PropertyImage:    &image,
 		PropertyCurrency: &currency,
 	}
+	applySpamResult(&lead, spam)
 
-	if err := database.GetDB().Create(&lead).Error; err != nil {
+	callingCode := model.LeadCallingCode(property.CountryCode, &property.User)
+	if err := model.CreateLeadWithContact(database.GetDB(), &lead, callingCode); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not create lead",
 		})
 	}
 
-	if email.GlobalEmailService != nil {
+	if spam.Flagged {
+		log.Printf("Lead %d flagged as spam (score %d): %v", lead.ID, spam.Score, spam.Reasons)
+	} else if email.GlobalEmailService != nil {
 		err := email.GlobalEmailService.SendLeadNotificationEmail(
 			property.User.Email,
 			property.Title,
@@ -87,6 +111,8 @@ func CreatePropertyLead(c *fiber.Ctx) error {
 		}
 	}
 
+	queueLeadAutoReply(lead, property.User, &property)
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "Your inquiry has been sent successfully. The agent will contact you soon.",
 	})
@@ -115,23 +141,30 @@ func CreateProfileLead(c *fiber.Ctx) error {
 		})
 	}
 
+	if antispam.IsDisposableEmail(input.Email) {
+		return respondDisposableEmail(c)
+	}
+	spam := antispam.Evaluate(input.submission(c, input.Name, input.Email, input.Message))
+
 	lead := model.Lead{
 		UserID:  uint(userID),
 		Name:    input.Name,
 		Email:   input.Email,
 		Phone:   input.Phone,
 		Message: input.Message,
-		Status:  "new",
 		Source:  model.LeadSourceProfile,
 	}
+	applySpamResult(&lead, spam)
 
-	if err := database.GetDB().Create(&lead).Error; err != nil {
+	if err := model.CreateLeadWithContact(database.GetDB(), &lead, model.LeadCallingCode("", &user)); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not create lead",
 		})
 	}
 
-	if email.GlobalEmailService != nil {
+	if spam.Flagged {
+		log.Printf("Lead %d flagged as spam (score %d): %v", lead.ID, spam.Score, spam.Reasons)
+	} else if email.GlobalEmailService != nil {
 		err := email.GlobalEmailService.SendLeadNotificationEmail(
 			user.Email,
 			"Profile Lead",
@@ -145,16 +178,89 @@ func CreateProfileLead(c *fiber.Ctx) error {
 		}
 	}
 
+	queueLeadAutoReply(lead, user, nil)
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "Your message has been sent successfully. The agent will contact you soon.",
 	})
 }
 
+// Lead listesinde izin verilen sıralama ve alan seçimi
+var leadListOptions = pagination.Options{
+	IDColumn:    "leads.id",
+	DefaultSort: "-created_at",
+	SortKeys: map[string]string{
+		"created_at": "leads.created_at",
+		"updated_at": "leads.updated_at",
+		"name":       "leads.name",
+		"status":     "leads.status",
+		"source":     "leads.source",
+	},
+	Fields: []string{
+		"CreatedAt", "UpdatedAt", "user_id", "property_id", "source", "name", "email", "phone",
+		"message", "status", "read_status", "property_title", "property_price",
+		"property_image", "property_currency", "stage_id", "closed_at",
+		"contact_id", "spam_score", "spam_flagged", "spam_reasons",
+	},
+}
+
+// Lead listesinde filter parametresiyle kullanılabilecek alanlar
+var leadFilterSchema = filter.Schema{
+	"status":     {Column: "leads.status", Type: filter.String}, // Aşama key'leri emlakçıya özel
+	"stage_id":   {Column: "leads.stage_id", Type: filter.Int},
+	"contact_id": {Column: "leads.contact_id", Type: filter.Int},
+	"spam_score": {Column: "leads.spam_score", Type: filter.Int},
+	"source": {Column: "leads.source", Type: filter.Enum, Values: []string{
+		string(model.LeadSourceProfile),
+		string(model.LeadSourceProperty),
+	}},
+	"read_status": {Column: "leads.read_status", Type: filter.Bool},
+	"property_id": {Column: "leads.property_id", Type: filter.Int},
+	"created_at":  {Column: "leads.created_at", Type: filter.Time},
+	"updated_at":  {Column: "leads.updated_at", Type: filter.Time},
+	"closed_at":   {Column: "leads.closed_at", Type: filter.Time},
+	"name":        {Column: "leads.name", Type: filter.String},
+	"email":       {Column: "leads.email", Type: filter.String},
+	"phone":       {Column: "leads.phone", Type: filter.String},
+}
+
+// spamScope spam parametresine göre işaretli lead'leri gizler (varsayılan),
+// dahil eder (include) ya da yalnızca onları listeler (only)
+func spamScope(spam string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch spam {
+		case "include":
+			return db
+		case "only":
+			return db.Where("leads.spam_flagged = ?", true)
+		default:
+			return db.Where("leads.spam_flagged = ?", false)
+		}
+	}
+}
+
+// GetMyLeads emlakçının lead'lerini listeler. Spam olarak işaretlenenler spam=include|only verilmedikçe gizlenir.
+// filter parametresi: status:in(new,contacted) created_at:>2026-01-01 sort:-created_at
 func GetMyLeads(c *fiber.Ctx) error {
 	claims := c.Locals("user").(*jwt.Claims)
 
-	var leads []model.Lead
-	query := database.GetDB().Where("user_id = ?", claims.UserID)
+	params, err := pagination.Parse(c, leadListOptions)
+	if err != nil {
+		return respondListParamError(c, err)
+	}
+
+	expr, err := filter.Parse(c.Query("filter"), leadFilterSchema)
+	if err != nil {
+		return respondListParamError(c, err)
+	}
+	if expr.Sort != "" {
+		if err := params.SetSort(expr.Sort, leadListOptions); err != nil {
+			return respondListParamError(c, err)
+		}
+	}
+
+	query := expr.Apply(database.GetDB().Model(&model.Lead{}).Where("user_id = ?", claims.UserID), leadFilterSchema).
+		Scopes(spamScope(c.Query("spam")))
 
 	// Filtreler
 	if status := c.Query("status"); status != "" {
@@ -173,22 +279,33 @@ func GetMyLeads(c *fiber.Ctx) error {
 		query = query.Where("source = ?", source)
 	}
 
-	// Sıralama
-	if sortBy := c.Query("sort"); sortBy != "" {
-		query = query.Order(sortBy)
-	} else {
-		query = query.Order("created_at desc")
+	ids, meta, err := params.Paginate(query)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch leads",
+		})
+	}
+
+	leads := []model.Lead{}
+	if len(ids) > 0 {
+		if err := params.Ordered(database.GetDB(), ids).Find(&leads).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not fetch leads",
+			})
+		}
 	}
 
-	if err := query.Find(&leads).Error; err != nil {
+	projected, err := params.Project(leads)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not fetch leads",
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"leads": leads,
-		"total": len(leads),
+		"leads":      projected,
+		"total":      meta.Total,
+		"pagination": meta,
 	})
 }
 
@@ -209,8 +326,10 @@ func UpdateLeadStatus(c *fiber.Ctx) error {
 		})
 	}
 
+	// status aşamanın key'i; stage_id ile de seçilebilir
 	input := struct {
-		Status string `json:"status"`
+		Status  string `json:"status"`
+		StageID uint   `json:"stage_id"`
 	}{}
 
 	if err := c.BodyParser(&input); err != nil {
@@ -219,33 +338,46 @@ func UpdateLeadStatus(c *fiber.Ctx) error {
 		})
 	}
 
-	// Status kontrolü
-	switch model.LeadStatus(input.Status) {
-	case model.LeadStatusNew,
-		model.LeadStatusRead,
-		model.LeadStatusContacted,
-		model.LeadStatusNoResponse,
-		model.LeadStatusCompleted:
-		// Geçerli status
-	default:
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid status value",
-			"valid_statuses": []string{
-				string(model.LeadStatusNew),
-				string(model.LeadStatusRead),
-				string(model.LeadStatusContacted),
-				string(model.LeadStatusNoResponse),
-				string(model.LeadStatusCompleted),
-			},
+	stages, err := model.LeadStages(database.GetDB(), claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch lead pipeline",
 		})
 	}
 
-	if err := database.GetDB().Model(&lead).Update("status", input.Status).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Could not update lead status",
+	var stage *model.LeadStage
+	validStatuses := make([]string, 0, len(stages))
+	for i := range stages {
+		validStatuses = append(validStatuses, stages[i].Key)
+		if (input.StageID != 0 && stages[i].ID == input.StageID) ||
+			(input.StageID == 0 && stages[i].Key == input.Status) {
+			stage = &stages[i]
+		}
+	}
+	if stage == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":          "Invalid status value",
+			"valid_statuses": validStatuses,
 		})
 	}
 
+	if lead.StageID == nil || *lead.StageID != stage.ID {
+		tx := database.GetDB().Begin()
+
+		if err := model.MoveLeadToStage(tx, &lead, stage, claims.UserID); err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not update lead status",
+			})
+		}
+
+		if err := tx.Commit().Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not update lead status",
+			})
+		}
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Lead status updated successfully",
 		"lead":    lead,
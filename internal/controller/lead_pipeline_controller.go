@@ -0,0 +1,418 @@
+package controller
+
+import (
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/utils/jwt"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	MaxLeadStageNameLength = 100
+	// Kanban görünümünde aşama başına varsayılan/en fazla lead sayısı
+	defaultBoardLeadLimit = 20
+	maxBoardLeadLimit     = 100
+)
+
+var stageColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type LeadStageInput struct {
+	Name    *string `json:"name"`
+	Outcome *string `json:"outcome"`
+	Color   *string `json:"color"`
+}
+
+// applyLeadStageInput girdiyi doğrulayıp aşamaya uygular
+func applyLeadStageInput(stage *model.LeadStage, input *LeadStageInput) error {
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" || utf8.RuneCountInString(name) > MaxLeadStageNameLength {
+			return fmt.Errorf("name must be between 1 and %d characters", MaxLeadStageNameLength)
+		}
+		stage.Name = name
+	}
+	if input.Outcome != nil {
+		outcome := model.StageOutcome(*input.Outcome)
+		if !model.StageOutcomes[outcome] {
+			return fmt.Errorf("outcome must be one of open, won, lost")
+		}
+		stage.Outcome = outcome
+	}
+	if input.Color != nil {
+		if *input.Color != "" && !stageColorPattern.MatchString(*input.Color) {
+			return fmt.Errorf("color must be a hex value like #1f2937")
+		}
+		stage.Color = *input.Color
+	}
+	return nil
+}
+
+// GetLeadPipeline emlakçının pipeline aşamalarını sırasıyla döner
+func GetLeadPipeline(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	stages, err := model.LeadStages(database.GetDB(), claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch lead pipeline",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"stages": stages,
+	})
+}
+
+// CreateLeadStage pipeline'ın sonuna yeni aşama ekler
+func CreateLeadStage(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	db := database.GetDB()
+
+	input := new(LeadStageInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+	if input.Name == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "name is required",
+		})
+	}
+
+	stages, err := model.LeadStages(db, claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch lead pipeline",
+		})
+	}
+	if len(stages) >= model.MaxLeadStages {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Pipeline can have at most %d stages", model.MaxLeadStages),
+		})
+	}
+
+	stage := model.LeadStage{
+		UserID:  claims.UserID,
+		Outcome: model.StageOutcomeOpen,
+		Order:   stages[len(stages)-1].Order + 1,
+	}
+	if err := applyLeadStageInput(&stage, input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	stage.Key = model.NewLeadStageKey(stage.Name, stages)
+
+	if err := db.Create(&stage).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not create stage",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(stage)
+}
+
+// UpdateLeadStage aşamanın adını, rengini ya da sonucunu değiştirir. Key değişmez.
+// Sonucu değişen aşamadaki lead'lerin kapanış zamanı da güncellenir.
+func UpdateLeadStage(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	input := new(LeadStageInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	stages, err := model.LeadStages(database.GetDB(), claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch lead pipeline",
+		})
+	}
+
+	var stage *model.LeadStage
+	for i := range stages {
+		if fmt.Sprint(stages[i].ID) == c.Params("stage_id") {
+			stage = &stages[i]
+		}
+	}
+	if stage == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Stage not found",
+		})
+	}
+
+	wasClosed := stage.IsClosed()
+	if err := applyLeadStageInput(stage, input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := model.ValidatePipeline(stages); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(stage).Select("name", "outcome", "color").Updates(stage).Error; err != nil {
+			return err
+		}
+
+		if wasClosed == stage.IsClosed() {
+			return nil
+		}
+		leads := tx.Model(&model.Lead{}).Where("user_id = ? AND stage_id = ?", claims.UserID, stage.ID)
+		if stage.IsClosed() {
+			return leads.Where("closed_at IS NULL").Update("closed_at", time.Now()).Error
+		}
+		return leads.Update("closed_at", nil).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not update stage",
+		})
+	}
+
+	return c.JSON(stage)
+}
+
+// ReorderLeadStages aşamaları yeniden sıralar; order tüm aşama ID'lerini içermelidir
+func ReorderLeadStages(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	input := struct {
+		Order []uint `json:"order"`
+	}{}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	stages, err := model.LeadStages(database.GetDB(), claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch lead pipeline",
+		})
+	}
+
+	if len(input.Order) != len(stages) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("order must contain all %d stages", len(stages)),
+		})
+	}
+
+	byID := make(map[uint]model.LeadStage, len(stages))
+	for _, stage := range stages {
+		byID[stage.ID] = stage
+	}
+
+	ordered := make([]model.LeadStage, 0, len(stages))
+	for i, id := range input.Order {
+		stage, ok := byID[id]
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("invalid stage %d in order", id),
+			})
+		}
+		delete(byID, id)
+		stage.Order = i
+		ordered = append(ordered, stage)
+	}
+
+	if err := model.ValidatePipeline(ordered); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		for _, stage := range ordered {
+			if err := tx.Model(&model.LeadStage{}).Where("id = ?", stage.ID).Update("order", stage.Order).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not reorder stages",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"stages": ordered,
+	})
+}
+
+// DeleteLeadStage aşamayı siler. Aşamada lead varsa move_to ile taşınacak aşama verilmelidir.
+func DeleteLeadStage(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	db := database.GetDB()
+
+	stages, err := model.LeadStages(db, claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch lead pipeline",
+		})
+	}
+
+	var stage, target *model.LeadStage
+	remaining := make([]model.LeadStage, 0, len(stages))
+	for i := range stages {
+		switch fmt.Sprint(stages[i].ID) {
+		case c.Params("stage_id"):
+			stage = &stages[i]
+			continue
+		case c.Query("move_to"):
+			target = &stages[i]
+		}
+		remaining = append(remaining, stages[i])
+	}
+	if stage == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Stage not found",
+		})
+	}
+	if err := model.ValidatePipeline(remaining); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var leadCount int64
+	if err := db.Model(&model.Lead{}).Where("user_id = ? AND stage_id = ?", claims.UserID, stage.ID).
+		Count(&leadCount).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not count stage leads",
+		})
+	}
+	if leadCount > 0 && target == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      "Stage has leads; provide move_to with another stage ID",
+			"lead_count": leadCount,
+		})
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Eşzamanlı oluşturulan lead'ler silinen aşamaya bağlanmasın diye kullanıcı kilitlenir
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").First(&model.User{}, claims.UserID).Error; err != nil {
+			return err
+		}
+
+		// Sayımdan sonra eklenen lead'ler de taşınır; hedef yoksa ilk aşamaya
+		if target == nil {
+			target = &remaining[0]
+		}
+		var leads []model.Lead
+		if err := tx.Where("user_id = ? AND stage_id = ?", claims.UserID, stage.ID).Find(&leads).Error; err != nil {
+			return err
+		}
+		for i := range leads {
+			if err := model.MoveLeadToStage(tx, &leads[i], target, claims.UserID); err != nil {
+				return err
+			}
+		}
+
+		// Key tekrar kullanılabilsin diye kalıcı olarak silinir
+		return tx.Unscoped().Delete(stage).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not delete stage",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetLeadBoard lead'leri aşamalara göre gruplar (kanban); her aşamanın toplam lead sayısı
+// ve en yeni lead'leri döner. Parametreler: limit (aşama başına), property_id, spam
+func GetLeadBoard(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	db := database.GetDB()
+
+	limit := c.QueryInt("limit", defaultBoardLeadLimit)
+	if limit < 0 || limit > maxBoardLeadLimit {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("limit must be between 0 and %d", maxBoardLeadLimit),
+		})
+	}
+
+	stages, err := model.LeadStages(db, claims.UserID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch lead pipeline",
+		})
+	}
+
+	base := func() *gorm.DB {
+		query := db.Model(&model.Lead{}).Where("user_id = ?", claims.UserID).
+			Scopes(spamScope(c.Query("spam")))
+		if propertyID := c.QueryInt("property_id"); propertyID > 0 {
+			query = query.Where("property_id = ?", propertyID)
+		}
+		return query
+	}
+
+	var counts []struct {
+		StageID *uint
+		Count   int64
+	}
+	if err := base().Select("stage_id, COUNT(*) AS count").Group("stage_id").Scan(&counts).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not count leads",
+		})
+	}
+	countByStage := make(map[uint]int64, len(counts))
+	for _, row := range counts {
+		if row.StageID != nil {
+			countByStage[*row.StageID] = row.Count
+		}
+	}
+
+	totals := fiber.Map{
+		string(model.StageOutcomeOpen): int64(0),
+		string(model.StageOutcomeWon):  int64(0),
+		string(model.StageOutcomeLost): int64(0),
+	}
+	columns := make([]fiber.Map, 0, len(stages))
+	for _, stage := range stages {
+		leads := []model.Lead{}
+		if limit > 0 {
+			if err := base().Where("stage_id = ?", stage.ID).
+				Order("created_at DESC").
+				Limit(limit).
+				Find(&leads).Error; err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Could not fetch leads",
+				})
+			}
+		}
+
+		count := countByStage[stage.ID]
+		totals[string(stage.Outcome)] = totals[string(stage.Outcome)].(int64) + count
+		columns = append(columns, fiber.Map{
+			"stage": stage,
+			"count": count,
+			"leads": leads,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"columns": columns,
+		"totals":  totals,
+	})
+}
@@ -3,7 +3,9 @@ package controller
 import (
 	"estepage_backend/internal/model"
 	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/utils/antispam"
 	"estepage_backend/pkg/utils/jwt"
+	"estepage_backend/pkg/utils/pagination"
 	"net/mail"
 	"strconv"
 	"strings"
@@ -15,6 +17,7 @@ import (
 type NewsletterSubscriptionInput struct {
 	Name  string `json:"name"`
 	Email string `json:"email" validate:"required,email"`
+	FormProtection
 }
 
 const (
@@ -80,6 +83,16 @@ func PublicSubscribe(c *fiber.Ctx) error {
 		})
 	}
 
+	if antispam.IsDisposableEmail(input.Email) {
+		return respondDisposableEmail(c)
+	}
+	// Bülten aboneliğinde inceleme kuyruğu yok; işaretli gönderimler reddedilir
+	if spam := antispam.Evaluate(input.submission(c, input.Name, input.Email, "")); spam.Flagged {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Could not verify your submission. Please reload the page and try again.",
+		})
+	}
+
 	var existingSubscriber model.NewsletterSubscriber
 	if err := database.GetDB().Where("user_id = ? AND email = ?", userID, input.Email).
 		First(&existingSubscriber).Error; err == nil {
@@ -109,6 +122,17 @@ func PublicSubscribe(c *fiber.Ctx) error {
 	})
 }
 
+// Abone listesinde izin verilen sıralamalar
+var subscriberListOptions = pagination.Options{
+	IDColumn:    "newsletter_subscribers.id",
+	DefaultSort: "-subscribed_at",
+	SortKeys: map[string]string{
+		"subscribed_at": "newsletter_subscribers.subscribed_at",
+		"name":          "newsletter_subscribers.name",
+		"email":         "newsletter_subscribers.email",
+	},
+}
+
 func GetMySubscribers(c *fiber.Ctx) error {
 	claims := c.Locals("user").(*jwt.Claims)
 
@@ -120,21 +144,34 @@ func GetMySubscribers(c *fiber.Ctx) error {
 		SubscribedAt time.Time `json:"join_date"`
 	}
 
-	var subscribers []SubscriberResponse
+	params, err := pagination.Parse(c, subscriberListOptions)
+	if err != nil {
+		return respondListParamError(c, err)
+	}
 
-	if err := database.GetDB().Model(&model.NewsletterSubscriber{}).
-		Select("id, name, email, source, subscribed_at").
-		Where("user_id = ?", claims.UserID).
-		Order("subscribed_at DESC").
-		Find(&subscribers).Error; err != nil {
+	ids, meta, err := params.Paginate(database.GetDB().Model(&model.NewsletterSubscriber{}).
+		Where("user_id = ?", claims.UserID))
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not fetch subscribers",
 		})
 	}
 
+	subscribers := []SubscriberResponse{}
+	if len(ids) > 0 {
+		if err := params.Ordered(database.GetDB().Model(&model.NewsletterSubscriber{}), ids).
+			Select("id, name, email, source, subscribed_at").
+			Find(&subscribers).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not fetch subscribers",
+			})
+		}
+	}
+
 	return c.JSON(fiber.Map{
 		"subscribers": subscribers,
-		"total":       len(subscribers),
+		"total":       meta.Total,
+		"pagination":  meta,
 	})
 }
 
@@ -0,0 +1,411 @@
+package controller
+
+import (
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/subscription"
+	"estepage_backend/pkg/utils/cloudflare"
+	"estepage_backend/pkg/utils/jwt"
+	"estepage_backend/pkg/utils/storage"
+	"estepage_backend/pkg/utils/validation"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	MaxAttachmentTitleLength = 200
+	// AttachmentDownloadExpiry gizli dokümanlar için üretilen indirme linkinin süresi
+	AttachmentDownloadExpiry = 15 * time.Minute
+	// AttachmentFormOverhead multipart formun dosya dışındaki alanları ve sınırları için pay
+	AttachmentFormOverhead = 1024 * 1024
+)
+
+type AttachmentInput struct {
+	Type     string `json:"type" form:"type"`
+	Title    string `json:"title" form:"title"`
+	URL      string `json:"url" form:"url"` // Video ve sanal tur için
+	IsPublic *bool  `json:"is_public" form:"is_public"`
+}
+
+type AttachmentMetadataInput struct {
+	ID       uint    `json:"id"`
+	Title    *string `json:"title"`
+	IsPublic *bool   `json:"is_public"`
+}
+
+type PropertyAttachmentsInput struct {
+	Order       []uint                    `json:"order"` // İlanın tüm ek ID'leri, yeni sırasıyla
+	Attachments []AttachmentMetadataInput `json:"attachments"`
+}
+
+// withDownloadURL dosya eklerine sahibin kullanabileceği indirme linkini ekler
+func withDownloadURL(attachment *model.PropertyAttachment) {
+	if attachment.ObjectKey == "" {
+		return
+	}
+	if attachment.IsPublic {
+		attachment.DownloadURL = attachment.URL
+		return
+	}
+
+	// Gizli ekler özel depolamadadır; link sadece süresi boyunca çalışır
+	signed, err := cloudflare.SignedAttachmentURL(attachment.ObjectKey, AttachmentDownloadExpiry)
+	if err != nil {
+		log.Printf("Error signing attachment %d: %v", attachment.ID, err)
+		return
+	}
+	attachment.DownloadURL = signed
+}
+
+// ListPropertyAttachments ilanın tüm eklerini (gizliler dahil) listeler
+func ListPropertyAttachments(c *fiber.Ctx) error {
+	attachments := []model.PropertyAttachment{}
+	if err := database.GetDB().Where("property_id = ?", c.Params("id")).
+		Order(`"order" ASC, id ASC`).
+		Find(&attachments).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch attachments",
+		})
+	}
+
+	for i := range attachments {
+		withDownloadURL(&attachments[i])
+	}
+
+	return c.JSON(fiber.Map{
+		"attachments": attachments,
+	})
+}
+
+// CreatePropertyAttachment kat planı/doküman yükler (multipart "file") ya da video/sanal tur linki ekler
+func CreatePropertyAttachment(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	// Sunucu gövde limiti en büyük plana göredir; kullanıcının planı burada uygulanır
+	planType := model.GetUserPlanType(database.GetDB(), claims.UserID)
+	maxSize := subscription.GetPlanMaxAttachmentSize(planType)
+	if int64(c.Request().Header.ContentLength()) > maxSize+AttachmentFormOverhead {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error":    "File size exceeds your plan limit",
+			"max_size": maxSize,
+		})
+	}
+
+	input := new(AttachmentInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	attachmentType := model.AttachmentType(input.Type)
+	if !model.AttachmentTypes[attachmentType] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid attachment type",
+		})
+	}
+
+	title := strings.TrimSpace(input.Title)
+	if utf8.RuneCountInString(title) > MaxAttachmentTitleLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Title must be at most %d characters", MaxAttachmentTitleLength),
+		})
+	}
+
+	db := database.GetDB()
+
+	var property model.Property
+	if err := db.Preload("User").First(&property, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Property not found",
+		})
+	}
+
+	limits := subscription.GetPlanLimits(planType)
+
+	var count int64
+	if err := db.Model(&model.PropertyAttachment{}).Where("property_id = ?", property.ID).Count(&count).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not check attachment count",
+		})
+	}
+	if int(count) >= limits.MaxAttachmentsPerList {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":         "You have reached the maximum attachment limit for this listing",
+			"current_count": count,
+			"max_limit":     limits.MaxAttachmentsPerList,
+		})
+	}
+
+	attachment := model.PropertyAttachment{
+		PropertyID: property.ID,
+		Type:       attachmentType,
+		Title:      title,
+		IsPublic:   input.IsPublic == nil || *input.IsPublic,
+		Order:      int(count),
+	}
+
+	if attachmentType.IsLink() {
+		link := strings.TrimSpace(input.URL)
+		if err := validation.ValidateLink(link); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		attachment.URL = link
+	} else {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "No file provided",
+			})
+		}
+		if file.Size > maxSize {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":    "File size exceeds your plan limit",
+				"max_size": maxSize,
+			})
+		}
+
+		src, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Could not read file",
+			})
+		}
+		data, err := io.ReadAll(src)
+		src.Close()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Could not read file",
+			})
+		}
+
+		contentType, err := validation.DetectAttachmentType(data)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if attachmentType == model.AttachmentDocument && contentType != "application/pdf" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": validation.ErrPDFRequired.Error(),
+			})
+		}
+
+		key, storedType, size, err := cloudflare.UploadAttachment(data, contentType, property.User.Username, property.Slug, attachment.IsPublic)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": fmt.Sprintf("Could not upload attachment: %v", err),
+			})
+		}
+
+		attachment.ObjectKey = key
+		attachment.FileName = file.Filename
+		attachment.ContentType = storedType
+		attachment.Size = size
+		if attachment.IsPublic {
+			attachment.URL = storage.GlobalStorage.URL(key)
+		}
+	}
+
+	if attachment.Title == "" {
+		attachment.Title = attachment.FileName
+	}
+
+	if err := db.Create(&attachment).Error; err != nil {
+		if attachment.ObjectKey != "" {
+			if delErr := cloudflare.DeleteAttachment(attachment.ObjectKey, attachment.IsPublic); delErr != nil {
+				log.Printf("Error deleting attachment object %s: %v", attachment.ObjectKey, delErr)
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not save attachment",
+		})
+	}
+
+	withDownloadURL(&attachment)
+	return c.Status(fiber.StatusCreated).JSON(attachment)
+}
+
+// UpdatePropertyAttachments ekleri sıralar, başlık ve görünürlüklerini günceller
+func UpdatePropertyAttachments(c *fiber.Ctx) error {
+	input := new(PropertyAttachmentsInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	tx := database.GetDB().Begin()
+
+	var property model.Property
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("User").First(&property, c.Params("id")).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Property not found",
+		})
+	}
+
+	var attachments []model.PropertyAttachment
+	if err := tx.Where("property_id = ?", property.ID).Order(`"order" ASC, id ASC`).Find(&attachments).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch attachments",
+		})
+	}
+
+	byID := make(map[uint]*model.PropertyAttachment, len(attachments))
+	for i := range attachments {
+		byID[attachments[i].ID] = &attachments[i]
+	}
+
+	if input.Order != nil {
+		if len(input.Order) != len(attachments) {
+			tx.Rollback()
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("order must contain all %d attachments of the property", len(attachments)),
+			})
+		}
+		seen := map[uint]bool{}
+		for i, id := range input.Order {
+			if byID[id] == nil || seen[id] {
+				tx.Rollback()
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": fmt.Sprintf("invalid attachment %d in order", id),
+				})
+			}
+			seen[id] = true
+			byID[id].Order = i
+		}
+	} else {
+		// Mevcut sırayı normalize et
+		for i := range attachments {
+			attachments[i].Order = i
+		}
+	}
+
+	// Görünürlüğü değişen eklerin eski depolamadaki dosyaları commit sonrası silinir
+	var staleFiles []model.PropertyAttachment
+	for _, meta := range input.Attachments {
+		attachment := byID[meta.ID]
+		if attachment == nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("attachment %d does not belong to this property", meta.ID),
+			})
+		}
+
+		if meta.Title != nil {
+			title := strings.TrimSpace(*meta.Title)
+			if utf8.RuneCountInString(title) > MaxAttachmentTitleLength {
+				tx.Rollback()
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": fmt.Sprintf("Title must be at most %d characters", MaxAttachmentTitleLength),
+				})
+			}
+			attachment.Title = title
+		}
+
+		if meta.IsPublic != nil && *meta.IsPublic != attachment.IsPublic {
+			wasPublic := attachment.IsPublic
+			attachment.IsPublic = *meta.IsPublic
+			if attachment.ObjectKey == "" {
+				continue
+			}
+
+			// Dosya görünürlüğüne göre public bucket ile özel depolama arasında taşınır;
+			// gizlenen dosyanın eski herkese açık adresi çalışmaya devam etmez
+			newKey, err := cloudflare.MoveAttachment(attachment.ObjectKey, attachment.ContentType, property.User.Username, property.Slug, attachment.IsPublic)
+			if err != nil {
+				tx.Rollback()
+				log.Printf("Error moving attachment %d: %v", attachment.ID, err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Could not update attachment visibility",
+				})
+			}
+			staleFiles = append(staleFiles, model.PropertyAttachment{ObjectKey: attachment.ObjectKey, IsPublic: wasPublic})
+			attachment.ObjectKey = newKey
+			attachment.URL = ""
+			if attachment.IsPublic {
+				attachment.URL = storage.GlobalStorage.URL(newKey)
+			}
+		}
+	}
+
+	for i := range attachments {
+		attachment := &attachments[i]
+		if err := tx.Model(attachment).
+			Select("order", "title", "is_public", "url", "object_key").
+			Updates(attachment).Error; err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not update attachments",
+			})
+		}
+	}
+
+	// Başarısız olursa yeni kopyalar referanssız kalır ve depolama temizliğinde silinir
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not update attachments",
+		})
+	}
+
+	for _, stale := range staleFiles {
+		if err := cloudflare.DeleteAttachment(stale.ObjectKey, stale.IsPublic); err != nil {
+			log.Printf("Error deleting attachment object %s: %v", stale.ObjectKey, err)
+		}
+	}
+
+	ordered := make([]model.PropertyAttachment, len(attachments))
+	for _, attachment := range attachments {
+		withDownloadURL(&attachment)
+		ordered[attachment.Order] = attachment
+	}
+
+	return c.JSON(fiber.Map{
+		"attachments": ordered,
+	})
+}
+
+func DeletePropertyAttachment(c *fiber.Ctx) error {
+	db := database.GetDB()
+
+	var attachment model.PropertyAttachment
+	if err := db.Where("id = ? AND property_id = ?", c.Params("attachment_id"), c.Params("id")).
+		First(&attachment).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Attachment not found",
+		})
+	}
+
+	if err := db.Delete(&attachment).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not delete attachment",
+		})
+	}
+
+	// Kalan eklerin sırasını boşluksuz hale getir
+	if err := db.Model(&model.PropertyAttachment{}).
+		Where(`property_id = ? AND "order" > ?`, attachment.PropertyID, attachment.Order).
+		Update("order", gorm.Expr(`"order" - 1`)).Error; err != nil {
+		log.Printf("Error reordering attachments: %v", err)
+	}
+
+	if attachment.ObjectKey != "" {
+		if err := cloudflare.DeleteAttachment(attachment.ObjectKey, attachment.IsPublic); err != nil {
+			log.Printf("Error deleting attachment object %s: %v", attachment.ObjectKey, err)
+		}
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -5,10 +5,15 @@ import (
 	"estepage_backend/pkg/database"
 	"estepage_backend/pkg/utils/cloudflare"
 	"estepage_backend/pkg/utils/jwt"
+	"estepage_backend/pkg/utils/location"
+	"estepage_backend/pkg/utils/pagination"
+	"estepage_backend/pkg/utils/storage"
 
 	"fmt"
-	"os"
+	"log"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -39,6 +44,10 @@ type PropertyInput struct {
 	District    string `json:"district"`
 	FullAddress string `json:"full_address" validate:"required"`
 
+	// Opsiyonel harita konumu
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+
 	// Features fields
 	Bedrooms        int  `json:"bedrooms" binding:"required,min=0"` // Minimum 0, zorunlu
 	Bathrooms       int  `json:"bathrooms" binding:"required,min=0"`
@@ -53,6 +62,229 @@ type PropertyInput struct {
 
 	Images   []string               `json:"images"`
 	Features []PropertyFeatureInput `json:"features"`
+
+	// Yayın durumu sadece oluştururken alınır; sonrası için /publication endpoint'i kullanılır
+	PublicationState model.PublicationState `json:"publication_state"`
+	PublishAt        *time.Time             `json:"publish_at"`
+}
+
+type PublicationInput struct {
+	State     model.PublicationState `json:"state" validate:"required"`
+	PublishAt *time.Time             `json:"publish_at"`
+}
+
+// validatePublicationChange geçişin izinli olduğunu ve planlı yayın zamanının gelecekte olduğunu kontrol eder
+func validatePublicationChange(current, next model.PublicationState, publishAt *time.Time) error {
+	if !next.IsValid() {
+		return fmt.Errorf("invalid publication state: %s", next)
+	}
+	if current != next && !current.CanTransitionTo(next) {
+		return fmt.Errorf("cannot change publication state from %s to %s", current, next)
+	}
+	if next == model.PublicationScheduled {
+		if publishAt == nil {
+			return fmt.Errorf("publish_at is required for scheduled listings")
+		}
+		if !publishAt.After(time.Now()) {
+			return fmt.Errorf("publish_at must be in the future")
+		}
+	}
+	return nil
+}
+
+// İlan listelerinde izin verilen sıralama ve alan seçimi
+var propertyListOptions = pagination.Options{
+	IDColumn:    "properties.id",
+	DefaultSort: "-created_at",
+	SortKeys: map[string]string{
+		"created_at": "properties.created_at",
+		"updated_at": "properties.updated_at",
+		"price":      "properties.price",
+		"title":      "properties.title",
+		"bedrooms":   "properties.bedrooms",
+		"area_sq_ft": "properties.area_sq_ft",
+	},
+	Fields: []string{
+		"CreatedAt", "UpdatedAt", "title", "slug", "type", "status", "price", "currency",
+		"description", "user_id", "country_code", "country_name", "state_code", "state_name",
+		"city", "district", "full_address", "latitude", "longitude", "location_approximate",
+		"bedrooms", "bathrooms", "garage_spaces", "area_sq_ft", "year_built", "swimming_pool",
+		"garden", "air_conditioning", "central_heating", "security_system", "images", "features",
+	},
+}
+
+// paginateProperties filtrelenmiş ilan sorgusunu sayfalar ve resimleriyle yükler
+func paginateProperties(query *gorm.DB, params *pagination.Params) ([]model.Property, pagination.Meta, error) {
+	ids, meta, err := params.Paginate(query)
+	if err != nil {
+		return nil, meta, err
+	}
+
+	properties := []model.Property{}
+	if len(ids) == 0 {
+		return properties, meta, nil
+	}
+
+	err = params.Ordered(database.GetDB().Model(&model.Property{}), ids).
+		Preload("Images", func(db *gorm.DB) *gorm.DB {
+			return db.Order("property_images.order ASC")
+		}).
+		Find(&properties).Error
+	return properties, meta, err
+}
+
+// respondListParamError sayfalama, sıralama ve filtre parametre hatalarını 400 olarak döner
+func respondListParamError(c *fiber.Ctx, err error) error {
+	if paramErr, ok := err.(interface{ Response() fiber.Map }); ok {
+		return c.Status(fiber.StatusBadRequest).JSON(paramErr.Response())
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// resolvePropertyCoordinates emlakçının işaretlediği konumu doğrular,
+// konum yoksa şehir/il merkezini yaklaşık konum olarak kullanır
+func resolvePropertyCoordinates(input *PropertyInput) (*float64, *float64, bool, error) {
+	if input.Latitude != nil || input.Longitude != nil {
+		if input.Latitude == nil || input.Longitude == nil {
+			return nil, nil, false, fmt.Errorf("latitude and longitude must be provided together")
+		}
+		if !location.ValidCoordinates(*input.Latitude, *input.Longitude) {
+			return nil, nil, false, fmt.Errorf("invalid coordinates")
+		}
+		return input.Latitude, input.Longitude, false, nil
+	}
+
+	lat, lng, ok := location.Centroid(input.CountryCode, input.StateCode, input.City)
+	if !ok {
+		return nil, nil, false, nil
+	}
+	return &lat, &lng, true, nil
+}
+
+// cloudflareIDFromURL yüklenen dosyanın adını (uzantısız, varyant eki olmadan) ID olarak döner.
+// UploadImage aynı ID'yi dosya adı olarak kullanır; boş ID'ler unique indekste çakışır.
+func cloudflareIDFromURL(imageURL string) string {
+	name := path.Base(imageURL)
+	return strings.TrimSuffix(strings.TrimSuffix(name, path.Ext(name)), "-full")
+}
+
+// newPropertyFromInput input'tan yeni bir ilan modeli oluşturur (yayın durumu hariç)
+func newPropertyFromInput(userID uint, input *PropertyInput, latitude, longitude *float64, approximate bool) model.Property {
+	return model.Property{
+		UserID:              userID,
+		Title:               input.Title,
+		Description:         input.Description,
+		Price:               input.Price,
+		Type:                input.Type,
+		Status:              input.Status,
+		Currency:            input.Currency,
+		CountryCode:         input.CountryCode,
+		CountryName:         input.CountryName,
+		StateCode:           input.StateCode,
+		StateName:           input.StateName,
+		City:                input.City,
+		District:            input.District,
+		FullAddress:         input.FullAddress,
+		Latitude:            latitude,
+		Longitude:           longitude,
+		LocationApproximate: approximate,
+		Bedrooms:            input.Bedrooms,
+		Bathrooms:           input.Bathrooms,
+		GarageSpaces:        input.GarageSpaces,
+		AreaSqFt:            input.AreaSqFt,
+		YearBuilt:           input.YearBuilt,
+		SwimmingPool:        input.SwimmingPool,
+		Garden:              input.Garden,
+		AirConditioning:     input.AirConditioning,
+		CentralHeating:      input.CentralHeating,
+		SecuritySystem:      input.SecuritySystem,
+	}
+}
+
+// isHostedImageURL sadece kendi depolamamıza yüklenmiş resimler ilana eklenebilir
+func isHostedImageURL(imageURL string) bool {
+	return cloudflare.IsHostedURL(imageURL)
+}
+
+// foreignImageURLs urls içinde kullanıcının kendi depolama alanına ait olmayan resimleri bulur.
+// Bu resimler ilana eklenemez ve ilan silinirken dosyaları silinmez.
+func foreignImageURLs(db *gorm.DB, userID uint, urls []string) (map[string]bool, error) {
+	if storage.GlobalStorage == nil || len(urls) == 0 {
+		return map[string]bool{}, nil
+	}
+	var user model.User
+	if err := db.Select("id", "username").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	return model.ForeignImageURLs(db, storage.GlobalStorage, &user, urls)
+}
+
+// removedPropertyImages ilandan çıkarılan ve dosyaları silinebilecek resimleri döner.
+// Yeni listede kalan, başka bir hesaba ait olan ya da başka bir ilanda da kullanılan
+// resimler dahil edilmez.
+func removedPropertyImages(db *gorm.DB, userID, propertyID uint, existing []model.PropertyImage, keptURLs []string) ([]model.PropertyImage, error) {
+	kept := make(map[string]bool, len(keptURLs))
+	for _, imageURL := range keptURLs {
+		kept[imageURL] = true
+	}
+
+	var removed []model.PropertyImage
+	var removedURLs []string
+	for _, img := range existing {
+		if !kept[img.URL] {
+			removed = append(removed, img)
+			removedURLs = append(removedURLs, img.URL)
+		}
+	}
+
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	foreign, err := foreignImageURLs(db, userID, removedURLs)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []string
+	if err := db.Model(&model.PropertyImage{}).
+		Joins("JOIN properties ON properties.id = property_images.property_id AND properties.deleted_at IS NULL").
+		Where("property_images.url IN ? AND property_images.property_id <> ?", removedURLs, propertyID).
+		Distinct().
+		Pluck("property_images.url", &shared).Error; err != nil {
+		return nil, err
+	}
+	for _, imageURL := range shared {
+		foreign[imageURL] = true
+	}
+
+	owned := removed[:0]
+	for _, img := range removed {
+		if !foreign[img.URL] {
+			owned = append(owned, img)
+		}
+	}
+	return owned, nil
+}
+
+// deletePropertyImageObjects kayıtları silinmiş resimlerin dosyalarını depolamadan siler.
+// Commit sonrası çağrılır; hatalar sadece loglanır.
+func deletePropertyImageObjects(images []model.PropertyImage) {
+	for i := range images {
+		if err := cloudflare.DeleteImageObjects(&images[i]); err != nil {
+			log.Printf("Could not delete image objects for %s: %v", images[i].URL, err)
+		}
+	}
+}
+
+// respondForeignImage başka bir hesaba ait resim URL'ini reddeder
+func respondForeignImage(c *fiber.Ctx, imageURL string) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "Image does not belong to your account",
+		"image": imageURL,
+	})
 }
 
 // CreateProperty yeni emlak ilanı oluşturur
@@ -73,33 +305,40 @@ func CreateProperty(c *fiber.Ctx) error {
 		})
 	}
 
-	property := model.Property{
-		UserID:          claims.UserID,
-		Title:           input.Title,
-		Description:     input.Description,
-		Price:           input.Price,
-		Type:            input.Type,
-		Status:          input.Status,
-		Currency:        input.Currency,
-		CountryCode:     input.CountryCode,
-		CountryName:     input.CountryName,
-		StateCode:       input.StateCode,
-		StateName:       input.StateName,
-		City:            input.City,
-		District:        input.District,
-		FullAddress:     input.FullAddress,
-		Bedrooms:        input.Bedrooms,
-		Bathrooms:       input.Bathrooms,
-		GarageSpaces:    input.GarageSpaces,
-		AreaSqFt:        input.AreaSqFt,
-		YearBuilt:       input.YearBuilt,
-		SwimmingPool:    input.SwimmingPool,
-		Garden:          input.Garden,
-		AirConditioning: input.AirConditioning,
-		CentralHeating:  input.CentralHeating,
-		SecuritySystem:  input.SecuritySystem,
+	latitude, longitude, approximate, err := resolvePropertyCoordinates(input)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Yeni ilanlar varsayılan olarak hemen yayına alınır, taslak ya da planlı da oluşturulabilir
+	publicationState := input.PublicationState
+	if publicationState == "" {
+		publicationState = model.PublicationPublished
+	}
+	if err := validatePublicationChange(model.PublicationDraft, publicationState, input.PublishAt); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	foreign, err := foreignImageURLs(database.GetDB(), claims.UserID, input.Images)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not check images",
+		})
+	}
+	for _, imageURL := range input.Images {
+		if foreign[imageURL] {
+			return respondForeignImage(c, imageURL)
+		}
 	}
 
+	property := newPropertyFromInput(claims.UserID, input, latitude, longitude, approximate)
+
+	property.ApplyPublicationState(publicationState, input.PublishAt, time.Now())
+
 	tx := database.GetDB().Begin()
 
 	if err := tx.Create(&property).Error; err != nil {
@@ -110,12 +349,13 @@ func CreateProperty(c *fiber.Ctx) error {
 	}
 
 	for i, imageURL := range input.Images {
-		if strings.HasPrefix(imageURL, "https://"+os.Getenv("R2_BUCKET_NAME")) {
+		if isHostedImageURL(imageURL) {
 			image := model.PropertyImage{
-				PropertyID: property.ID,
-				URL:        imageURL,
-				Order:      i,
-				IsCover:    i == 0,
+				PropertyID:   property.ID,
+				URL:          imageURL,
+				CloudflareID: cloudflareIDFromURL(imageURL),
+				Order:        i,
+				IsCover:      i == 0,
 				// Size bilgisi upload sırasında cloudflare.UploadPropertyImage
 				// fonksiyonunda kaydediliyor
 			}
@@ -128,6 +368,20 @@ func CreateProperty(c *fiber.Ctx) error {
 		}
 	}
 
+	if _, err := model.RecordPriceChange(tx, property.ID, 0, "", property.Price, property.Currency); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not record price history",
+		})
+	}
+
+	if _, err := model.RecordPropertyRevision(tx, property.ID, claims.UserID, "created"); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not record property revision",
+		})
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not complete the property creation",
@@ -175,8 +429,25 @@ func UpdateProperty(c *fiber.Ctx) error {
 		})
 	}
 
+	latitude, longitude, approximate, err := resolvePropertyCoordinates(input)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	tx := database.GetDB().Begin()
 
+	// Revizyon geçmişi olmayan eski ilanlar için güncelleme öncesi hali sakla
+	if err := model.EnsureBaselineRevision(tx, property.ID, claims.UserID); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not record property revision",
+		})
+	}
+
+	previousPrice, previousCurrency := property.Price, property.Currency
+
 	// Property bilgilerini güncelle
 	property.Title = input.Title
 	property.Type = input.Type
@@ -191,6 +462,9 @@ func UpdateProperty(c *fiber.Ctx) error {
 	property.City = input.City
 	property.District = input.District
 	property.FullAddress = input.FullAddress
+	property.Latitude = latitude
+	property.Longitude = longitude
+	property.LocationApproximate = approximate
 
 	if err := tx.Save(&property).Error; err != nil {
 		tx.Rollback()
@@ -199,38 +473,80 @@ func UpdateProperty(c *fiber.Ctx) error {
 		})
 	}
 
-	// Mevcut resimleri sil
-	var oldImages []model.PropertyImage
-	if err := tx.Where("property_id = ?", property.ID).Find(&oldImages).Error; err != nil {
+	if _, err := model.RecordPriceChange(tx, property.ID, previousPrice, previousCurrency, property.Price, property.Currency); err != nil {
 		tx.Rollback()
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Could not fetch old images",
+			"error": "Could not record price history",
 		})
 	}
 
-	for _, oldImage := range oldImages {
-		fileName := cloudflare.GetFileNameFromURL(oldImage.URL)
-		if err := cloudflare.DeleteImage(fileName); err != nil {
-			tx.Rollback()
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Could not delete old image from Cloudflare R2",
-			})
+	// Upload sırasında kaydedilen boyut/varyant bilgileri yeni satırlara taşınır
+	var existingImages []model.PropertyImage
+	if err := tx.Where("property_id = ?", property.ID).Find(&existingImages).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch existing images",
+		})
+	}
+	existingByURL := make(map[string]model.PropertyImage, len(existingImages))
+	for _, img := range existingImages {
+		existingByURL[img.URL] = img
+	}
+
+	// İlanda zaten olan resimler dışındakiler kullanıcının kendi dosyaları olmalı
+	var addedURLs []string
+	for _, imageURL := range input.Images {
+		if _, ok := existingByURL[imageURL]; !ok {
+			addedURLs = append(addedURLs, imageURL)
 		}
-		if err := tx.Delete(&oldImage).Error; err != nil {
+	}
+	foreign, err := foreignImageURLs(tx, claims.UserID, addedURLs)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not check images",
+		})
+	}
+	for _, imageURL := range addedURLs {
+		if foreign[imageURL] {
 			tx.Rollback()
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Could not delete old image from database",
-			})
+			return respondForeignImage(c, imageURL)
 		}
 	}
 
+	// Mevcut resim kayıtlarını sil. Dosyalar depolamada bırakılır; önceki revizyonlar
+	// bu URL'lere referans verdiği için geri yüklenebilmeli. İlan silinince orphan
+	// temizliği kullanılmayan dosyaları toplar.
+	if err := tx.Unscoped().Where("property_id = ?", property.ID).Delete(&model.PropertyImage{}).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not delete old images from database",
+		})
+	}
+
 	// Yeni resimleri kaydet
 	for i, imageURL := range input.Images {
 		image := model.PropertyImage{
-			PropertyID: property.ID,
-			URL:        imageURL,
-			Order:      i,
-			IsCover:    i == 0,
+			PropertyID:   property.ID,
+			URL:          imageURL,
+			CloudflareID: cloudflareIDFromURL(imageURL),
+			Order:        i,
+			IsCover:      i == 0,
+		}
+		if existing, ok := existingByURL[imageURL]; ok {
+			image.CloudflareID = existing.CloudflareID
+			image.ThumbnailURL = existing.ThumbnailURL
+			image.Size = existing.Size
+			image.Width = existing.Width
+			image.Height = existing.Height
+			image.Variants = existing.Variants
+			image.Caption = existing.Caption
+			image.AltText = existing.AltText
+			image.PerceptualHash = existing.PerceptualHash
+			image.BlurHash = existing.BlurHash
+			image.LQIP = existing.LQIP
+			image.DominantColor = existing.DominantColor
+			image.WatermarkVersion = existing.WatermarkVersion
 		}
 		if err := tx.Create(&image).Error; err != nil {
 			tx.Rollback()
@@ -240,6 +556,13 @@ func UpdateProperty(c *fiber.Ctx) error {
 		}
 	}
 
+	if _, err := model.RecordPropertyRevision(tx, property.ID, claims.UserID, "updated"); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not record property revision",
+		})
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not complete the update",
@@ -254,6 +577,13 @@ func UpdateProperty(c *fiber.Ctx) error {
 	return c.JSON(property)
 }
 
+// publicPropertiesQuery kullanıcının ziyaretçilere açık ilanları; profil sayfası ve feed'ler kullanır
+func publicPropertiesQuery(userID uint) *gorm.DB {
+	return database.GetDB().Model(&model.Property{}).
+		Scopes(model.PublishedProperties).
+		Where("properties.user_id = ?", userID)
+}
+
 // ListUserProperties belirli bir kullanıcının public ilanlarını listeler
 func ListUserProperties(c *fiber.Ctx) error {
 	username := c.Params("username")
@@ -270,13 +600,20 @@ func ListUserProperties(c *fiber.Ctx) error {
 		})
 	}
 
-	var properties []model.Property
-	if err := database.GetDB().Where("user_id = ? AND status = ?", user.ID, "active").
-		Preload("Images", func(db *gorm.DB) *gorm.DB {
-			return db.Order("property_images.order ASC")
-		}).
-		Order("created_at desc").
-		Find(&properties).Error; err != nil {
+	params, err := pagination.Parse(c, propertyListOptions)
+	if err != nil {
+		return respondListParamError(c, err)
+	}
+
+	properties, meta, err := paginateProperties(publicPropertiesQuery(user.ID), params)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch properties",
+		})
+	}
+
+	projected, err := params.Project(properties)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not fetch properties",
 		})
@@ -284,7 +621,8 @@ func ListUserProperties(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"user":       user.GetPublicProfile(),
-		"properties": properties,
+		"properties": projected,
+		"pagination": meta,
 	})
 }
 
@@ -306,8 +644,8 @@ func GetPropertyBySlug(c *fiber.Ctx) error {
 	}
 
 	var property model.Property
-	if err := database.GetDB().Where("user_id = ? AND status = ? AND slug = ?",
-		user.ID, "active", propertySlug).
+	if err := database.GetDB().Scopes(model.PublishedProperties).
+		Where("user_id = ? AND slug = ?", user.ID, propertySlug).
 		Preload("Images", func(db *gorm.DB) *gorm.DB {
 			return db.Order("property_images.order ASC")
 		}).
@@ -322,12 +660,28 @@ func GetPropertyBySlug(c *fiber.Ctx) error {
 		})
 	}
 
+	priceHistory, err := model.GetPriceSummary(database.GetDB(), &property)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch price history",
+		})
+	}
+
+	attachments, err := model.PublicAttachments(database.GetDB(), property.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch attachments",
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"user": fiber.Map{
 			"username":     user.Username,
 			"company_name": user.CompanyName,
 		},
-		"property": property,
+		"property":      property,
+		"price_history": priceHistory,
+		"attachments":   attachments,
 	})
 }
 
@@ -335,19 +689,80 @@ func GetPropertyBySlug(c *fiber.Ctx) error {
 func ListMyProperties(c *fiber.Ctx) error {
 	claims := c.Locals("user").(*jwt.Claims)
 
-	var properties []model.Property
-	if err := database.GetDB().Where("user_id = ?", claims.UserID).
-		Preload("Images", func(db *gorm.DB) *gorm.DB {
-			return db.Order("property_images.order ASC")
-		}).
-		Order("created_at desc").
-		Find(&properties).Error; err != nil {
+	params, err := pagination.Parse(c, propertyListOptions)
+	if err != nil {
+		return respondListParamError(c, err)
+	}
+
+	query := database.GetDB().Model(&model.Property{}).Where("user_id = ?", claims.UserID)
+	if state := c.Query("publication_state"); state != "" {
+		query = query.Where("publication_state = ?", state)
+	}
+
+	properties, meta, err := paginateProperties(query, params)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not fetch properties",
 		})
 	}
 
-	return c.JSON(properties)
+	projected, err := params.Project(properties)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch properties",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"properties": projected,
+		"pagination": meta,
+	})
+}
+
+// UpdatePropertyPublication ilanın yayın durumunu değiştirir (taslak, planlı, yayında, arşiv)
+func UpdatePropertyPublication(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	id := c.Params("id")
+
+	input := new(PublicationInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	var property model.Property
+	if err := database.GetDB().First(&property, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Property not found",
+		})
+	}
+
+	if property.UserID != claims.UserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Not authorized to update this property",
+		})
+	}
+
+	if err := validatePublicationChange(property.PublicationState, input.State, input.PublishAt); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":               err.Error(),
+			"current_state":       property.PublicationState,
+			"allowed_transitions": property.PublicationState.AllowedTransitions(),
+		})
+	}
+
+	property.ApplyPublicationState(input.State, input.PublishAt, time.Now())
+
+	if err := database.GetDB().Model(&property).Select(
+		"publication_state", "publish_at", "published_at", "archived_at",
+	).Updates(&property).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not update publication state",
+		})
+	}
+
+	return c.JSON(property)
 }
 
 // DeleteProperty emlak ilanını siler
@@ -371,7 +786,7 @@ func DeleteProperty(c *fiber.Ctx) error {
 
 	tx := database.GetDB().Begin()
 
-	// İlişkili resimleri Cloudflare R2'den sil
+	// İlişkili resimler commit sonrası Cloudflare R2'den silinir
 	var images []model.PropertyImage
 	if err := tx.Where("property_id = ?", property.ID).Find(&images).Error; err != nil {
 		tx.Rollback()
@@ -380,14 +795,24 @@ func DeleteProperty(c *fiber.Ctx) error {
 		})
 	}
 
-	for _, image := range images {
-		fileName := cloudflare.GetFileNameFromURL(image.URL)
-		if err := cloudflare.DeleteImage(fileName); err != nil {
-			tx.Rollback()
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Could not delete image from Cloudflare R2",
-			})
-		}
+	// Başka bir hesaba ait ya da kullanıcının başka bir ilanında da kullanılan dosyalar
+	// silinmez, sadece kayıt kaldırılır
+	removed, err := removedPropertyImages(tx, claims.UserID, property.ID, images, nil)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not check property images",
+		})
+	}
+
+	var attachmentFiles []model.PropertyAttachment
+	if err := tx.Select("id", "object_key", "is_public").
+		Where("property_id = ? AND object_key <> ''", property.ID).
+		Find(&attachmentFiles).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch property attachments",
+		})
 	}
 
 	// Property'yi ve ilişkili kayıtları sil
@@ -404,5 +829,14 @@ func DeleteProperty(c *fiber.Ctx) error {
 		})
 	}
 
+	deletePropertyImageObjects(removed)
+
+	// Ekler silme kesinleştikten sonra kaldırılır; silinemeyenleri orphan taraması bulur
+	for _, file := range attachmentFiles {
+		if err := cloudflare.DeleteAttachment(file.ObjectKey, file.IsPublic); err != nil {
+			log.Printf("Error deleting attachment %s: %v", file.ObjectKey, err)
+		}
+	}
+
 	return c.SendStatus(fiber.StatusNoContent)
 }
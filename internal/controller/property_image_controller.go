@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/utils/jwt"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	MaxImageCaptionLength = 500
+	MaxImageAltTextLength = 250
+)
+
+type ImageMetadataInput struct {
+	ID      uint    `json:"id"`
+	Caption *string `json:"caption"`
+	AltText *string `json:"alt_text"`
+}
+
+// PropertyImagesInput tüm alanlar opsiyoneldir; gönderilmeyenler değişmez
+type PropertyImagesInput struct {
+	Order        []uint               `json:"order"` // İlanın tüm resim ID'leri, yeni sırasıyla
+	CoverImageID *uint                `json:"cover_image_id"`
+	Images       []ImageMetadataInput `json:"images"`
+}
+
+func validatePropertyImagesInput(input *PropertyImagesInput, images map[uint]*model.PropertyImage) error {
+	if input.Order != nil {
+		if len(input.Order) != len(images) {
+			return fmt.Errorf("order must contain all %d images of the property", len(images))
+		}
+		seen := map[uint]bool{}
+		for _, id := range input.Order {
+			if images[id] == nil {
+				return fmt.Errorf("image %d does not belong to this property", id)
+			}
+			if seen[id] {
+				return fmt.Errorf("image %d appears more than once in order", id)
+			}
+			seen[id] = true
+		}
+	}
+
+	if input.CoverImageID != nil && images[*input.CoverImageID] == nil {
+		return fmt.Errorf("image %d does not belong to this property", *input.CoverImageID)
+	}
+
+	for _, meta := range input.Images {
+		if images[meta.ID] == nil {
+			return fmt.Errorf("image %d does not belong to this property", meta.ID)
+		}
+		if meta.Caption != nil && utf8.RuneCountInString(*meta.Caption) > MaxImageCaptionLength {
+			return fmt.Errorf("caption must be at most %d characters", MaxImageCaptionLength)
+		}
+		if meta.AltText != nil && utf8.RuneCountInString(*meta.AltText) > MaxImageAltTextLength {
+			return fmt.Errorf("alt text must be at most %d characters", MaxImageAltTextLength)
+		}
+	}
+
+	return nil
+}
+
+// UpdatePropertyImages resimleri yeniden yüklemeden sıralar, kapak seçer ve açıklamaları günceller.
+// İşlem sonunda ilanda her zaman tek bir kapak resmi bulunur.
+func UpdatePropertyImages(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	input := new(PropertyImagesInput)
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	tx := database.GetDB().Begin()
+
+	// Aynı ilan için eşzamanlı güncellemeleri sıraya sok
+	var property model.Property
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&property, c.Params("id")).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Property not found",
+		})
+	}
+
+	var images []model.PropertyImage
+	if err := tx.Where("property_id = ?", property.ID).Order(`"order" ASC, id ASC`).Find(&images).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch property images",
+		})
+	}
+
+	byID := make(map[uint]*model.PropertyImage, len(images))
+	for i := range images {
+		byID[images[i].ID] = &images[i]
+	}
+
+	if err := validatePropertyImagesInput(input, byID); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := model.EnsureBaselineRevision(tx, property.ID, claims.UserID); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not record property revision",
+		})
+	}
+
+	if input.Order != nil {
+		for i, id := range input.Order {
+			byID[id].Order = i
+		}
+	} else {
+		// Eski kayıtlarda sıra boşluklu/tekrarlı olabilir; mevcut sırayı normalize et
+		for i := range images {
+			images[i].Order = i
+		}
+	}
+
+	for _, meta := range input.Images {
+		if meta.Caption != nil {
+			byID[meta.ID].Caption = strings.TrimSpace(*meta.Caption)
+		}
+		if meta.AltText != nil {
+			byID[meta.ID].AltText = strings.TrimSpace(*meta.AltText)
+		}
+	}
+
+	// Kapak: seçilen resim, yoksa mevcut kapak, o da yoksa ilk sıradaki resim
+	var coverID uint
+	if input.CoverImageID != nil {
+		coverID = *input.CoverImageID
+	} else {
+		for _, img := range images {
+			if img.IsCover && (coverID == 0 || img.Order < byID[coverID].Order) {
+				coverID = img.ID
+			}
+		}
+		if coverID == 0 {
+			for _, img := range images {
+				if coverID == 0 || img.Order < byID[coverID].Order {
+					coverID = img.ID
+				}
+			}
+		}
+	}
+
+	for i := range images {
+		img := &images[i]
+		img.IsCover = img.ID == coverID
+		if err := tx.Model(img).Select("order", "is_cover", "caption", "alt_text").Updates(img).Error; err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not update images",
+			})
+		}
+	}
+
+	if _, err := model.RecordPropertyRevision(tx, property.ID, claims.UserID, "images updated"); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not record property revision",
+		})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not update images",
+		})
+	}
+
+	ordered := make([]model.PropertyImage, len(images))
+	for _, img := range images {
+		ordered[img.Order] = img
+	}
+
+	return c.JSON(fiber.Map{
+		"images": ordered,
+	})
+}
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/utils/jwt"
+	"estepage_backend/pkg/utils/pagination"
+	"estepage_backend/pkg/utils/storage"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var propertyRevisionListOptions = pagination.Options{
+	IDColumn: "property_revisions.id",
+	SortKeys: map[string]string{
+		"revision":   "property_revisions.revision",
+		"created_at": "property_revisions.created_at",
+	},
+	DefaultSort: "-revision",
+}
+
+// PropertyRevisionResponse revizyon ve bir önceki revizyona göre alan farkları
+type PropertyRevisionResponse struct {
+	model.PropertyRevision
+	Changes []model.FieldChange `json:"changes"`
+}
+
+// ListPropertyRevisions ilanın revizyon geçmişini alan bazlı farklarla listeler
+func ListPropertyRevisions(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var property model.Property
+	if err := database.GetDB().First(&property, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Property not found",
+		})
+	}
+
+	params, err := pagination.Parse(c, propertyRevisionListOptions)
+	if err != nil {
+		return respondListParamError(c, err)
+	}
+
+	query := database.GetDB().Model(&model.PropertyRevision{}).Where("property_id = ?", property.ID)
+
+	ids, meta, err := params.Paginate(query)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch revisions",
+		})
+	}
+
+	var revisions []model.PropertyRevision
+	if len(ids) > 0 {
+		if err := params.Ordered(database.GetDB(), ids).Find(&revisions).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not fetch revisions",
+			})
+		}
+	}
+
+	// Farkları hesaplamak için her revizyonun bir öncekini de yükle
+	previousNumbers := make([]int, 0, len(revisions))
+	for _, rev := range revisions {
+		previousNumbers = append(previousNumbers, rev.Revision-1)
+	}
+
+	previous := map[int]model.PropertyRevision{}
+	if len(previousNumbers) > 0 {
+		var prevRevisions []model.PropertyRevision
+		if err := database.GetDB().
+			Where("property_id = ? AND revision IN ?", property.ID, previousNumbers).
+			Find(&prevRevisions).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not fetch revisions",
+			})
+		}
+		for _, rev := range prevRevisions {
+			previous[rev.Revision] = rev
+		}
+	}
+
+	response := make([]PropertyRevisionResponse, 0, len(revisions))
+	for _, rev := range revisions {
+		changes, err := model.DiffPropertySnapshots(previous[rev.Revision-1].Snapshot, rev.Snapshot)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not compare revisions",
+			})
+		}
+		response = append(response, PropertyRevisionResponse{PropertyRevision: rev, Changes: changes})
+	}
+
+	return c.JSON(fiber.Map{
+		"revisions":  response,
+		"pagination": meta,
+	})
+}
+
+// RestorePropertyRevision ilanı seçilen revizyona geri döndürür ve bunu yeni bir revizyon olarak kaydeder
+func RestorePropertyRevision(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	id := c.Params("id")
+
+	revisionNumber, err := strconv.Atoi(c.Params("rev"))
+	if err != nil || revisionNumber < 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid revision number",
+		})
+	}
+
+	var property model.Property
+	if err := database.GetDB().First(&property, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Property not found",
+		})
+	}
+
+	var revision model.PropertyRevision
+	if err := database.GetDB().
+		Where("property_id = ? AND revision = ?", property.ID, revisionNumber).
+		First(&revision).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Revision not found",
+		})
+	}
+
+	previousPrice, previousCurrency := property.Price, property.Currency
+
+	tx := database.GetDB().Begin()
+
+	// Dosyası silinmiş resimler geri yüklenmez
+	missingImages, err := model.RestorePropertyRevision(tx, storage.GlobalStorage, &property, &revision)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not restore revision",
+		})
+	}
+
+	if _, err := model.RecordPriceChange(tx, property.ID, previousPrice, previousCurrency, property.Price, property.Currency); err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not record price history",
+		})
+	}
+
+	restored, err := model.RecordPropertyRevision(tx, property.ID, claims.UserID, fmt.Sprintf("restored from #%d", revision.Revision))
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not record property revision",
+		})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not complete the restore",
+		})
+	}
+
+	database.GetDB().
+		Preload("Images", func(db *gorm.DB) *gorm.DB {
+			return db.Order("property_images.order ASC")
+		}).
+		Preload("Features", func(db *gorm.DB) *gorm.DB {
+			return db.Order("property_features.order ASC")
+		}).
+		First(&property, property.ID)
+
+	return c.JSON(fiber.Map{
+		"property":       property,
+		"revision":       restored,
+		"missing_images": missingImages,
+	})
+}
@@ -0,0 +1,591 @@
+package controller
+
+import (
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/utils/currency"
+	"estepage_backend/pkg/utils/location"
+	"estepage_backend/pkg/utils/pagination"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Arama sonuçlarında facet sayılarının hesaplandığı boyutlar
+var propertyFacetDimensions = []string{
+	"type",
+	"status",
+	"country_code",
+	"state_code",
+	"city",
+	"bedrooms",
+	"bathrooms",
+}
+
+// Boolean olanak filtreleri (query parametresi = kolon adı)
+var propertyAmenityColumns = []string{
+	"swimming_pool",
+	"garden",
+	"air_conditioning",
+	"central_heating",
+	"security_system",
+}
+
+const (
+	maxFacetBuckets = 50
+	maxSearchRadius = 500.0 // km
+)
+
+// GeoPoint harita araması için merkez nokta
+type GeoPoint struct {
+	Lat float64
+	Lng float64
+}
+
+type PropertySearchFilter struct {
+	Query         string
+	Types         []string
+	Statuses      []string
+	PriceCurrency string
+	MinPrice      *float64
+	MaxPrice      *float64
+	MinBedrooms   *int
+	MinBathrooms  *int
+	MinArea       *int
+	MaxArea       *int
+	MinYear       *int
+	MaxYear       *int
+	Amenities     map[string]bool
+	CountryCode   string
+	StateCode     string
+	City          string
+	Near          *GeoPoint
+	RadiusKm      *float64
+	BBox          []float64 // min_lng, min_lat, max_lng, max_lat
+}
+
+type FacetBucket struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+func splitQueryList(raw string) []string {
+	var values []string
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func queryOptionalInt(c *fiber.Ctx, key string) (*int, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	return &v, nil
+}
+
+func queryOptionalFloat(c *fiber.Ctx, key string) (*float64, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative number", key)
+	}
+	return &v, nil
+}
+
+// parsePropertySearchFilter query parametrelerini doğrulayarak filtreye çevirir
+func parsePropertySearchFilter(c *fiber.Ctx) (*PropertySearchFilter, error) {
+	f := &PropertySearchFilter{
+		Query:         strings.TrimSpace(c.Query("q")),
+		Types:         splitQueryList(c.Query("type")),
+		Statuses:      splitQueryList(c.Query("status")),
+		PriceCurrency: strings.ToUpper(c.Query("currency", currency.BaseCurrency)),
+		Amenities:     map[string]bool{},
+		CountryCode:   strings.ToUpper(c.Query("country_code")),
+		StateCode:     c.Query("state_code"),
+		City:          strings.TrimSpace(c.Query("city")),
+	}
+
+	if !currency.IsSupported(f.PriceCurrency) {
+		return nil, fmt.Errorf("unsupported currency: %s", f.PriceCurrency)
+	}
+
+	var err error
+	if f.MinPrice, err = queryOptionalFloat(c, "min_price"); err != nil {
+		return nil, err
+	}
+	if f.MaxPrice, err = queryOptionalFloat(c, "max_price"); err != nil {
+		return nil, err
+	}
+	if f.MinBedrooms, err = queryOptionalInt(c, "min_bedrooms"); err != nil {
+		return nil, err
+	}
+	if f.MinBathrooms, err = queryOptionalInt(c, "min_bathrooms"); err != nil {
+		return nil, err
+	}
+	if f.MinArea, err = queryOptionalInt(c, "min_area"); err != nil {
+		return nil, err
+	}
+	if f.MaxArea, err = queryOptionalInt(c, "max_area"); err != nil {
+		return nil, err
+	}
+	if f.MinYear, err = queryOptionalInt(c, "min_year"); err != nil {
+		return nil, err
+	}
+	if f.MaxYear, err = queryOptionalInt(c, "max_year"); err != nil {
+		return nil, err
+	}
+
+	if err := parseGeoFilter(c, f); err != nil {
+		return nil, err
+	}
+
+	for _, column := range propertyAmenityColumns {
+		raw := c.Query(column)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false", column)
+		}
+		f.Amenities[column] = v
+	}
+
+	return f, nil
+}
+
+// parseGeoFilter lat/lng/radius_km ve bbox parametrelerini okur
+func parseGeoFilter(c *fiber.Ctx, f *PropertySearchFilter) error {
+	rawLat, rawLng := c.Query("lat"), c.Query("lng")
+	if rawLat != "" || rawLng != "" {
+		lat, errLat := strconv.ParseFloat(rawLat, 64)
+		lng, errLng := strconv.ParseFloat(rawLng, 64)
+		if errLat != nil || errLng != nil || !location.ValidCoordinates(lat, lng) {
+			return fmt.Errorf("lat and lng must be valid coordinates")
+		}
+		f.Near = &GeoPoint{Lat: lat, Lng: lng}
+	}
+
+	radius, err := queryOptionalFloat(c, "radius_km")
+	if err != nil {
+		return err
+	}
+	if radius != nil {
+		if f.Near == nil {
+			return fmt.Errorf("radius_km requires lat and lng")
+		}
+		if *radius == 0 || *radius > maxSearchRadius {
+			return fmt.Errorf("radius_km must be between 0 and %g", maxSearchRadius)
+		}
+		f.RadiusKm = radius
+	}
+
+	if raw := c.Query("bbox"); raw != "" {
+		parts := strings.Split(raw, ",")
+		if len(parts) != 4 {
+			return fmt.Errorf("bbox must be min_lng,min_lat,max_lng,max_lat")
+		}
+		bbox := make([]float64, 4)
+		for i, part := range parts {
+			v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return fmt.Errorf("bbox must be min_lng,min_lat,max_lng,max_lat")
+			}
+			bbox[i] = v
+		}
+		if !location.ValidCoordinates(bbox[1], bbox[0]) ||
+			!location.ValidCoordinates(bbox[3], bbox[2]) ||
+			bbox[1] > bbox[3] {
+			return fmt.Errorf("bbox coordinates are out of range")
+		}
+		f.BBox = bbox
+	}
+
+	return nil
+}
+
+// apply filtreleri sorguya ekler. skip verilen facet boyutunun filtresi atlanır,
+// böylece bir boyutun sayıları kendi seçiminden etkilenmez.
+func (f *PropertySearchFilter) apply(db *gorm.DB, skip string) *gorm.DB {
+	db = db.Scopes(model.PublishedProperties)
+	if f.Query != "" {
+		db = db.Where("properties.search_vector @@ "+model.PropertySearchQuerySQL, f.Query, f.Query)
+	}
+	if len(f.Types) > 0 && skip != "type" {
+		db = db.Where("properties.type IN ?", f.Types)
+	}
+	if len(f.Statuses) > 0 && skip != "status" {
+		db = db.Where("properties.status IN ?", f.Statuses)
+	}
+	if f.CountryCode != "" && skip != "country_code" {
+		db = db.Where("properties.country_code = ?", f.CountryCode)
+	}
+	if f.StateCode != "" && skip != "state_code" {
+		db = db.Where("properties.state_code = ?", f.StateCode)
+	}
+	if f.City != "" && skip != "city" {
+		db = db.Where("LOWER(properties.city) = LOWER(?)", f.City)
+	}
+	if f.MinBedrooms != nil && skip != "bedrooms" {
+		db = db.Where("properties.bedrooms >= ?", *f.MinBedrooms)
+	}
+	if f.MinBathrooms != nil && skip != "bathrooms" {
+		db = db.Where("properties.bathrooms >= ?", *f.MinBathrooms)
+	}
+	if f.MinArea != nil {
+		db = db.Where("properties.area_sq_ft >= ?", *f.MinArea)
+	}
+	if f.MaxArea != nil {
+		db = db.Where("properties.area_sq_ft <= ?", *f.MaxArea)
+	}
+	if f.MinYear != nil {
+		db = db.Where("properties.year_built >= ?", *f.MinYear)
+	}
+	if f.MaxYear != nil {
+		db = db.Where("properties.year_built <= ?", *f.MaxYear)
+	}
+
+	// Fiyatlar farklı para birimlerinde olduğu için USD üzerinden karşılaştırılır
+	priceUSD := currency.SQLToUSD("properties.price", "properties.currency")
+	if f.MinPrice != nil {
+		minUSD, _ := currency.ToUSD(*f.MinPrice, f.PriceCurrency)
+		db = db.Where(priceUSD+" >= ?", minUSD)
+	}
+	if f.MaxPrice != nil {
+		maxUSD, _ := currency.ToUSD(*f.MaxPrice, f.PriceCurrency)
+		db = db.Where(priceUSD+" <= ?", maxUSD)
+	}
+
+	if f.Near != nil && f.RadiusKm != nil {
+		// Önce kutu ile indeks üzerinden daralt, sonra gerçek mesafeyle filtrele
+		minLat, minLng, maxLat, maxLng := location.BoundingBox(f.Near.Lat, f.Near.Lng, *f.RadiusKm)
+		db = db.Where("properties.latitude BETWEEN ? AND ? AND properties.longitude BETWEEN ? AND ?",
+			minLat, maxLat, minLng, maxLng)
+		db = db.Where(location.HaversineSQL("properties.latitude", "properties.longitude")+" <= ?",
+			f.Near.Lat, f.Near.Lat, f.Near.Lng, *f.RadiusKm)
+	}
+	if len(f.BBox) == 4 {
+		db = db.Where("properties.latitude BETWEEN ? AND ?", f.BBox[1], f.BBox[3])
+		if f.BBox[0] <= f.BBox[2] {
+			db = db.Where("properties.longitude BETWEEN ? AND ?", f.BBox[0], f.BBox[2])
+		} else {
+			// Harita görünümü 180. meridyeni kesiyor
+			db = db.Where("(properties.longitude >= ? OR properties.longitude <= ?)", f.BBox[0], f.BBox[2])
+		}
+	}
+
+	if skip != "amenities" {
+		for _, column := range propertyAmenityColumns {
+			if v, ok := f.Amenities[column]; ok {
+				db = db.Where("properties."+column+" = ?", v)
+			}
+		}
+	}
+
+	return db
+}
+
+// buildPropertyFacets her boyut için değer bazında ilan sayılarını hesaplar
+func buildPropertyFacets(f *PropertySearchFilter) (fiber.Map, error) {
+	db := database.GetDB()
+	facets := fiber.Map{}
+
+	for _, dim := range propertyFacetDimensions {
+		buckets := []FacetBucket{}
+		if err := f.apply(db.Model(&model.Property{}), dim).
+			Select("properties." + dim + "::text AS value, COUNT(*) AS count").
+			Group("properties." + dim).
+			Order("count DESC").
+			Limit(maxFacetBuckets).
+			Scan(&buckets).Error; err != nil {
+			return nil, err
+		}
+		facets[dim] = buckets
+	}
+
+	selects := make([]string, len(propertyAmenityColumns))
+	for i, column := range propertyAmenityColumns {
+		selects[i] = fmt.Sprintf("COUNT(*) FILTER (WHERE properties.%s) AS %s", column, column)
+	}
+	amenities := map[string]interface{}{}
+	if err := f.apply(db.Model(&model.Property{}), "amenities").
+		Select(strings.Join(selects, ", ")).
+		Take(&amenities).Error; err != nil {
+		return nil, err
+	}
+	facets["amenities"] = amenities
+
+	return facets, nil
+}
+
+// PropertyHighlight eşleşen kelimeleri <mark> ile işaretlenmiş kısa metin parçaları.
+// Metin HTML olarak escape edilmiştir; sadece <mark> etiketleri ham bırakılır.
+type PropertyHighlight struct {
+	ID          uint   `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// ts_headline işaretleri; ilan metninde geçemeyecek özel kullanım karakterleri seçilir
+// ki HTML escape sonrası <mark> ile değiştirilebilsinler
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+var highlightMarker = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+// renderHighlight ts_headline çıktısını escape eder ve işaretleri <mark> etiketine çevirir
+func renderHighlight(headline string) string {
+	return highlightMarker.Replace(html.EscapeString(headline))
+}
+
+// buildPropertyHighlights arama sonuçları için başlık ve açıklama snippet'lerini üretir
+func buildPropertyHighlights(query string, ids []uint) (map[uint]PropertyHighlight, error) {
+	highlights := map[uint]PropertyHighlight{}
+	if query == "" || len(ids) == 0 {
+		return highlights, nil
+	}
+
+	selectors := `StartSel="` + highlightStart + `", StopSel="` + highlightStop + `"`
+	// İlan metnindeki işaret karakterleri sahte etiket üretmesin diye önceden silinir
+	stripMarkers := func(column string) string {
+		return fmt.Sprintf("translate(%s, '%s%s', '')", column, highlightStart, highlightStop)
+	}
+
+	var rows []PropertyHighlight
+	if err := database.GetDB().Model(&model.Property{}).
+		Select(
+			"properties.id, "+
+				"ts_headline('turkish', "+stripMarkers("properties.title")+", "+model.PropertySearchQuerySQL+", ?) AS title, "+
+				"ts_headline('turkish', "+stripMarkers("coalesce(properties.description, '')")+", "+model.PropertySearchQuerySQL+", ?) AS description",
+			query, query, selectors+", HighlightAll=true",
+			query, query, selectors+", MaxWords=35, MinWords=15, MaxFragments=2",
+		).
+		Where("properties.id IN ?", ids).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		row.Title = renderHighlight(row.Title)
+		row.Description = renderHighlight(row.Description)
+		highlights[row.ID] = row
+	}
+	return highlights, nil
+}
+
+// propertySearchKeyset sort parametresini whitelist üzerinden sıralama ifadesine çevirir.
+// Metin araması yapılıyorsa varsayılan sıralama alaka düzeyidir.
+func propertySearchKeyset(sort string, f *PropertySearchFilter) (pagination.Keyset, error) {
+	keyset := pagination.Keyset{IDColumn: "properties.id"}
+	query := f.Query
+
+	if sort == "" && query != "" {
+		sort = "relevance"
+	} else if sort == "" && f.Near != nil {
+		sort = "distance"
+	}
+
+	switch sort {
+	case "distance":
+		if f.Near == nil {
+			return keyset, fmt.Errorf("sort=distance requires lat and lng")
+		}
+		// Koordinatı olmayan ilanlar en sona düşer
+		keyset.Expr = "COALESCE(" + location.HaversineSQL("properties.latitude", "properties.longitude") + ", 'Infinity'::float8)"
+		keyset.Args = []interface{}{f.Near.Lat, f.Near.Lat, f.Near.Lng}
+	case "relevance":
+		if query == "" {
+			return keyset, fmt.Errorf("sort=relevance requires a search query (q)")
+		}
+		keyset.Expr = "ts_rank_cd(properties.search_vector, " + model.PropertySearchQuerySQL + ")"
+		keyset.Args = []interface{}{query, query}
+		keyset.Desc = true
+	case "", "newest":
+		keyset.Expr, keyset.Desc = "properties.created_at", true
+	case "oldest":
+		keyset.Expr = "properties.created_at"
+	case "price_asc":
+		keyset.Expr = currency.SQLToUSD("properties.price", "properties.currency")
+	case "price_desc":
+		keyset.Expr, keyset.Desc = currency.SQLToUSD("properties.price", "properties.currency"), true
+	default:
+		return keyset, fmt.Errorf("invalid sort value: %s", sort)
+	}
+
+	return keyset, nil
+}
+
+// loadPropertiesInOrder ID listesindeki ilanları resimleriyle yükler ve sırayı korur
+func loadPropertiesInOrder(ids []uint) ([]model.Property, error) {
+	properties := []model.Property{}
+	if len(ids) == 0 {
+		return properties, nil
+	}
+
+	var found []model.Property
+	if err := database.GetDB().
+		Preload("Images", func(db *gorm.DB) *gorm.DB {
+			return db.Order("property_images.order ASC")
+		}).
+		Preload("Features", func(db *gorm.DB) *gorm.DB {
+			return db.Order("property_features.order ASC")
+		}).
+		Where("id IN ?", ids).
+		Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]model.Property, len(found))
+	for _, p := range found {
+		byID[p.ID] = p
+	}
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			properties = append(properties, p)
+		}
+	}
+	return properties, nil
+}
+
+// loadAgentSummaries ilan sahiplerinin public özet bilgilerini döner
+func loadAgentSummaries(properties []model.Property) (map[uint]fiber.Map, error) {
+	agents := map[uint]fiber.Map{}
+	if len(properties) == 0 {
+		return agents, nil
+	}
+
+	userIDs := make([]uint, 0, len(properties))
+	for _, p := range properties {
+		userIDs = append(userIDs, p.UserID)
+	}
+
+	var users []model.User
+	if err := database.GetDB().Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	for _, u := range users {
+		agents[u.ID] = fiber.Map{
+			"username":     u.Username,
+			"company_name": u.CompanyName,
+			"full_name":    u.GetFullName(),
+			"avatar":       u.Avatar,
+		}
+	}
+	return agents, nil
+}
+
+// SearchProperties tüm emlakçıların ilanlarında filtreli arama yapar,
+// facet sayıları ve cursor tabanlı sayfalama ile birlikte döner
+func SearchProperties(c *fiber.Ctx) error {
+	filter, err := parsePropertySearchFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	keyset, err := propertySearchKeyset(c.Query("sort"), filter)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":        err.Error(),
+			"allowed_sort": []string{"relevance", "distance", "newest", "oldest", "price_asc", "price_desc"},
+		})
+	}
+
+	cursor, err := pagination.DecodeCursor(c.Query("cursor"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid cursor",
+		})
+	}
+	limit := pagination.ParseLimit(c.Query("limit"))
+
+	db := database.GetDB()
+
+	var total int64
+	if err := filter.apply(db.Model(&model.Property{}), "").Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not count properties",
+		})
+	}
+
+	var rows []pagination.Row
+	query := keyset.SelectRow(filter.apply(db.Model(&model.Property{}), ""))
+	if err := keyset.Apply(query, cursor, limit).Scan(&rows).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not search properties",
+		})
+	}
+
+	rows, nextCursor := pagination.Trim(rows, limit)
+
+	properties, err := loadPropertiesInOrder(pagination.IDs(rows))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch properties",
+		})
+	}
+
+	agents, err := loadAgentSummaries(properties)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch agents",
+		})
+	}
+
+	highlights, err := buildPropertyHighlights(filter.Query, pagination.IDs(rows))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not build search highlights",
+		})
+	}
+
+	facets, err := buildPropertyFacets(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not calculate facets",
+		})
+	}
+
+	// Merkez nokta verildiyse her ilan için mesafeyi ekle
+	distances := map[uint]float64{}
+	if filter.Near != nil {
+		for _, p := range properties {
+			if p.Latitude != nil && p.Longitude != nil {
+				distances[p.ID] = location.HaversineKm(filter.Near.Lat, filter.Near.Lng, *p.Latitude, *p.Longitude)
+			}
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"properties":   properties,
+		"distances_km": distances,
+		"agents":       agents,
+		"highlights":   highlights,
+		"facets":       facets,
+		"total":        total,
+		"pagination": fiber.Map{
+			"limit":       limit,
+			"next_cursor": nextCursor,
+			"has_more":    nextCursor != "",
+		},
+	})
+}
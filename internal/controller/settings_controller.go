@@ -6,14 +6,17 @@ import (
 	"estepage_backend/pkg/database"
 	"estepage_backend/pkg/email"
 	"estepage_backend/pkg/utils/cloudflare"
+	imageutil "estepage_backend/pkg/utils/image"
 	"estepage_backend/pkg/utils/jwt"
 	"fmt"
+	"io"
 	"log"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 type ProfileUpdateInput struct {
@@ -224,3 +227,183 @@ func ChangePassword(c *fiber.Ctx) error {
 		"message": "Password changed successfully",
 	})
 }
+
+type WatermarkSettingsInput struct {
+	Enabled  *bool    `json:"enabled"`
+	Position *string  `json:"position"`
+	Opacity  *float64 `json:"opacity"`
+	Scale    *float64 `json:"scale"`
+}
+
+func watermarkSettings(user *model.User) fiber.Map {
+	return fiber.Map{
+		"enabled":  user.WatermarkEnabled,
+		"logo_url": user.WatermarkLogoURL,
+		"position": user.WatermarkPosition,
+		"opacity":  user.WatermarkOpacity,
+		"scale":    user.WatermarkScale,
+		"version":  user.WatermarkVersion,
+	}
+}
+
+// pendingWatermarkImages filigranı güncel olmayan (yeniden üretilecek) resim sayısı
+func pendingWatermarkImages(user *model.User) int64 {
+	var count int64
+	database.GetDB().Model(&model.PropertyImage{}).
+		Joins("JOIN properties ON properties.id = property_images.property_id AND properties.deleted_at IS NULL").
+		Where("properties.user_id = ? AND property_images.watermark_version <> ?", user.ID, user.WatermarkVersion).
+		Count(&count)
+	return count
+}
+
+func GetWatermarkSettings(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var user model.User
+	if err := database.GetDB().First(&user, claims.UserID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"watermark":      watermarkSettings(&user),
+		"pending_images": pendingWatermarkImages(&user),
+	})
+}
+
+// UpdateWatermarkSettings filigran ayarlarını günceller. Değişiklik varsa sürüm artırılır ve
+// mevcut ilan fotoğrafları arka planda yeniden üretilir.
+func UpdateWatermarkSettings(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+	input := new(WatermarkSettingsInput)
+
+	if err := c.BodyParser(input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid input",
+		})
+	}
+
+	var user model.User
+	if err := database.GetDB().First(&user, claims.UserID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	updates := map[string]interface{}{}
+	if input.Enabled != nil && *input.Enabled != user.WatermarkEnabled {
+		if *input.Enabled && user.WatermarkLogoURL == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Upload a watermark logo first",
+			})
+		}
+		updates["watermark_enabled"] = *input.Enabled
+	}
+	if input.Position != nil && *input.Position != user.WatermarkPosition {
+		if !imageutil.WatermarkPositions[*input.Position] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid watermark position",
+			})
+		}
+		updates["watermark_position"] = *input.Position
+	}
+	if input.Opacity != nil && *input.Opacity != user.WatermarkOpacity {
+		if *input.Opacity < 0.05 || *input.Opacity > 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Opacity must be between 0.05 and 1",
+			})
+		}
+		updates["watermark_opacity"] = *input.Opacity
+	}
+	if input.Scale != nil && *input.Scale != user.WatermarkScale {
+		if *input.Scale < 0.05 || *input.Scale > 0.5 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Scale must be between 0.05 and 0.5",
+			})
+		}
+		updates["watermark_scale"] = *input.Scale
+	}
+
+	if len(updates) > 0 {
+		updates["watermark_version"] = gorm.Expr("watermark_version + 1")
+		if err := database.GetDB().Model(&user).Updates(updates).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not update watermark settings",
+			})
+		}
+		database.GetDB().First(&user, user.ID)
+	}
+
+	return c.JSON(fiber.Map{
+		"watermark":      watermarkSettings(&user),
+		"pending_images": pendingWatermarkImages(&user),
+	})
+}
+
+func UploadWatermarkLogo(c *fiber.Ctx) error {
+	claims := c.Locals("user").(*jwt.Claims)
+
+	var user model.User
+	if err := database.GetDB().First(&user, claims.UserID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	file, err := c.FormFile("logo")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "No logo image provided",
+		})
+	}
+
+	if file.Size > imageutil.MaxWatermarkLogoSize {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Logo size must be less than 2MB",
+		})
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Could not read logo",
+		})
+	}
+	data, err := io.ReadAll(src)
+	src.Close()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Could not read logo",
+		})
+	}
+
+	logoURL, err := cloudflare.UploadWatermarkLogo(data, user.Username)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Could not upload logo: %v", err),
+		})
+	}
+
+	oldLogo := user.WatermarkLogoURL
+	if err := database.GetDB().Model(&user).Updates(map[string]interface{}{
+		"watermark_logo_url": logoURL,
+		"watermark_version":  gorm.Expr("watermark_version + 1"),
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not update watermark logo",
+		})
+	}
+
+	if oldLogo != "" {
+		if err := cloudflare.DeleteImage(oldLogo); err != nil {
+			log.Printf("Error deleting old watermark logo: %v", err)
+		}
+	}
+
+	database.GetDB().First(&user, user.ID)
+	return c.JSON(fiber.Map{
+		"watermark":      watermarkSettings(&user),
+		"pending_images": pendingWatermarkImages(&user),
+	})
+}
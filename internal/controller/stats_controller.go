@@ -21,6 +21,20 @@ type DashboardStats struct {
 	TopProperties     []TopProperty      `json:"top_properties"`
 	DailyStats        []DailyStat        `json:"daily_stats"`
 	PropertyTypeStats []PropertyTypeStat `json:"property_type_stats"`
+	RecentPriceDrops  []PriceDrop        `json:"recent_price_drops"`
+}
+
+// PriceDrop son dönemde fiyatı düşürülen ilan
+type PriceDrop struct {
+	PropertyID       uint      `json:"property_id"`
+	Title            string    `json:"title"`
+	Slug             string    `json:"slug"`
+	PreviousPrice    float64   `json:"previous_price"`
+	PreviousCurrency string    `json:"previous_currency"`
+	Price            float64   `json:"price"`
+	Currency         string    `json:"currency"`
+	ChangePercent    float64   `json:"change_percent"`
+	ChangedAt        time.Time `json:"changed_at"`
 }
 
 type TopProperty struct {
@@ -47,6 +61,8 @@ type PropertyTypeStat struct {
 
 const (
 	ViewCooldown = 24 * time.Hour // Aynı IP için bekleme süresi
+
+	PriceDropWindow = 30 * 24 * time.Hour // Dashboard'da gösterilen fiyat düşüşlerinin süresi
 )
 
 // GetDashboardStats dashboard istatistiklerini getirir
@@ -62,7 +78,8 @@ func GetDashboardStats(c *fiber.Ctx) error {
 		Count(&stats.TotalListings)
 
 	db.Model(&model.Property{}).
-		Where("user_id = ? AND status = ?", claims.UserID, "active").
+		Scopes(model.PublishedProperties).
+		Where("user_id = ?", claims.UserID).
 		Count(&stats.ActiveListings)
 
 	// Toplam görüntülenme
@@ -76,7 +93,7 @@ func GetDashboardStats(c *fiber.Ctx) error {
 	db.Table("properties").
 		Select("properties.id, properties.title, properties.price, properties.location, properties.type, COUNT(property_views.id) as views").
 		Joins("LEFT JOIN property_views ON properties.id = property_views.property_id").
-		Where("properties.user_id = ? AND properties.status = ?", claims.UserID, "active").
+		Where("properties.user_id = ? AND properties.publication_state = ?", claims.UserID, model.PublicationPublished).
 		Group("properties.id").
 		Order("views DESC").
 		Limit(5).
@@ -115,6 +132,22 @@ func GetDashboardStats(c *fiber.Ctx) error {
 	}
 	stats.DailyStats = dailyStats
 
+	// Son 30 günün fiyat düşüşleri
+	var priceDrops []PriceDrop
+	db.Table("property_price_histories").
+		Select(`properties.id AS property_id, properties.title, properties.slug,
+			property_price_histories.previous_price, property_price_histories.previous_currency,
+			property_price_histories.price, property_price_histories.currency,
+			property_price_histories.change_percent, property_price_histories.created_at AS changed_at`).
+		Joins("JOIN properties ON property_price_histories.property_id = properties.id").
+		Where("properties.user_id = ? AND properties.deleted_at IS NULL", claims.UserID).
+		Where("property_price_histories.deleted_at IS NULL AND property_price_histories.change_percent < 0").
+		Where("property_price_histories.created_at >= ?", time.Now().Add(-PriceDropWindow)).
+		Order("property_price_histories.created_at DESC").
+		Limit(5).
+		Scan(&priceDrops)
+	stats.RecentPriceDrops = priceDrops
+
 	return c.JSON(stats)
 }
 
@@ -128,9 +161,9 @@ func RecordPropertyView(c *fiber.Ctx) error {
 		})
 	}
 
-	// İlanın varlığını kontrol et
+	// İlanın varlığını kontrol et, yayında olmayan ilanlar sayılmaz
 	var property model.Property
-	if err := database.GetDB().First(&property, propertyID).Error; err != nil {
+	if err := database.GetDB().Scopes(model.PublishedProperties).First(&property, propertyID).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Property not found",
 		})
@@ -1,17 +1,35 @@
 package controller
 
 import (
+	"encoding/json"
 	"estepage_backend/internal/model"
 	"estepage_backend/pkg/database"
 	"estepage_backend/pkg/utils/cloudflare"
+	imageutil "estepage_backend/pkg/utils/image"
 	"estepage_backend/pkg/utils/jwt"
+	"estepage_backend/pkg/utils/validation"
 	"fmt"
 	"log"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm/clause"
 )
 
+// ImageFormOverhead resim yükleme formunun dosya dışındaki alanları ve sınırları için pay
+const ImageFormOverhead = 1024 * 1024
+
+// userWatermark yeni yüklenen resimlere basılacak filigranı ve kaydedilecek sürümü döner.
+// Logo yüklenemezse resim filigransız işlenir ve sürüm 0 kaydedilir; cron daha sonra yeniden üretir.
+func userWatermark(user *model.User) (*imageutil.Watermark, int) {
+	watermark, err := cloudflare.UserWatermark(user)
+	if err != nil {
+		log.Printf("Error loading watermark for user %d: %v", user.ID, err)
+		return nil, 0
+	}
+	return watermark, user.WatermarkVersion
+}
+
 func UploadPropertyImage(c *fiber.Ctx) error {
 	claims := c.Locals("user").(*jwt.Claims)
 	propertyID := c.Params("property_id")
@@ -79,11 +97,20 @@ func UploadPropertyImage(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := validation.ValidateImage(file); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	watermark, watermarkVersion := userWatermark(&user)
+
 	// Upload config'i hazırla ve kullan
 	config := cloudflare.UploadImageConfig{
 		File:         file,
 		Username:     user.Username,
 		PropertySlug: property.Slug,
+		Watermark:    watermark,
 	}
 
 	// Cloudflare R2'ye yükle
@@ -94,13 +121,31 @@ func UploadPropertyImage(c *fiber.Ctx) error {
 		})
 	}
 
+	variants, err := json.Marshal(result.Variants)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not save image variants",
+		})
+	}
+
 	// Veritabanına kaydet
 	image := model.PropertyImage{
 		PropertyID:   uint(propertyIDUint),
 		URL:          result.URL,
+		ThumbnailURL: result.ThumbnailURL,
 		CloudflareID: result.CloudflareID,
+		Size:         result.Size,
+		Width:        result.Width,
+		Height:       result.Height,
+		Variants:     variants,
 		Order:        int(imageCount),
 		IsCover:      imageCount == 0,
+
+		PerceptualHash:   result.PerceptualHash,
+		BlurHash:         result.Placeholder.BlurHash,
+		LQIP:             result.Placeholder.LQIP,
+		DominantColor:    result.Placeholder.DominantColor,
+		WatermarkVersion: watermarkVersion,
 	}
 
 	if err := database.GetDB().Create(&image).Error; err != nil {
@@ -109,9 +154,18 @@ func UploadPropertyImage(c *fiber.Ctx) error {
 		})
 	}
 
+	// Aynı ilanda benzer bir fotoğraf varsa uyar (yükleme engellenmez)
+	duplicates, err := model.FindDuplicateImages(database.GetDB(), image.PropertyID, image.ID, image.PerceptualHash)
+	if err != nil {
+		log.Printf("Error checking duplicate images: %v", err)
+	}
+	image.DuplicateImageIDs = duplicates
+
 	return c.Status(fiber.StatusCreated).JSON(image)
 }
 
+// DeletePropertyImage resmi siler; kalan resimlerin sırası kapatılır ve kapak silindiyse
+// ilk resim kapak olur. Dosyalar commit sonrası silinir.
 func DeletePropertyImage(c *fiber.Ctx) error {
 	claims := c.Locals("user").(*jwt.Claims)
 	imageID := c.Params("image_id")
@@ -123,31 +177,88 @@ func DeletePropertyImage(c *fiber.Ctx) error {
 		})
 	}
 
-	// Property'nin sahibi mi kontrol et
+	tx := database.GetDB().Begin()
+
+	// Property'nin sahibi mi kontrol et; aynı ilan için eşzamanlı resim işlemlerini sıraya sok
 	var property model.Property
-	if err := database.GetDB().First(&property, image.PropertyID).Error; err != nil {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&property, image.PropertyID).Error; err != nil {
+		tx.Rollback()
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Property not found",
 		})
 	}
 
 	if property.UserID != claims.UserID {
+		tx.Rollback()
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 			"error": "Not authorized",
 		})
 	}
 
-	// Cloudflare R2'den sil - artık tam URL'i kullanıyoruz
-	if err := cloudflare.DeleteImage(image.URL); err != nil {
-		log.Printf("Error deleting image from Cloudflare R2: %v", err)
+	// Başka bir hesaba ait ya da başka bir ilanda da kullanılan dosyalar silinmez
+	removed, err := removedPropertyImages(tx, claims.UserID, property.ID, []model.PropertyImage{image}, nil)
+	if err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not check image",
+		})
 	}
 
 	// Database'den sil
-	if err := database.GetDB().Delete(&image).Error; err != nil {
+	result := tx.Where("property_id = ?", property.ID).Delete(&image)
+	if result.Error != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not delete image",
+		})
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Image not found",
+		})
+	}
+
+	// Kalan resimlerin sırasını boşluksuz yap; kapak yoksa ilk resmi kapak seç
+	var remaining []model.PropertyImage
+	if err := tx.Where("property_id = ?", property.ID).Order(`"order" ASC, id ASC`).Find(&remaining).Error; err != nil {
+		tx.Rollback()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not fetch property images",
+		})
+	}
+	coverID := uint(0)
+	for _, img := range remaining {
+		if img.IsCover {
+			coverID = img.ID
+			break
+		}
+	}
+	if coverID == 0 && len(remaining) > 0 {
+		coverID = remaining[0].ID
+	}
+	for i := range remaining {
+		img := &remaining[i]
+		if img.Order == i && img.IsCover == (img.ID == coverID) {
+			continue
+		}
+		img.Order, img.IsCover = i, img.ID == coverID
+		if err := tx.Model(img).Select("order", "is_cover").Updates(img).Error; err != nil {
+			tx.Rollback()
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Could not update images",
+			})
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not delete image",
 		})
 	}
 
+	// Cloudflare R2'den tüm varyantlarıyla birlikte sil
+	deletePropertyImageObjects(removed)
+
 	return c.SendStatus(fiber.StatusNoContent)
 }
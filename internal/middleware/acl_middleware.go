@@ -32,6 +32,22 @@ func CheckPropertyOwnership() fiber.Handler {
 	}
 }
 
+// AdminOnly sadece yönetici hesaplarının erişimine izin verir
+func AdminOnly() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims := c.Locals("user").(*jwt.Claims)
+
+		var user model.User
+		if err := database.DB.Select("id", "is_admin").First(&user, claims.UserID).Error; err != nil || !user.IsAdmin {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Admin access required",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
 func CheckSubscriptionLimit() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		claims := c.Locals("user").(*jwt.Claims)
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+// Herkese açık formlar için gönderim limitleri
+const (
+	FormIPLimit      = 5
+	FormIPWindow     = 10 * time.Minute
+	FormTargetLimit  = 30
+	FormTargetWindow = time.Hour
+	formLimitMessage = "Too many submissions. Please try again later."
+
+	formTargetLimitedKey = "form_target_limited"
+)
+
+func formLimitReached(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error": formLimitMessage,
+	})
+}
+
+// FormIPRateLimit aynı IP'den gelen form gönderimlerini sınırlar. Proxy arkasında
+// PROXY_HEADER ve TRUSTED_PROXIES ayarlanmazsa tüm istemciler proxy'nin IP'sini paylaşır.
+func FormIPRateLimit() fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:               FormIPLimit,
+		Expiration:        FormIPWindow,
+		LimiterMiddleware: limiter.SlidingWindow{},
+		KeyGenerator: func(c *fiber.Ctx) string {
+			return c.IP()
+		},
+		LimitReached: formLimitReached,
+	})
+}
+
+// FormTargetRateLimit aynı ilana/emlakçıya (param ile belirtilen hedef) gelen gönderimleri
+// IP'den bağımsız olarak sayar. Limit aşıldığında istek reddedilmez, böylece saldırgan hedefin
+// formunu kapatamaz; gönderim FormTargetLimited ile işaretlenir ve spam olarak değerlendirilir.
+func FormTargetRateLimit(param string) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:               FormTargetLimit,
+		Expiration:        FormTargetWindow,
+		LimiterMiddleware: limiter.SlidingWindow{},
+		KeyGenerator: func(c *fiber.Ctx) string {
+			return param + ":" + c.Params(param)
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			c.Locals(formTargetLimitedKey, true)
+			return c.Next()
+		},
+	})
+}
+
+// FormTargetLimited istek hedef başına gönderim limitini aştıysa true döner
+func FormTargetLimited(c *fiber.Ctx) bool {
+	limited, _ := c.Locals(formTargetLimitedKey).(bool)
+	return limited
+}
@@ -0,0 +1,291 @@
+// internal/model/contact.go
+
+package model
+
+import (
+	"estepage_backend/pkg/utils/location"
+	"estepage_backend/pkg/utils/phone"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Contact emlakçının bir kişisi. Aynı kişiden gelen tüm lead'ler normalize edilmiş
+// e-posta ya da E.164 telefon numarasıyla eşleştirilip tek bir kişiye bağlanır.
+type Contact struct {
+	gorm.Model
+	UserID     uint      `json:"user_id" gorm:"not null;index:idx_contacts_user_email;index:idx_contacts_user_phone"`
+	Name       string    `json:"name"`
+	Email      string    `json:"email" gorm:"index:idx_contacts_user_email"` // Küçük harfe çevrilmiş
+	Phone      string    `json:"phone" gorm:"index:idx_contacts_user_phone"` // E.164
+	LastSeenAt time.Time `json:"last_seen_at"`                               // Son lead'in zamanı
+
+	LeadCount int64 `json:"lead_count" gorm:"-"`
+}
+
+// NormalizeEmail e-postayı eşleştirme için normalize eder; geçersizse boş döner
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return ""
+	}
+	return email
+}
+
+// LeadCallingCode ülke kodu olmadan girilen lead telefonları için varsayılan ülke kodu:
+// ilanın ülkesi, yoksa emlakçının kendi numarasının ülkesi
+func LeadCallingCode(countryCode string, agent *User) string {
+	if code := location.CallingCode(countryCode); code != "" {
+		return code
+	}
+	for _, number := range []string{agent.PhoneNumber, agent.WhatsAppNumber} {
+		if e164, ok := phone.NormalizeE164(number, ""); ok {
+			return location.CallingCodeOf(e164)
+		}
+	}
+	return ""
+}
+
+// ContactMergeCandidate e-postası bir kişiyle, telefonu başka bir kişiyle eşleşen lead'lerden
+// çıkan birleştirme önerisi. Herkese açık formdan gelen veriyle kişiler otomatik birleştirilmez;
+// emlakçı öneriyi onaylar ya da reddeder. ContactID her zaman küçük olan ID'dir.
+type ContactMergeCandidate struct {
+	gorm.Model
+	UserID      uint `json:"user_id" gorm:"not null;index"`
+	ContactID   uint `json:"contact_id" gorm:"not null;uniqueIndex:idx_contact_merge_pair"`
+	DuplicateID uint `json:"duplicate_id" gorm:"not null;uniqueIndex:idx_contact_merge_pair"`
+}
+
+// recordMergeCandidate iki kişi için birleştirme önerisi kaydeder (varsa tekrar eklemez)
+func recordMergeCandidate(tx *gorm.DB, userID, first, second uint) error {
+	candidate := ContactMergeCandidate{UserID: userID, ContactID: min(first, second), DuplicateID: max(first, second)}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&candidate).Error
+}
+
+// matchContact lead bilgileriyle eşleşen kişiyi bulur ya da oluşturur. E-posta ve telefon
+// farklı kişilere aitse lead e-postası eşleşen kişiye bağlanır ve diğerleri için birleştirme
+// önerisi kaydedilir. tx içinde, kullanıcı kilitliyken çağrılmalıdır.
+func matchContact(tx *gorm.DB, userID uint, name, email, phoneNumber string, seenAt time.Time) (*Contact, error) {
+	var matches []Contact
+	if email != "" || phoneNumber != "" {
+		query := tx.Where("user_id = ?", userID)
+		switch {
+		case email != "" && phoneNumber != "":
+			query = query.Where("email = ? OR phone = ?", email, phoneNumber)
+		case email != "":
+			query = query.Where("email = ?", email)
+		default:
+			query = query.Where("phone = ?", phoneNumber)
+		}
+		if err := query.Order("id ASC").Find(&matches).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if len(matches) == 0 {
+		contact := &Contact{
+			UserID:     userID,
+			Name:       name,
+			Email:      email,
+			Phone:      phoneNumber,
+			LastSeenAt: seenAt,
+		}
+		return contact, tx.Create(contact).Error
+	}
+
+	// E-postası eşleşen kişi tercih edilir, yoksa en eskisi
+	contact := &matches[0]
+	for i := range matches {
+		if email != "" && matches[i].Email == email {
+			contact = &matches[i]
+			break
+		}
+	}
+
+	if len(matches) > 1 {
+		for _, other := range matches {
+			if other.ID == contact.ID {
+				continue
+			}
+			if err := recordMergeCandidate(tx, userID, contact.ID, other.ID); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		// Bilgiler sadece çakışma yoksa tamamlanır; aksi halde aynı numara iki kişide kalır
+		if contact.Email == "" {
+			contact.Email = email
+		}
+		if contact.Phone == "" {
+			contact.Phone = phoneNumber
+		}
+	}
+
+	if contact.Name == "" {
+		contact.Name = name
+	}
+	if seenAt.After(contact.LastSeenAt) {
+		contact.LastSeenAt = seenAt
+	}
+
+	err := tx.Model(contact).Select("name", "email", "phone", "last_seen_at").Updates(contact).Error
+	return contact, err
+}
+
+// MergeContacts emlakçının onayıyla duplicate kişiyi contact'a birleştirir: lead'ler taşınır,
+// boş alanlar tamamlanır, duplicate ve ona ait birleştirme önerileri silinir.
+func MergeContacts(db *gorm.DB, userID, contactID, duplicateID uint) (*Contact, error) {
+	var contact Contact
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").First(&User{}, userID).Error; err != nil {
+			return err
+		}
+
+		var duplicate Contact
+		if err := tx.Where("id = ? AND user_id = ?", contactID, userID).First(&contact).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ? AND user_id = ?", duplicateID, userID).First(&duplicate).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&Lead{}).Where("contact_id = ?", duplicate.ID).
+			Update("contact_id", contact.ID).Error; err != nil {
+			return err
+		}
+
+		if contact.Name == "" {
+			contact.Name = duplicate.Name
+		}
+		if contact.Email == "" {
+			contact.Email = duplicate.Email
+		}
+		if contact.Phone == "" {
+			contact.Phone = duplicate.Phone
+		}
+		if duplicate.LastSeenAt.After(contact.LastSeenAt) {
+			contact.LastSeenAt = duplicate.LastSeenAt
+		}
+		if err := tx.Model(&contact).Select("name", "email", "phone", "last_seen_at").Updates(&contact).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().
+			Where("contact_id = ? OR duplicate_id = ?", duplicate.ID, duplicate.ID).
+			Delete(&ContactMergeCandidate{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&duplicate).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// ContactMergeCandidates kişi için bekleyen birleştirme önerilerindeki diğer kişileri döner
+func ContactMergeCandidates(db *gorm.DB, userID, contactID uint) ([]Contact, error) {
+	contacts := []Contact{}
+	err := db.Where("user_id = ? AND id IN (?)", userID,
+		db.Model(&ContactMergeCandidate{}).
+			Select("CASE WHEN contact_id = ? THEN duplicate_id ELSE contact_id END", contactID).
+			Where("user_id = ? AND (contact_id = ? OR duplicate_id = ?)", userID, contactID, contactID)).
+		Order("id ASC").
+		Find(&contacts).Error
+	return contacts, err
+}
+
+// linkLeadContact lead'i kişisine bağlar. Aynı emlakçı için eşzamanlı eşleştirmeler
+// kullanıcı satırı kilitlenerek sıraya sokulur.
+func linkLeadContact(tx *gorm.DB, lead *Lead, callingCode string) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").First(&User{}, lead.UserID).Error; err != nil {
+		return err
+	}
+
+	phoneNumber, _ := phone.NormalizeE164(lead.Phone, callingCode)
+	seenAt := lead.CreatedAt
+	if seenAt.IsZero() {
+		seenAt = time.Now()
+	}
+
+	contact, err := matchContact(tx, lead.UserID, strings.TrimSpace(lead.Name), NormalizeEmail(lead.Email), phoneNumber, seenAt)
+	if err != nil {
+		return err
+	}
+	lead.ContactID = &contact.ID
+	return nil
+}
+
+// CreateLeadWithContact lead'i kişisiyle eşleştirip kaydeder. Aşaması verilmemiş lead
+// emlakçının pipeline'ının ilk aşamasına konur; pipeline okunamazsa lead kaydedilmez.
+func CreateLeadWithContact(db *gorm.DB, lead *Lead, callingCode string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Kullanıcı satırını kilitler; aşama silme işlemiyle aynı sıraya girer
+		if err := linkLeadContact(tx, lead, callingCode); err != nil {
+			return err
+		}
+
+		if lead.StageID == nil {
+			stage, err := InitialLeadStage(tx, lead.UserID)
+			if err != nil {
+				return err
+			}
+			lead.StageID = &stage.ID
+			lead.Status = LeadStatus(stage.Key)
+		}
+		return tx.Create(lead).Error
+	})
+}
+
+// LinkLeadsToContacts kişiye bağlı olmayan (eski) lead'leri kişileriyle eşleştirir
+func LinkLeadsToContacts(db *gorm.DB) error {
+	agents := map[uint]*User{}
+	linked := 0
+
+	var leads []Lead
+	err := db.Where("contact_id IS NULL").
+		Preload("Property").
+		FindInBatches(&leads, 200, func(batch *gorm.DB, _ int) error {
+			for i := range leads {
+				lead := &leads[i]
+
+				agent, ok := agents[lead.UserID]
+				if !ok {
+					agent = &User{}
+					if err := db.First(agent, lead.UserID).Error; err != nil {
+						log.Printf("Could not load agent %d for lead %d: %v", lead.UserID, lead.ID, err)
+						continue
+					}
+					agents[lead.UserID] = agent
+				}
+
+				countryCode := ""
+				if lead.Property != nil {
+					countryCode = lead.Property.CountryCode
+				}
+
+				err := db.Transaction(func(tx *gorm.DB) error {
+					if err := linkLeadContact(tx, lead, LeadCallingCode(countryCode, agent)); err != nil {
+						return err
+					}
+					return tx.Model(&Lead{}).Where("id = ?", lead.ID).Update("contact_id", lead.ContactID).Error
+				})
+				if err != nil {
+					return err
+				}
+				linked++
+			}
+			return nil
+		}).Error
+
+	if linked > 0 {
+		log.Printf("Linked %d leads to contacts", linked)
+	}
+	return err
+}
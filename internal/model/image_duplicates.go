@@ -0,0 +1,134 @@
+package model
+
+import (
+	"estepage_backend/pkg/utils/image"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FormatPerceptualHash hash'i veritabanında tutulan hex biçimine çevirir
+func FormatPerceptualHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+func parsePerceptualHash(value string) (uint64, bool) {
+	hash, err := strconv.ParseUint(value, 16, 64)
+	return hash, err == nil && value != ""
+}
+
+// FindDuplicateImages aynı ilandaki, verilen hash'e yakın resimlerin ID'lerini döner
+func FindDuplicateImages(db *gorm.DB, propertyID, excludeImageID uint, perceptualHash string) ([]uint, error) {
+	hash, ok := parsePerceptualHash(perceptualHash)
+	if !ok {
+		return nil, nil
+	}
+
+	var images []PropertyImage
+	if err := db.Select("id", "perceptual_hash").
+		Where("property_id = ? AND id <> ? AND perceptual_hash <> ''", propertyID, excludeImageID).
+		Find(&images).Error; err != nil {
+		return nil, err
+	}
+
+	var duplicates []uint
+	for _, img := range images {
+		other, ok := parsePerceptualHash(img.PerceptualHash)
+		if ok && image.HammingDistance(hash, other) <= image.DuplicateHashThreshold {
+			duplicates = append(duplicates, img.ID)
+		}
+	}
+	return duplicates, nil
+}
+
+// CrossAccountDuplicate farklı hesaplara ait ilanlarda bulunan benzer resim çifti
+type CrossAccountDuplicate struct {
+	Distance int `json:"distance"`
+
+	ImageID    uint      `json:"image_id"`
+	ImageURL   string    `json:"image_url"`
+	PropertyID uint      `json:"property_id"`
+	UserID     uint      `json:"user_id"`
+	Username   string    `json:"username"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	OtherImageID    uint      `json:"other_image_id"`
+	OtherImageURL   string    `json:"other_image_url"`
+	OtherPropertyID uint      `json:"other_property_id"`
+	OtherUserID     uint      `json:"other_user_id"`
+	OtherUsername   string    `json:"other_username"`
+	OtherCreatedAt  time.Time `json:"other_created_at"`
+}
+
+// hammingSQL iki hex hash kolonu arasındaki Hamming mesafesini Postgres'te hesaplar
+func hammingSQL(a, b string) string {
+	return "length(replace((('x' || lpad(" + a + ", 16, '0'))::bit(64) # ('x' || lpad(" + b + ", 16, '0'))::bit(64))::text, '0', ''))"
+}
+
+// hashBandsSQL 64 bitlik hash'i threshold+1 parçaya bölen (band, başlangıç biti, uzunluk)
+// VALUES listesi. Mesafesi threshold ve altındaki iki hash güvercin yuvası ilkesine göre
+// en az bir parçada birebir aynıdır; bu yüzden sadece ortak parçası olan çiftler karşılaştırılır.
+func hashBandsSQL(threshold int) string {
+	bands := threshold + 1
+	rows := make([]string, 0, bands)
+	start := 1
+	for band := 0; band < bands; band++ {
+		length := 64 / bands
+		if band < 64%bands {
+			length++
+		}
+		rows = append(rows, fmt.Sprintf("(%d, %d, %d)", band, start, length))
+		start += length
+	}
+	return "VALUES " + strings.Join(rows, ", ")
+}
+
+// FindCrossAccountDuplicates farklı kullanıcıların silinmemiş ilanlarında bulunan benzer resimleri listeler.
+// Her çiftte ilk resim daha önce yüklenmiş olandır (muhtemel orijinal). Tüm resimleri birbiriyle
+// karşılaştırmak yerine hash parçalarına göre gruplanır; Hamming mesafesi sadece aynı gruba
+// düşen adaylar için hesaplanır.
+func FindCrossAccountDuplicates(db *gorm.DB, limit, offset int) ([]CrossAccountDuplicate, error) {
+	distance := hammingSQL("a.perceptual_hash", "b.perceptual_hash")
+
+	var rows []CrossAccountDuplicate
+	err := db.Raw(`
+		WITH hashes AS (
+			SELECT i.id, i.created_at, p.user_id,
+				('x' || lpad(i.perceptual_hash, 16, '0'))::bit(64) AS bits
+			FROM property_images i
+			JOIN properties p ON p.id = i.property_id AND p.deleted_at IS NULL
+			WHERE i.perceptual_hash <> '' AND i.deleted_at IS NULL
+		),
+		bands AS (
+			SELECT h.id, h.created_at, h.user_id, band.id AS band,
+				substring(h.bits FROM band.start FOR band.length) AS value
+			FROM hashes h
+			CROSS JOIN (`+hashBandsSQL(image.DuplicateHashThreshold)+`) AS band(id, start, length)
+		),
+		candidates AS (
+			SELECT DISTINCT x.id AS image_id, y.id AS other_image_id
+			FROM bands x
+			JOIN bands y ON y.band = x.band AND y.value = x.value AND y.user_id <> x.user_id
+				AND (y.created_at > x.created_at OR (y.created_at = x.created_at AND y.id > x.id))
+		)
+		SELECT `+distance+` AS distance,
+			a.id AS image_id, a.url AS image_url, pa.id AS property_id, ua.id AS user_id, ua.username AS username,
+			a.created_at AS created_at,
+			b.id AS other_image_id, b.url AS other_image_url, pb.id AS other_property_id, ub.id AS other_user_id,
+			ub.username AS other_username, b.created_at AS other_created_at
+		FROM candidates
+		JOIN property_images a ON a.id = candidates.image_id
+		JOIN properties pa ON pa.id = a.property_id
+		JOIN users ua ON ua.id = pa.user_id
+		JOIN property_images b ON b.id = candidates.other_image_id
+		JOIN properties pb ON pb.id = b.property_id
+		JOIN users ub ON ub.id = pb.user_id
+		WHERE `+distance+` <= ?
+		ORDER BY distance ASC, b.created_at DESC, a.id, b.id
+		LIMIT ? OFFSET ?`, image.DuplicateHashThreshold, limit, offset).
+		Scan(&rows).Error
+	return rows, err
+}
@@ -4,7 +4,9 @@ package model
 
 import (
 	"estepage_backend/pkg/database"
+	"time"
 
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -15,6 +17,7 @@ const (
 	LeadSourceProfile  LeadSource = "profile_page"
 	LeadSourceProperty LeadSource = "property_page"
 
+	// Varsayılan pipeline aşamalarının key'leri (bkz. DefaultLeadStages)
 	LeadStatusNew        LeadStatus = "new"
 	LeadStatusRead       LeadStatus = "read"
 	LeadStatusContacted  LeadStatus = "contacted"
@@ -31,9 +34,24 @@ type Lead struct {
 	Email      string     `json:"email"`
 	Phone      string     `json:"phone"`
 	Message    string     `json:"message" gorm:"type:text"`
-	Status     LeadStatus `json:"status" gorm:"type:string;default:'new'"`
+	Status     LeadStatus `json:"status" gorm:"type:string;default:'new'"` // Aşamanın key'i
 	ReadStatus bool       `json:"read_status" gorm:"default:false"`
 
+	// Pipeline aşaması; ClosedAt kazanıldı/kaybedildi aşamasına geçilen zaman
+	StageID  *uint      `json:"stage_id" gorm:"index"`
+	ClosedAt *time.Time `json:"closed_at"`
+
+	// Aynı kişinin tüm lead'leri tek kişiye bağlanır (bkz. Contact)
+	ContactID *uint `json:"contact_id" gorm:"index"`
+
+	// Spam değerlendirmesi; işaretli lead'ler için bildirim e-postası gönderilmez
+	SpamScore   int            `json:"spam_score" gorm:"default:0"`
+	SpamFlagged bool           `json:"spam_flagged" gorm:"default:false;index"`
+	SpamReasons datatypes.JSON `json:"spam_reasons"` // []string
+
+	// Ziyaretçiye otomatik yanıt gönderildiyse zamanı (gönderim limitleri için)
+	AutoRepliedAt *time.Time `json:"auto_replied_at" gorm:"index"`
+
 	// Property detayları - hepsi nullable
 	PropertyTitle    *string  `json:"property_title,omitempty"`
 	PropertyPrice    *float64 `json:"property_price,omitempty"`
@@ -41,7 +59,9 @@ type Lead struct {
 	PropertyCurrency *string  `json:"property_currency,omitempty"`
 
 	// İlişkiler
-	Property *Property `json:"property,omitempty" gorm:"foreignKey:PropertyID"`
+	Property *Property  `json:"property,omitempty" gorm:"foreignKey:PropertyID"`
+	Stage    *LeadStage `json:"stage,omitempty" gorm:"foreignKey:StageID"`
+	Contact  *Contact   `json:"contact,omitempty" gorm:"foreignKey:ContactID"`
 }
 
 // Property modelini de ayrıca güncelleyelim
@@ -0,0 +1,104 @@
+// internal/model/lead_activity.go
+
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LeadActivityType string
+
+const (
+	LeadActivityNote         LeadActivityType = "note"
+	LeadActivityCall         LeadActivityType = "call"
+	LeadActivityEmail        LeadActivityType = "email"
+	LeadActivityMeeting      LeadActivityType = "meeting"
+	LeadActivityStatusChange LeadActivityType = "status_change" // Sistem tarafından kaydedilir
+)
+
+// LoggableLeadActivityTypes emlakçının elle ekleyebileceği aktivite türleri
+var LoggableLeadActivityTypes = map[LeadActivityType]bool{
+	LeadActivityNote:    true,
+	LeadActivityCall:    true,
+	LeadActivityEmail:   true,
+	LeadActivityMeeting: true,
+}
+
+// LeadActivity lead zaman akışındaki tek bir kayıt: not, görüşme ya da durum değişikliği
+type LeadActivity struct {
+	gorm.Model
+	LeadID     uint             `json:"lead_id" gorm:"not null;index"`
+	UserID     uint             `json:"user_id" gorm:"not null"`
+	Type       LeadActivityType `json:"type" gorm:"type:varchar(20);not null"`
+	Body       string           `json:"body" gorm:"type:text"`
+	OccurredAt time.Time        `json:"occurred_at" gorm:"not null;index"` // Görüşmenin yapıldığı zaman
+
+	// Sadece status_change kayıtlarında dolu
+	FromStatus LeadStatus `json:"from_status,omitempty" gorm:"type:varchar(50)"`
+	ToStatus   LeadStatus `json:"to_status,omitempty" gorm:"type:varchar(50)"`
+}
+
+// LeadReminder emlakçıya zamanı gelince e-postayla hatırlatılan takip
+type LeadReminder struct {
+	gorm.Model
+	LeadID      uint       `json:"lead_id" gorm:"not null;index"`
+	UserID      uint       `json:"user_id" gorm:"not null;index"`
+	Note        string     `json:"note" gorm:"type:text"`
+	DueAt       time.Time  `json:"due_at" gorm:"not null;index"`
+	CompletedAt *time.Time `json:"completed_at"`
+	NotifiedAt  *time.Time `json:"notified_at"` // Hatırlatma e-postasının gönderildiği zaman
+
+	Lead *Lead `json:"lead,omitempty" gorm:"foreignKey:LeadID"`
+}
+
+// RecordLeadStatusChange durum değişikliğini lead'in zaman akışına ekler
+func RecordLeadStatusChange(tx *gorm.DB, lead *Lead, userID uint, from, to LeadStatus) error {
+	return tx.Create(&LeadActivity{
+		LeadID:     lead.ID,
+		UserID:     userID,
+		Type:       LeadActivityStatusChange,
+		OccurredAt: time.Now(),
+		FromStatus: from,
+		ToStatus:   to,
+	}).Error
+}
+
+// ClaimDueLeadReminders zamanı gelmiş, tamamlanmamış ve henüz bildirilmemiş en fazla limit
+// hatırlatmayı notified_at işaretlenerek tek sorguda sahiplenir. Aynı anda çalışan başka bir
+// işlem (ya da instance) kilitli satırları atlar, böylece bir hatırlatma iki kez gönderilmez.
+// Gönderilemeyen hatırlatmalar ReleaseLeadReminder ile tekrar kuyruğa bırakılmalıdır.
+func ClaimDueLeadReminders(db *gorm.DB, now time.Time, limit int) ([]LeadReminder, error) {
+	var ids []uint
+	if err := db.Raw(`
+		UPDATE lead_reminders SET notified_at = ?
+		WHERE id IN (
+			SELECT lead_reminders.id FROM lead_reminders
+			JOIN leads ON leads.id = lead_reminders.lead_id AND leads.deleted_at IS NULL
+			WHERE lead_reminders.due_at <= ? AND lead_reminders.completed_at IS NULL
+				AND lead_reminders.notified_at IS NULL AND lead_reminders.deleted_at IS NULL
+			ORDER BY lead_reminders.due_at ASC
+			LIMIT ?
+			FOR UPDATE OF lead_reminders SKIP LOCKED
+		)
+		RETURNING id`, now, now, limit).
+		Scan(&ids).Error; err != nil {
+		return nil, err
+	}
+
+	reminders := []LeadReminder{}
+	if len(ids) == 0 {
+		return reminders, nil
+	}
+	err := db.Where("id IN ?", ids).
+		Preload("Lead").
+		Order("due_at ASC").
+		Find(&reminders).Error
+	return reminders, err
+}
+
+// ReleaseLeadReminder sahiplenilen ama gönderilemeyen hatırlatmayı sonraki çalışmaya bırakır
+func ReleaseLeadReminder(db *gorm.DB, id uint) error {
+	return db.Model(&LeadReminder{}).Where("id = ?", id).Update("notified_at", nil).Error
+}
@@ -0,0 +1,232 @@
+// internal/model/lead_auto_reply.go
+
+package model
+
+import (
+	"estepage_backend/pkg/utils/phone"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+const (
+	MaxLeadAutoReplySubjectLength = 200
+	MaxLeadAutoReplyBodyLength    = 5000
+
+	// Şablona eklenen ziyaretçi adının üst sınırı
+	MaxLeadNameLength = 40
+
+	// Formlar üçüncü kişilerin adresine e-posta göndermek için kullanılamasın diye
+	// aynı alıcıya ve aynı emlakçı adına gönderilen otomatik yanıtlar sınırlanır
+	MaxAutoRepliesPerRecipient = 2
+	AutoReplyRecipientWindow   = 24 * time.Hour
+	MaxAutoRepliesPerAgent     = 30
+	AutoReplyAgentWindow       = time.Hour
+)
+
+// LeadAutoReply lead gönderen ziyaretçiye otomatik gönderilen yanıt şablonu. PropertyID 0 ise
+// emlakçının tüm lead'leri için geçerli genel şablondur; ilana özel şablon genel olanı ezer,
+// kapalı bir ilan şablonu o ilan için otomatik yanıtı tamamen kapatır.
+type LeadAutoReply struct {
+	gorm.Model
+	UserID     uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_lead_auto_reply_target"`
+	PropertyID uint   `json:"property_id" gorm:"not null;default:0;uniqueIndex:idx_lead_auto_reply_target"`
+	Enabled    bool   `json:"enabled"`
+	Subject    string `json:"subject" gorm:"type:varchar(200)"` // Boşsa varsayılan konu kullanılır
+	Body       string `json:"body" gorm:"type:text;not null"`
+}
+
+// LeadAutoReplyVariables şablonlarda {{değişken}} olarak kullanılabilecek alanlar
+var LeadAutoReplyVariables = []string{
+	"lead_name",
+	"agent_name",
+	"company_name",
+	"agent_email",
+	"agent_phone",
+	"whatsapp_link",
+	"profile_url",
+	"property_title",
+	"property_price",
+	"property_url",
+}
+
+var (
+	autoReplyVariablePattern = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+	leadNameLinkPattern      = regexp.MustCompile(`(?i)\S*(https?://|www\.|\.[a-z]{2,})\S*`)
+)
+
+// SanitizeLeadName ziyaretçinin girdiği adı e-postada kullanılabilecek kısa, düz bir isme
+// indirger: linkler ve alan adları, harf dışı karakterler atılır, uzunluk sınırlanır.
+// Böylece form, emlakçının adıyla başkalarına link ya da mesaj göndermek için kullanılamaz.
+func SanitizeLeadName(name string) string {
+	name = leadNameLinkPattern.ReplaceAllString(name, " ")
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsMark(r) || r == '\'' || r == '-' {
+			return r
+		}
+		return ' '
+	}, name)
+
+	var result []rune
+	for _, word := range strings.Fields(name) {
+		if strings.Trim(word, "'-") == "" {
+			continue
+		}
+		candidate := []rune(word)
+		if len(result) > 0 {
+			candidate = append([]rune{' '}, candidate...)
+		}
+		if len(result)+len(candidate) > MaxLeadNameLength {
+			break
+		}
+		result = append(result, candidate...)
+	}
+	return string(result)
+}
+
+// AutoReplyAllowed alıcıya ve emlakçıya ait gönderim limitleri aşılmadıysa true döner
+func AutoReplyAllowed(db *gorm.DB, agentID uint, recipient string, now time.Time) (bool, error) {
+	var recipientCount int64
+	if err := db.Model(&Lead{}).
+		Where("LOWER(email) = LOWER(?) AND auto_replied_at > ?", strings.TrimSpace(recipient), now.Add(-AutoReplyRecipientWindow)).
+		Count(&recipientCount).Error; err != nil {
+		return false, err
+	}
+	if recipientCount >= MaxAutoRepliesPerRecipient {
+		return false, nil
+	}
+
+	var agentCount int64
+	if err := db.Model(&Lead{}).
+		Where("user_id = ? AND auto_replied_at > ?", agentID, now.Add(-AutoReplyAgentWindow)).
+		Count(&agentCount).Error; err != nil {
+		return false, err
+	}
+	return agentCount < MaxAutoRepliesPerAgent, nil
+}
+
+// AutoReplyContext şablonun doldurulduğu lead, emlakçı ve (varsa) ilan
+type AutoReplyContext struct {
+	Lead     *Lead
+	Agent    *User
+	Property *Property // Profil lead'lerinde nil
+}
+
+// AutoReplyMessage doldurulmuş otomatik yanıt
+type AutoReplyMessage struct {
+	Subject      string `json:"subject"`
+	Body         string `json:"body"`
+	AgentName    string `json:"agent_name"`
+	WhatsAppLink string `json:"whatsapp_link"`
+	PropertyURL  string `json:"property_url"`
+}
+
+// AgentDisplayName emlakçının ziyaretçilere gösterilen adı
+func AgentDisplayName(agent *User) string {
+	if name := agent.GetFullName(); name != "" {
+		return name
+	}
+	if agent.CompanyName != "" {
+		return agent.CompanyName
+	}
+	return agent.Username
+}
+
+// AgentWhatsAppLink emlakçının WhatsApp numarasına wa.me linki; ilan varsa mesaj ilanla doldurulur
+func AgentWhatsAppLink(agent *User, property *Property) string {
+	countryCode := ""
+	if property != nil {
+		countryCode = property.CountryCode
+	}
+
+	e164, ok := phone.NormalizeE164(agent.WhatsAppNumber, LeadCallingCode(countryCode, agent))
+	if !ok {
+		return ""
+	}
+
+	link := "https://wa.me/" + strings.TrimPrefix(e164, "+")
+	if property != nil {
+		link += "?text=" + url.QueryEscape("Hi, I'm interested in "+property.Title)
+	}
+	return link
+}
+
+// Render şablonu bağlamla doldurur. Bilinmeyen değişkenler olduğu gibi bırakılır.
+func (r *LeadAutoReply) Render(ctx AutoReplyContext) AutoReplyMessage {
+	agent := ctx.Agent
+	message := AutoReplyMessage{
+		AgentName:    AgentDisplayName(agent),
+		WhatsAppLink: AgentWhatsAppLink(agent, ctx.Property),
+	}
+
+	agentEmail := agent.BusinessEmail
+	if agentEmail == "" {
+		agentEmail = agent.Email
+	}
+
+	values := map[string]string{
+		"lead_name":     SanitizeLeadName(ctx.Lead.Name),
+		"agent_name":    message.AgentName,
+		"company_name":  agent.CompanyName,
+		"agent_email":   agentEmail,
+		"agent_phone":   agent.PhoneNumber,
+		"whatsapp_link": message.WhatsAppLink,
+		"profile_url":   "https://estapage.com/" + agent.Username,
+	}
+	if ctx.Property != nil {
+		message.PropertyURL = PropertyPublicURL(agent.Username, ctx.Property.Slug)
+		values["property_title"] = ctx.Property.Title
+		values["property_price"] = fmt.Sprintf("%.0f %s", ctx.Property.Price, ctx.Property.Currency)
+		values["property_url"] = message.PropertyURL
+	}
+
+	fill := func(text string) string {
+		return autoReplyVariablePattern.ReplaceAllStringFunc(text, func(match string) string {
+			name := autoReplyVariablePattern.FindStringSubmatch(match)[1]
+			if value, ok := values[name]; ok {
+				return value
+			}
+			for _, variable := range LeadAutoReplyVariables {
+				if variable == name {
+					return "" // İlan değişkeni profil lead'inde boş kalır
+				}
+			}
+			return match
+		})
+	}
+
+	subject := strings.TrimSpace(r.Subject)
+	if subject == "" {
+		if ctx.Property != nil {
+			subject = "Re: {{property_title}}"
+		} else {
+			subject = "Thanks for contacting {{agent_name}}"
+		}
+	}
+
+	message.Subject = strings.Join(strings.Fields(fill(subject)), " ")
+	message.Body = strings.TrimSpace(fill(r.Body))
+	return message
+}
+
+// ResolveLeadAutoReply lead için geçerli şablonu döner: önce ilana özel, yoksa genel şablon.
+// Otomatik yanıt yoksa ya da kapalıysa nil döner.
+func ResolveLeadAutoReply(db *gorm.DB, userID, propertyID uint) (*LeadAutoReply, error) {
+	var replies []LeadAutoReply
+	if err := db.Where("user_id = ? AND property_id IN ?", userID, []uint{0, propertyID}).
+		Order("property_id DESC").
+		Limit(1).
+		Find(&replies).Error; err != nil {
+		return nil, err
+	}
+
+	if len(replies) == 0 || !replies[0].Enabled {
+		return nil, nil
+	}
+	return &replies[0], nil
+}
@@ -0,0 +1,205 @@
+// internal/model/lead_pipeline.go
+
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StageOutcome string
+
+const (
+	StageOutcomeOpen StageOutcome = "open"
+	StageOutcomeWon  StageOutcome = "won"
+	StageOutcomeLost StageOutcome = "lost"
+)
+
+var StageOutcomes = map[StageOutcome]bool{
+	StageOutcomeOpen: true,
+	StageOutcomeWon:  true,
+	StageOutcomeLost: true,
+}
+
+const (
+	MaxLeadStages         = 20
+	MaxLeadStageKeyLength = 50
+)
+
+// LeadStage emlakçının lead pipeline'ındaki bir aşama. Key lead'in Status alanında tutulur;
+// pipeline'ın ilk aşaması yeni lead'lerin başladığı aşamadır ve her zaman açık olmalıdır.
+type LeadStage struct {
+	gorm.Model
+	UserID  uint         `json:"user_id" gorm:"not null;uniqueIndex:idx_lead_stage_user_key"`
+	Key     string       `json:"key" gorm:"type:varchar(50);not null;uniqueIndex:idx_lead_stage_user_key"`
+	Name    string       `json:"name" gorm:"type:varchar(100);not null"`
+	Order   int          `json:"order" gorm:"default:0"`
+	Outcome StageOutcome `json:"outcome" gorm:"type:varchar(10);not null;default:'open'"`
+	Color   string       `json:"color" gorm:"type:varchar(7)"`
+}
+
+// DefaultLeadStages eski sabit durumlarla birebir aynı varsayılan pipeline
+var DefaultLeadStages = []LeadStage{
+	{Key: string(LeadStatusNew), Name: "New", Outcome: StageOutcomeOpen},
+	{Key: string(LeadStatusRead), Name: "Read", Outcome: StageOutcomeOpen},
+	{Key: string(LeadStatusContacted), Name: "Contacted", Outcome: StageOutcomeOpen},
+	// Yanıt vermeyen lead'ler hâlâ takip edilir; kapalı sayılmaz
+	{Key: string(LeadStatusNoResponse), Name: "No Response", Outcome: StageOutcomeOpen},
+	{Key: string(LeadStatusCompleted), Name: "Completed", Outcome: StageOutcomeWon},
+}
+
+// IsClosed kazanıldı ya da kaybedildi aşaması mı
+func (s *LeadStage) IsClosed() bool {
+	return s.Outcome == StageOutcomeWon || s.Outcome == StageOutcomeLost
+}
+
+// LeadStages kullanıcının pipeline'ını sırasıyla döner; hiç aşaması yoksa varsayılanları oluşturur
+func LeadStages(db *gorm.DB, userID uint) ([]LeadStage, error) {
+	var stages []LeadStage
+	if err := db.Where("user_id = ?", userID).Order(`"order" ASC, id ASC`).Find(&stages).Error; err != nil {
+		return nil, err
+	}
+	if len(stages) > 0 {
+		return stages, nil
+	}
+
+	stages = make([]LeadStage, len(DefaultLeadStages))
+	for i, stage := range DefaultLeadStages {
+		stage.UserID = userID
+		stage.Order = i
+		stages[i] = stage
+	}
+
+	// Eşzamanlı isteklerde ikinci ekleme sessizce atlanır
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&stages).Error; err != nil {
+		return nil, err
+	}
+
+	stages = nil
+	err := db.Where("user_id = ?", userID).Order(`"order" ASC, id ASC`).Find(&stages).Error
+	return stages, err
+}
+
+// InitialLeadStage yeni lead'lerin başlayacağı aşama (pipeline'ın ilk aşaması)
+func InitialLeadStage(db *gorm.DB, userID uint) (*LeadStage, error) {
+	stages, err := LeadStages(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &stages[0], nil
+}
+
+// ValidatePipeline pipeline'ın kurallarını kontrol eder (stages sıralı olmalı)
+func ValidatePipeline(stages []LeadStage) error {
+	if len(stages) == 0 {
+		return fmt.Errorf("pipeline must have at least one stage")
+	}
+	if len(stages) > MaxLeadStages {
+		return fmt.Errorf("pipeline can have at most %d stages", MaxLeadStages)
+	}
+	if stages[0].Outcome != StageOutcomeOpen {
+		return fmt.Errorf("the first stage must be an open stage")
+	}
+	return nil
+}
+
+// NewLeadStageKey aşama adından kullanıcının pipeline'ında benzersiz bir key üretir
+func NewLeadStageKey(name string, stages []LeadStage) string {
+	base := strings.ReplaceAll(slug.Make(name), "-", "_")
+	if base == "" {
+		base = "stage"
+	}
+	if len(base) > MaxLeadStageKeyLength-4 {
+		base = strings.TrimRight(base[:MaxLeadStageKeyLength-4], "_")
+	}
+
+	taken := make(map[string]bool, len(stages))
+	for _, stage := range stages {
+		taken[stage.Key] = true
+	}
+
+	key := base
+	for i := 2; taken[key]; i++ {
+		key = fmt.Sprintf("%s_%d", base, i)
+	}
+	return key
+}
+
+// MoveLeadToStage lead'i aşamaya taşır, kapanış zamanını günceller ve
+// değişikliği zaman akışına kaydeder
+func MoveLeadToStage(tx *gorm.DB, lead *Lead, stage *LeadStage, userID uint) error {
+	previousStatus := lead.Status
+
+	var closedAt *time.Time
+	if stage.IsClosed() {
+		// Kapalı aşamalar arası geçişte ilk kapanış zamanı korunur
+		closedAt = lead.ClosedAt
+		if closedAt == nil {
+			now := time.Now()
+			closedAt = &now
+		}
+	}
+
+	if err := tx.Model(lead).Updates(map[string]interface{}{
+		"stage_id":  stage.ID,
+		"status":    stage.Key,
+		"closed_at": closedAt,
+	}).Error; err != nil {
+		return err
+	}
+	lead.StageID = &stage.ID
+	lead.Status = LeadStatus(stage.Key)
+	lead.ClosedAt = closedAt
+
+	if previousStatus == lead.Status {
+		return nil
+	}
+	return RecordLeadStatusChange(tx, lead, userID, previousStatus, lead.Status)
+}
+
+// AssignUnstagedLeads aşaması olmayan (pipeline öncesi) lead'leri Status değerine karşılık gelen
+// aşamaya, eşleşme yoksa ilk aşamaya bağlar. userID 0 ise tüm kullanıcılar için çalışır.
+// Yeni lead'ler oluşturulurken aşamaya bağlandığından sadece açılışta çalıştırılır.
+func AssignUnstagedLeads(db *gorm.DB, userID uint) error {
+	query := db.Model(&Lead{}).Where("stage_id IS NULL")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var userIDs []uint
+	if err := query.Distinct("user_id").Pluck("user_id", &userIDs).Error; err != nil {
+		return err
+	}
+
+	for _, id := range userIDs {
+		stages, err := LeadStages(db, id)
+		if err != nil {
+			return err
+		}
+
+		if err := db.Exec(`UPDATE leads SET stage_id = lead_stages.id,
+			closed_at = CASE WHEN lead_stages.outcome IN ('won', 'lost') THEN leads.updated_at END
+			FROM lead_stages
+			WHERE leads.stage_id IS NULL AND leads.user_id = ?
+			AND lead_stages.user_id = leads.user_id AND lead_stages.key = leads.status
+			AND lead_stages.deleted_at IS NULL`, id).Error; err != nil {
+			return err
+		}
+
+		if err := db.Model(&Lead{}).
+			Where("stage_id IS NULL AND user_id = ?", id).
+			Updates(map[string]interface{}{
+				"stage_id": stages[0].ID,
+				"status":   stages[0].Key,
+			}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
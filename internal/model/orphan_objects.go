@@ -0,0 +1,247 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"estepage_backend/pkg/utils/storage"
+	"strings"
+	"time"
+
+	"github.com/gosimple/slug"
+	"gorm.io/gorm"
+)
+
+// OrphanGracePeriod bu süreden yeni dosyalar referanssız olsa da silinmez
+// (yükleme ile veritabanı kaydı arasındaki yarış için)
+const OrphanGracePeriod = 24 * time.Hour
+
+// OrphanObject depolamada olup hiçbir kayıt tarafından kullanılmayan dosya
+type OrphanObject struct {
+	Key          string    `json:"key"`
+	URL          string    `json:"url"`
+	Username     string    `json:"username"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	Deletable    bool      `json:"deletable"` // Bekleme süresi dolmuş ve prefix tek hesaba ait mi
+	Shared       bool      `json:"shared"`    // Prefix'e birden fazla hesap eşleniyor
+}
+
+type OrphanReport struct {
+	ScannedUsers   int            `json:"scanned_users"`
+	ScannedObjects int            `json:"scanned_objects"`
+	OrphanCount    int            `json:"orphan_count"`
+	DeletableCount int            `json:"deletable_count"`
+	OrphanBytes    int64          `json:"orphan_bytes"`
+	Orphans        []OrphanObject `json:"orphans"`
+}
+
+// UserStoragePrefixes kullanıcının dosyalarının bulunduğu prefix'ler.
+// İlan resimleri slug'lanmış, avatarlar ham kullanıcı adıyla kaydedilir.
+func UserStoragePrefixes(username string) []string {
+	prefixes := []string{"users/" + slug.Make(username) + "/"}
+	if raw := "users/" + username + "/"; raw != prefixes[0] {
+		prefixes = append(prefixes, raw)
+	}
+	return prefixes
+}
+
+// ForeignImageURLs urls içinden kullanıcının ilanına ekleyemeyeceği ve silemeyeceği depolama
+// URL'lerini döner: kullanıcının prefix'leri dışında kalanlar ya da başka bir hesabın ilanında
+// kullanılanlar (aynı prefix'e eşlenen hesaplar için). Depolamaya ait olmayan URL'ler dahil edilmez.
+func ForeignImageURLs(db *gorm.DB, store storage.Storage, user *User, urls []string) (map[string]bool, error) {
+	foreign := map[string]bool{}
+	prefixes := UserStoragePrefixes(user.Username)
+
+	var hosted []string
+	for _, url := range urls {
+		key, ok := store.KeyFromURL(url)
+		if !ok {
+			continue
+		}
+		owned := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			foreign[url] = true
+			continue
+		}
+		hosted = append(hosted, url)
+	}
+	if len(hosted) == 0 {
+		return foreign, nil
+	}
+
+	var used []string
+	if err := db.Model(&PropertyImage{}).
+		Joins("JOIN properties ON properties.id = property_images.property_id AND properties.deleted_at IS NULL").
+		Where("property_images.url IN ? AND properties.user_id <> ?", hosted, user.ID).
+		Distinct().
+		Pluck("property_images.url", &used).Error; err != nil {
+		return nil, err
+	}
+	for _, url := range used {
+		foreign[url] = true
+	}
+	return foreign, nil
+}
+
+// referencedObjectKeys tüm kayıtların referans verdiği dosya anahtarları: silinmemiş ilanların
+// resimleri (tüm varyantlarıyla), bu ilanların revizyonlarındaki resimler ve ekleri, avatarlar,
+// filigran logoları ve onay bekleyen doğrudan yüklemeler. Sahibine göre süzülmez; bir prefix'te
+// başka bir hesabın (örn. kullanıcı adı değişmiş) dosyası olsa da kullanımda sayılır.
+func referencedObjectKeys(db *gorm.DB, store storage.Storage, now time.Time) (map[string]bool, error) {
+	keys := map[string]bool{}
+	addURL := func(url string) {
+		if key, ok := store.KeyFromURL(url); ok {
+			keys[key] = true
+		}
+	}
+
+	var users []User
+	if err := db.Select("id", "avatar", "watermark_logo_url").
+		Where("avatar <> '' OR watermark_logo_url <> ''").
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if user.Avatar != "" {
+			addURL(user.Avatar)
+		}
+		if user.WatermarkLogoURL != "" {
+			addURL(user.WatermarkLogoURL)
+		}
+	}
+
+	var images []PropertyImage
+	err := db.Joins("JOIN properties ON properties.id = property_images.property_id AND properties.deleted_at IS NULL").
+		FindInBatches(&images, 500, func(tx *gorm.DB, batch int) error {
+			for i := range images {
+				for _, url := range images[i].ObjectURLs() {
+					addURL(url)
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []PropertyRevision
+	err = db.Select("property_revisions.id", "property_revisions.snapshot").
+		Joins("JOIN properties ON properties.id = property_revisions.property_id AND properties.deleted_at IS NULL").
+		FindInBatches(&revisions, 100, func(tx *gorm.DB, batch int) error {
+			for _, revision := range revisions {
+				var snapshot struct {
+					Images []PropertyImage `json:"images"`
+				}
+				if err := json.Unmarshal(revision.Snapshot, &snapshot); err != nil {
+					return err
+				}
+				for i := range snapshot.Images {
+					for _, url := range snapshot.Images[i].ObjectURLs() {
+						addURL(url)
+					}
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var attachmentKeys []string
+	if err := db.Model(&PropertyAttachment{}).
+		Joins("JOIN properties ON properties.id = property_attachments.property_id AND properties.deleted_at IS NULL").
+		Where("property_attachments.object_key <> ''").
+		Pluck("property_attachments.object_key", &attachmentKeys).Error; err != nil {
+		return nil, err
+	}
+	for _, key := range attachmentKeys {
+		keys[key] = true
+	}
+
+	var pendingKeys []string
+	if err := db.Model(&PendingUpload{}).
+		Where("expires_at > ?", now).
+		Pluck("object_key", &pendingKeys).Error; err != nil {
+		return nil, err
+	}
+	for _, key := range pendingKeys {
+		keys[key] = true
+	}
+
+	return keys, nil
+}
+
+// FindOrphanedObjects kullanıcıların depolama prefix'lerini listeleyip veritabanıyla karşılaştırır.
+// username boş değilse sadece o kullanıcı taranır. Hiçbir şey silinmez. Birden fazla hesabın
+// eşlendiği prefix'lerdeki (örn. "Ali" ve "ali") dosyalar raporlanır ama silinebilir sayılmaz.
+func FindOrphanedObjects(db *gorm.DB, store storage.Storage, username string, now time.Time) (*OrphanReport, error) {
+	var users []User
+	if err := db.Select("id", "username").Order("id ASC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	owners := map[string]int{}
+	for _, user := range users {
+		for _, prefix := range UserStoragePrefixes(user.Username) {
+			owners[prefix]++
+		}
+	}
+
+	referenced, err := referencedObjectKeys(db, store, now)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &OrphanReport{Orphans: []OrphanObject{}}
+	scanned := map[string]bool{}
+	for _, user := range users {
+		if username != "" && user.Username != username {
+			continue
+		}
+
+		for _, prefix := range UserStoragePrefixes(user.Username) {
+			if scanned[prefix] {
+				continue
+			}
+			scanned[prefix] = true
+
+			objects, err := store.List(context.TODO(), prefix)
+			if err != nil {
+				return nil, err
+			}
+
+			shared := owners[prefix] > 1
+			report.ScannedObjects += len(objects)
+			for _, obj := range objects {
+				if referenced[obj.Key] {
+					continue
+				}
+
+				orphan := OrphanObject{
+					Key:          obj.Key,
+					URL:          store.URL(obj.Key),
+					Username:     user.Username,
+					Size:         obj.Size,
+					LastModified: obj.LastModified,
+					Deletable:    !shared && now.Sub(obj.LastModified) > OrphanGracePeriod,
+					Shared:       shared,
+				}
+				report.Orphans = append(report.Orphans, orphan)
+				report.OrphanCount++
+				report.OrphanBytes += obj.Size
+				if orphan.Deletable {
+					report.DeletableCount++
+				}
+			}
+		}
+		report.ScannedUsers++
+	}
+
+	return report, nil
+}
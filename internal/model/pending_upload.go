@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PendingUpload imzalı URL ile doğrudan depolamaya yüklenen, henüz onaylanmamış resim.
+// Onaylanan ya da reddedilen yüklemelerin kaydı silinir.
+type PendingUpload struct {
+	gorm.Model
+	UserID      uint      `json:"user_id" gorm:"not null;index"`
+	PropertyID  uint      `json:"property_id" gorm:"not null;index"`
+	ObjectKey   string    `json:"-" gorm:"uniqueIndex;not null"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"` // İmzalı URL'e bağlanan, bildirilen boyut
+	ExpiresAt   time.Time `json:"expires_at" gorm:"index"`
+}
+
+// CountPendingUploads ilan için süresi dolmamış, onay bekleyen yükleme sayısı
+func CountPendingUploads(db *gorm.DB, propertyID uint, now time.Time) (int64, error) {
+	var count int64
+	err := db.Model(&PendingUpload{}).
+		Where("property_id = ? AND expires_at > ?", propertyID, now).
+		Count(&count).Error
+	return count, err
+}
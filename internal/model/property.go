@@ -1,8 +1,14 @@
 package model
 
 import (
+	"encoding/json"
+	"estepage_backend/pkg/utils/storage"
+	"fmt"
+	"path"
 	"strings"
+	"time"
 
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -20,6 +26,11 @@ const (
 	PropertyTypeIndustrial PropertyType = "Industrial"
 )
 
+var PropertyTypes = []PropertyType{
+	PropertyTypeHouse, PropertyTypeApartment, PropertyTypeCondo, PropertyTypeVilla,
+	PropertyTypeTownhouse, PropertyTypeLand, PropertyTypeCommercial, PropertyTypeIndustrial,
+}
+
 // Property Status
 type PropertyStatus string
 
@@ -31,6 +42,11 @@ const (
 	PropertyStatusUnderContract PropertyStatus = "Under Contract"
 )
 
+var PropertyStatuses = []PropertyStatus{
+	PropertyStatusForSale, PropertyStatusForRent, PropertyStatusSold,
+	PropertyStatusRented, PropertyStatusUnderContract,
+}
+
 // Currency Types
 type Currency string
 
@@ -56,6 +72,12 @@ type Property struct {
 
 	UserID uint `json:"user_id" gorm:"uniqueIndex:idx_user_property_slug"`
 
+	// Yayın durumu (bkz. property_publication.go). Mevcut ilanlar yayında kabul edilir.
+	PublicationState PublicationState `json:"publication_state" gorm:"type:string;default:'published';index"`
+	PublishAt        *time.Time       `json:"publish_at"`   // Planlı yayın zamanı
+	PublishedAt      *time.Time       `json:"published_at"` // İlk yayına alınma zamanı
+	ArchivedAt       *time.Time       `json:"archived_at"`
+
 	// Location fields
 	CountryCode string `json:"country_code" gorm:"not null"`
 	CountryName string `json:"country_name" gorm:"not null"`
@@ -65,18 +87,26 @@ type Property struct {
 	District    string `json:"district"`
 	FullAddress string `json:"full_address" gorm:"type:text"`
 
+	// Koordinatlar; emlakçı konum işaretlemezse şehir/il merkezinden doldurulur
+	Latitude            *float64 `json:"latitude" gorm:"index:idx_property_coordinates"`
+	Longitude           *float64 `json:"longitude" gorm:"index:idx_property_coordinates"`
+	LocationApproximate bool     `json:"location_approximate" gorm:"default:false"` // Merkezden mi türetildi?
+
 	// Features fields
 
-	Bedrooms        int  `json:"bedrooms"gorm:"not null"`         // Yatak odası sayısı
-	Bathrooms       int  `json:"bathrooms"gorm:"not null"`        // Banyo sayısı
-	GarageSpaces    int  `json:"garage_spaces"gorm:"not null"`    // Garaj alanı
-	AreaSqFt        int  `json:"area_sq_ft"gorm:"not null"`       // Alan (sq ft)
-	YearBuilt       int  `json:"year_built"gorm:"not null"`       // İnşa yılı
-	SwimmingPool    bool `json:"swimming_pool"gorm:"not null"`    // Havuz var mı?
-	Garden          bool `json:"garden"`                          // Bahçe var mı?
-	AirConditioning bool `json:"air_conditioning"gorm:"not null"` // Klima var mı?
-	CentralHeating  bool `json:"central_heating"gorm:"not null"`  // Merkezi ısıtma var mı?
-	SecuritySystem  bool `json:"security_system"gorm:"not null"`  // Güvenlik sistemi var mı?
+	Bedrooms        int  `json:"bedrooms" gorm:"not null"`         // Yatak odası sayısı
+	Bathrooms       int  `json:"bathrooms" gorm:"not null"`        // Banyo sayısı
+	GarageSpaces    int  `json:"garage_spaces" gorm:"not null"`    // Garaj alanı
+	AreaSqFt        int  `json:"area_sq_ft" gorm:"not null"`       // Alan (sq ft)
+	YearBuilt       int  `json:"year_built" gorm:"not null"`       // İnşa yılı
+	SwimmingPool    bool `json:"swimming_pool" gorm:"not null"`    // Havuz var mı?
+	Garden          bool `json:"garden"`                           // Bahçe var mı?
+	AirConditioning bool `json:"air_conditioning" gorm:"not null"` // Klima var mı?
+	CentralHeating  bool `json:"central_heating" gorm:"not null"`  // Merkezi ısıtma var mı?
+	SecuritySystem  bool `json:"security_system" gorm:"not null"`  // Güvenlik sistemi var mı?
+
+	// Full-text arama dokümanı, hook'lar tarafından SQL ile doldurulur (bkz. property_search.go)
+	SearchVector string `json:"-" gorm:"type:tsvector;->:false;<-:false"`
 
 	// İlişkiler
 	User     User              `json:"-" gorm:"foreignKey:UserID"`
@@ -90,13 +120,132 @@ type PropertyImage struct {
 	URL          string `json:"url" gorm:"not null"`
 	CloudflareID string `json:"cloudflare_id" gorm:"unique"`
 	ThumbnailURL string `json:"thumbnail_url"`
-	Size         int64  `json:"size"`
+	Size         int64  `json:"size"` // Yüklenen orijinal dosyanın boyutu
 	IsCover      bool   `json:"is_cover" gorm:"default:false"`
 	Order        int    `json:"order" gorm:"default:0"`
+	Caption      string `json:"caption" gorm:"type:varchar(500)"`
+	AltText      string `json:"alt_text" gorm:"type:varchar(250)"`
+
+	// İşlenmiş resim bilgileri (bkz. pkg/utils/image). Variants []ImageVariant tutar.
+	Width    int            `json:"width"`
+	Height   int            `json:"height"`
+	Variants datatypes.JSON `json:"variants"`
+
+	// Resim işlenirken kullanılan User.WatermarkVersion
+	WatermarkVersion int `json:"watermark_version" gorm:"default:0"`
+
+	// Başarısız yeniden üretim denemeleri; WatermarkFailedVersion hedeflenen sürümdür,
+	// kullanıcı filigranı yeniden değiştirirse sayaç sıfırdan başlar
+	WatermarkAttempts      int        `json:"-" gorm:"default:0"`
+	WatermarkFailedVersion int        `json:"-" gorm:"default:0"`
+	WatermarkError         string     `json:"-"`
+	WatermarkRetryAt       *time.Time `json:"-"`
+
+	// Algısal hash (16 haneli hex), kopya fotoğraf tespiti için
+	PerceptualHash string `json:"perceptual_hash" gorm:"type:varchar(16);index"`
+	// Resim yüklenene kadar gösterilen yer tutucular (bkz. pkg/utils/image/placeholder.go)
+	BlurHash      string `json:"blurhash" gorm:"type:varchar(64)"`
+	LQIP          string `json:"lqip" gorm:"column:lqip;type:text"`
+	DominantColor string `json:"dominant_color" gorm:"type:varchar(7)"`
+
+	// Upload cevabında aynı ilandaki benzer resimler (kaydedilmez)
+	DuplicateImageIDs []uint `json:"duplicate_image_ids,omitempty" gorm:"-"`
 
 	Property Property `json:"-" gorm:"foreignKey:PropertyID"`
 }
 
+// ImageVariant bir resmin belirli boyut ve formattaki kopyası
+type ImageVariant struct {
+	Name   string `json:"name"`   // thumbnail, card, full
+	Format string `json:"format"` // webp, jpeg
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Size   int64  `json:"size"`
+}
+
+// OriginalKey filigransız kopyanın anahtarı. Kopya storage.PrivateStorage'da, varyantların
+// anahtarlarıyla aynı dizin yapısında tutulur; public ise resmin herkese açık depolaması.
+// İşlenmemiş (eski) ya da harici resimler için false döner.
+func (img *PropertyImage) OriginalKey(public storage.Storage) (string, bool) {
+	if len(img.Variants) == 0 || img.CloudflareID == "" {
+		return "", false
+	}
+	fullKey, ok := public.KeyFromURL(img.URL)
+	if !ok {
+		return "", false
+	}
+	return path.Dir(fullKey) + "/" + img.CloudflareID + OriginalSuffix, true
+}
+
+// OriginalSuffix filigransız kopyaların dosya adı eki. Eski kopyalar herkese açık bucket'ta
+// varyantların yanında aynı adla durur (bkz. cloudflare.MoveLegacyOriginals)
+const OriginalSuffix = "-original.jpg"
+
+// legacyOriginalURL taşınmamış eski filigransız kopyanın herkese açık adresi
+func (img *PropertyImage) legacyOriginalURL() string {
+	if len(img.Variants) == 0 || img.CloudflareID == "" {
+		return ""
+	}
+	return path.Dir(img.URL) + "/" + img.CloudflareID + OriginalSuffix
+}
+
+// ObjectURLs resme ait herkese açık depolamadaki tüm dosyaların URL'leri (silme işlemleri için).
+// Henüz taşınmamış olabilecek eski filigransız kopya da dahildir; özel kopya için bkz. OriginalKey.
+func (img *PropertyImage) ObjectURLs() []string {
+	urls := []string{img.URL}
+	seen := map[string]bool{img.URL: true}
+	if original := img.legacyOriginalURL(); original != "" {
+		urls = append(urls, original)
+		seen[original] = true
+	}
+
+	var variants []ImageVariant
+	if len(img.Variants) > 0 {
+		json.Unmarshal(img.Variants, &variants)
+	}
+	for _, v := range variants {
+		if v.URL != "" && !seen[v.URL] {
+			seen[v.URL] = true
+			urls = append(urls, v.URL)
+		}
+	}
+	return urls
+}
+
+// HostedVariant resmin URL'ine karşılık gelen işlenmiş varyant; eski ya da harici resimlerde false döner
+func (img *PropertyImage) HostedVariant() (ImageVariant, bool) {
+	var variants []ImageVariant
+	if len(img.Variants) > 0 {
+		json.Unmarshal(img.Variants, &variants)
+	}
+	for _, v := range variants {
+		if v.URL == img.URL {
+			return v, true
+		}
+	}
+	return ImageVariant{}, false
+}
+
+// CoverImage ilanın kapak resmi; kapak seçilmemişse ilk resim, resim yoksa nil.
+// Images yüklenmiş olmalıdır.
+func (p *Property) CoverImage() *PropertyImage {
+	for i := range p.Images {
+		if p.Images[i].IsCover {
+			return &p.Images[i]
+		}
+	}
+	if len(p.Images) > 0 {
+		return &p.Images[0]
+	}
+	return nil
+}
+
+// PropertyPublicURL ilanın ziyaretçilere açık sayfasının adresi
+func PropertyPublicURL(username, slug string) string {
+	return "https://estapage.com/p/" + username + "/" + slug
+}
+
 // BeforeCreate property oluşturulurken slug'ı otomatik oluşturur
 func (p *Property) BeforeCreate(tx *gorm.DB) error {
 	if p.Slug == "" {
@@ -110,18 +259,43 @@ func (p *Property) BeforeCreate(tx *gorm.DB) error {
 		}, slug)
 
 		// Slug'ın benzersiz olduğundan emin ol
-		var count int64
-		tx.Model(&Property{}).Where("user_id = ? AND slug = ?", p.UserID, slug).Count(&count)
-		if count > 0 {
-			// Eğer aynı slug varsa sonuna timestamp ekle
-			slug = slug + "-" + p.CreatedAt.Format("20060102")
+		unique, err := uniquePropertySlug(tx, p.UserID, p.ID, slug)
+		if err != nil {
+			return err
 		}
-
-		p.Slug = slug
+		p.Slug = unique
 	}
 	return nil
 }
 
+// uniquePropertySlug slug kullanıcının başka bir ilanında (silinmişler dahil, unique index
+// onları da kapsar) kullanılıyorsa sonuna -2, -3... ekleyerek boşta olan ilk slug'ı döner
+func uniquePropertySlug(tx *gorm.DB, userID, propertyID uint, slug string) (string, error) {
+	if slug == "" {
+		slug = "property"
+	}
+	if userID == 0 {
+		return slug, nil
+	}
+
+	var taken []string
+	if err := tx.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&Property{}).
+		Where("user_id = ? AND id <> ? AND (slug = ? OR slug LIKE ?)", userID, propertyID, slug, slug+"-%").
+		Pluck("slug", &taken).Error; err != nil {
+		return "", err
+	}
+
+	used := make(map[string]bool, len(taken))
+	for _, s := range taken {
+		used[s] = true
+	}
+	candidate := slug
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d", slug, i)
+	}
+	return candidate, nil
+}
+
 func (p *Property) BeforeSave(tx *gorm.DB) error {
 	// Türkçe karakter haritası
 	replacer := strings.NewReplacer(
@@ -160,6 +334,11 @@ func (p *Property) BeforeSave(tx *gorm.DB) error {
 	// Baştaki ve sondaki tireleri temizle
 	slug = strings.Trim(slug, "-")
 
-	p.Slug = slug
+	// Aynı başlıklı ilanlar için slug'ı benzersiz yap
+	unique, err := uniquePropertySlug(tx, p.UserID, p.ID, slug)
+	if err != nil {
+		return err
+	}
+	p.Slug = unique
 	return nil
 }
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+type AttachmentType string
+
+const (
+	AttachmentFloorPlan   AttachmentType = "floor_plan"   // PDF ya da resim
+	AttachmentDocument    AttachmentType = "document"     // PDF broşür, tapu vb.
+	AttachmentVideo       AttachmentType = "video"        // Link
+	AttachmentVirtualTour AttachmentType = "virtual_tour" // Link
+)
+
+var AttachmentTypes = map[AttachmentType]bool{
+	AttachmentFloorPlan:   true,
+	AttachmentDocument:    true,
+	AttachmentVideo:       true,
+	AttachmentVirtualTour: true,
+}
+
+// IsLink ek dosya değil harici bir link mi
+func (t AttachmentType) IsLink() bool {
+	return t == AttachmentVideo || t == AttachmentVirtualTour
+}
+
+// PropertyAttachment ilana eklenen kat planı, doküman ya da video/sanal tur linki.
+// Dosyalar ObjectKey ile saklanır; URL sadece herkese açık dosyalar ve linkler için doludur.
+type PropertyAttachment struct {
+	gorm.Model
+	PropertyID  uint           `json:"property_id" gorm:"not null;index"`
+	Type        AttachmentType `json:"type" gorm:"type:varchar(20);not null"`
+	Title       string         `json:"title" gorm:"type:varchar(200)"`
+	URL         string         `json:"url"`
+	ObjectKey   string         `json:"-"`
+	FileName    string         `json:"file_name"`
+	ContentType string         `json:"content_type"`
+	Size        int64          `json:"size"`
+	IsPublic    bool           `json:"is_public" gorm:"default:true"`
+	Order       int            `json:"order" gorm:"default:0"`
+
+	// Sahibine dönen, süreli indirme linki (kaydedilmez)
+	DownloadURL string `json:"download_url,omitempty" gorm:"-"`
+}
+
+// PublicAttachments yayındaki ilan sayfasında gösterilen ekler
+func PublicAttachments(db *gorm.DB, propertyID uint) ([]PropertyAttachment, error) {
+	attachments := []PropertyAttachment{}
+	err := db.Where("property_id = ? AND is_public = ?", propertyID, true).
+		Order(`"order" ASC, id ASC`).
+		Find(&attachments).Error
+	return attachments, err
+}
+
+// LegacyPrivateAttachmentFiles gizli dosya eklerinin anahtarları ve içerik türleri
+// (bkz. cloudflare.MoveLegacyPrivateAttachments)
+func LegacyPrivateAttachmentFiles(db *gorm.DB) ([]PropertyAttachment, error) {
+	attachments := []PropertyAttachment{}
+	err := db.Select("id", "object_key", "content_type").
+		Where("is_public = ? AND object_key <> ''", false).
+		Find(&attachments).Error
+	return attachments, err
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type ImportStatus string
+
+const (
+	ImportStatusPending   ImportStatus = "pending"
+	ImportStatusRunning   ImportStatus = "running"
+	ImportStatusCompleted ImportStatus = "completed"
+	ImportStatusFailed    ImportStatus = "failed"
+)
+
+// PropertyImport CSV/XML dosyasından toplu ilan aktarımı (arka planda çalışır)
+type PropertyImport struct {
+	gorm.Model
+	UserID        uint           `json:"user_id" gorm:"index;not null"`
+	FileName      string         `json:"file_name"`
+	Format        string         `json:"format"` // csv, xml
+	Status        ImportStatus   `json:"status" gorm:"type:string;default:'pending';index"`
+	TotalRows     int            `json:"total_rows"`
+	ProcessedRows int            `json:"processed_rows"`
+	ImportedRows  int            `json:"imported_rows"`
+	FailedRows    int            `json:"failed_rows"`
+	Errors        datatypes.JSON `json:"errors"` // []ImportRowError
+	Message       string         `json:"message"`
+	StartedAt     *time.Time     `json:"started_at"`
+	FinishedAt    *time.Time     `json:"finished_at"`
+}
+
+// ImportRowError satır bazlı hata raporu. Warning true ise satır yine de aktarılmıştır.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+	Warning bool   `json:"warning,omitempty"`
+}
+
+// FailInterruptedImports sunucu yeniden başladığında yarım kalan aktarımları başarısız işaretler
+func FailInterruptedImports(db *gorm.DB) error {
+	now := time.Now()
+	return db.Model(&PropertyImport{}).
+		Where("status IN ?", []ImportStatus{ImportStatusPending, ImportStatusRunning}).
+		Updates(map[string]interface{}{
+			"status":      ImportStatusFailed,
+			"message":     "Import was interrupted by a server restart",
+			"finished_at": now,
+		}).Error
+}
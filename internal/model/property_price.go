@@ -0,0 +1,129 @@
+package model
+
+import (
+	"estepage_backend/pkg/utils/currency"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PropertyPriceHistory ilanın fiyat değişikliklerini tutar. İlk kayıt ilan oluşturulurken
+// (PreviousPrice 0) ya da ilk fiyat değişikliğinde eklenir.
+type PropertyPriceHistory struct {
+	gorm.Model
+	PropertyID       uint       `json:"property_id" gorm:"index;not null"`
+	Price            float64    `json:"price" gorm:"not null"`
+	Currency         Currency   `json:"currency" gorm:"not null"`
+	PreviousPrice    float64    `json:"previous_price"`
+	PreviousCurrency Currency   `json:"previous_currency"`
+	ChangePercent    float64    `json:"change_percent"` // Önceki fiyata göre yüzde değişim (USD bazında)
+	NotifiedAt       *time.Time `json:"-"`              // Abonelere fiyat düşüşü bildirimi gönderildi mi?
+}
+
+// PricePoint public fiyat geçmişindeki tek bir nokta
+type PricePoint struct {
+	Price     float64   `json:"price"`
+	Currency  Currency  `json:"currency"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// PriceSummary ziyaretçiye gösterilen fiyat özeti ("%5 düştü" gibi)
+type PriceSummary struct {
+	OriginalPrice    float64      `json:"original_price"`
+	OriginalCurrency Currency     `json:"original_currency"`
+	ChangePercent    float64      `json:"change_percent"` // İlk fiyata göre toplam değişim
+	Reduced          bool         `json:"reduced"`
+	LastChangedAt    *time.Time   `json:"last_changed_at"`
+	History          []PricePoint `json:"history"`
+}
+
+// PriceChangePercent iki fiyat arasındaki yüzde değişimi döner.
+// Para birimi değişmişse karşılaştırma USD üzerinden yapılır.
+func PriceChangePercent(previousPrice float64, previousCurrency Currency, price float64, priceCurrency Currency) float64 {
+	if previousPrice <= 0 {
+		return 0
+	}
+
+	oldValue, newValue := previousPrice, price
+	if previousCurrency != priceCurrency {
+		var err error
+		if oldValue, err = currency.ToUSD(previousPrice, string(previousCurrency)); err != nil {
+			return 0
+		}
+		if newValue, err = currency.ToUSD(price, string(priceCurrency)); err != nil {
+			return 0
+		}
+	}
+
+	return math.Round((newValue-oldValue)/oldValue*10000) / 100
+}
+
+// RecordPriceChange fiyat ya da para birimi değiştiyse geçmişe yeni bir kayıt ekler
+func RecordPriceChange(tx *gorm.DB, propertyID uint, previousPrice float64, previousCurrency Currency, price float64, priceCurrency Currency) (*PropertyPriceHistory, error) {
+	if previousPrice == price && previousCurrency == priceCurrency {
+		return nil, nil
+	}
+
+	entry := PropertyPriceHistory{
+		PropertyID:       propertyID,
+		Price:            price,
+		Currency:         priceCurrency,
+		PreviousPrice:    previousPrice,
+		PreviousCurrency: previousCurrency,
+		ChangePercent:    PriceChangePercent(previousPrice, previousCurrency, price, priceCurrency),
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetPriceSummary ilanın fiyat geçmişinden public özet üretir. Geçmiş yoksa nil döner.
+func GetPriceSummary(db *gorm.DB, property *Property) (*PriceSummary, error) {
+	var history []PropertyPriceHistory
+	if err := db.Where("property_id = ?", property.ID).
+		Order("created_at ASC, id ASC").
+		Find(&history).Error; err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, nil
+	}
+
+	first := history[0]
+	summary := &PriceSummary{
+		OriginalPrice:    first.Price,
+		OriginalCurrency: first.Currency,
+		History:          make([]PricePoint, 0, len(history)+1),
+	}
+
+	// Eski ilanlarda ilk kayıt bir değişikliktir; önceki fiyat başlangıç noktasıdır
+	if first.PreviousPrice > 0 {
+		summary.OriginalPrice = first.PreviousPrice
+		summary.OriginalCurrency = first.PreviousCurrency
+		summary.History = append(summary.History, PricePoint{
+			Price:     first.PreviousPrice,
+			Currency:  first.PreviousCurrency,
+			ChangedAt: property.CreatedAt,
+		})
+	}
+
+	for _, entry := range history {
+		summary.History = append(summary.History, PricePoint{
+			Price:     entry.Price,
+			Currency:  entry.Currency,
+			ChangedAt: entry.CreatedAt,
+		})
+	}
+
+	if len(summary.History) > 1 {
+		last := summary.History[len(summary.History)-1].ChangedAt
+		summary.LastChangedAt = &last
+	}
+
+	summary.ChangePercent = PriceChangePercent(summary.OriginalPrice, summary.OriginalCurrency, property.Price, property.Currency)
+	summary.Reduced = summary.ChangePercent < 0
+
+	return summary, nil
+}
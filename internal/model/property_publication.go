@@ -0,0 +1,85 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PublicationState ilanın yayın durumu; pazar durumundan (PropertyStatus) bağımsızdır
+type PublicationState string
+
+const (
+	PublicationDraft     PublicationState = "draft"
+	PublicationScheduled PublicationState = "scheduled"
+	PublicationPublished PublicationState = "published"
+	PublicationArchived  PublicationState = "archived"
+)
+
+// Her durumdan geçilebilecek durumlar
+var publicationTransitions = map[PublicationState][]PublicationState{
+	PublicationDraft:     {PublicationScheduled, PublicationPublished, PublicationArchived},
+	PublicationScheduled: {PublicationDraft, PublicationPublished, PublicationArchived},
+	PublicationPublished: {PublicationArchived},
+	PublicationArchived:  {PublicationDraft},
+}
+
+// IsValid durumun tanımlı olup olmadığını döner
+func (s PublicationState) IsValid() bool {
+	_, ok := publicationTransitions[s]
+	return ok
+}
+
+// AllowedTransitions mevcut durumdan geçilebilecek durumları döner
+func (s PublicationState) AllowedTransitions() []PublicationState {
+	return publicationTransitions[s]
+}
+
+// CanTransitionTo geçişin izinli olup olmadığını kontrol eder
+func (s PublicationState) CanTransitionTo(next PublicationState) bool {
+	for _, allowed := range publicationTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// PublishedProperties sadece yayındaki ilanları döndüren scope
+func PublishedProperties(db *gorm.DB) *gorm.DB {
+	return db.Where("properties.publication_state = ?", PublicationPublished)
+}
+
+// ApplyPublicationState ilanın durum alanlarını yeni duruma göre günceller.
+// Geçişin geçerliliği çağıran tarafından kontrol edilmelidir.
+func (p *Property) ApplyPublicationState(next PublicationState, publishAt *time.Time, now time.Time) {
+	p.PublicationState = next
+	switch next {
+	case PublicationScheduled:
+		p.PublishAt = publishAt
+	case PublicationPublished:
+		p.PublishAt = nil
+		if p.PublishedAt == nil {
+			p.PublishedAt = &now
+		}
+		p.ArchivedAt = nil
+	case PublicationArchived:
+		p.PublishAt = nil
+		p.ArchivedAt = &now
+	case PublicationDraft:
+		p.PublishAt = nil
+		p.ArchivedAt = nil
+	}
+}
+
+// PublishDueProperties yayın zamanı gelmiş planlı ilanları yayına alır
+func PublishDueProperties(db *gorm.DB, now time.Time) (int64, error) {
+	// Sadece durum alanları değiştiği için slug/arama hook'larına gerek yok
+	result := db.Session(&gorm.Session{SkipHooks: true}).Model(&Property{}).
+		Where("publication_state = ? AND publish_at <= ?", PublicationScheduled, now).
+		Updates(map[string]interface{}{
+			"publication_state": PublicationPublished,
+			"published_at":      gorm.Expr("COALESCE(published_at, ?)", now),
+		})
+	return result.RowsAffected, result.Error
+}
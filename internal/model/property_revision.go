@@ -0,0 +1,289 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"estepage_backend/pkg/utils/storage"
+	"reflect"
+	"sort"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PropertyRevision ilanın bir güncelleme sonrasındaki tam görüntüsü (alanlar, resimler, özellikler)
+type PropertyRevision struct {
+	gorm.Model
+	PropertyID uint           `json:"property_id" gorm:"uniqueIndex:idx_property_revision;not null"`
+	Revision   int            `json:"revision" gorm:"uniqueIndex:idx_property_revision;not null"`
+	UserID     uint           `json:"user_id" gorm:"index"` // Değişikliği yapan kullanıcı
+	Note       string         `json:"note"`                 // Örn: "created", "updated", "restored from #3"
+	Snapshot   datatypes.JSON `json:"-"`
+}
+
+// FieldChange iki revizyon arasındaki tek bir alan farkı
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// Diff'e dahil edilmeyen alanlar (sistem alanları ve yayın durumu)
+var revisionIgnoredFields = map[string]bool{
+	"ID":                true,
+	"CreatedAt":         true,
+	"UpdatedAt":         true,
+	"DeletedAt":         true,
+	"user_id":           true,
+	"slug":              true,
+	"publication_state": true,
+	"publish_at":        true,
+	"published_at":      true,
+	"archived_at":       true,
+}
+
+// SnapshotProperty ilanı resim ve özellikleriyle birlikte JSON olarak döner
+func SnapshotProperty(tx *gorm.DB, propertyID uint) (datatypes.JSON, error) {
+	var property Property
+	if err := tx.
+		Preload("Images", func(db *gorm.DB) *gorm.DB {
+			return db.Order("property_images.order ASC")
+		}).
+		Preload("Features", func(db *gorm.DB) *gorm.DB {
+			return db.Order("property_features.order ASC")
+		}).
+		First(&property, propertyID).Error; err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(property)
+	if err != nil {
+		return nil, err
+	}
+	return datatypes.JSON(data), nil
+}
+
+// lockPropertyForRevision ilan satırını kilitler; aynı ilana eşzamanlı kaydedilen
+// revizyonlar aynı numarayı almaz
+func lockPropertyForRevision(tx *gorm.DB, propertyID uint) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&Property{}, propertyID).Error
+}
+
+// RecordPropertyRevision ilanın mevcut halini yeni bir revizyon olarak kaydeder
+func RecordPropertyRevision(tx *gorm.DB, propertyID, userID uint, note string) (*PropertyRevision, error) {
+	if err := lockPropertyForRevision(tx, propertyID); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := SnapshotProperty(tx, propertyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var last int
+	if err := tx.Model(&PropertyRevision{}).
+		Where("property_id = ?", propertyID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&last).Error; err != nil {
+		return nil, err
+	}
+
+	revision := PropertyRevision{
+		PropertyID: propertyID,
+		Revision:   last + 1,
+		UserID:     userID,
+		Note:       note,
+		Snapshot:   snapshot,
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// EnsureBaselineRevision revizyonu hiç olmayan (eski) ilanlar için güncellemeden önceki hali kaydeder
+func EnsureBaselineRevision(tx *gorm.DB, propertyID, userID uint) error {
+	if err := lockPropertyForRevision(tx, propertyID); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := tx.Model(&PropertyRevision{}).Where("property_id = ?", propertyID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err := RecordPropertyRevision(tx, propertyID, userID, "baseline")
+	return err
+}
+
+// normalizeSnapshot snapshot'ı karşılaştırılabilir alanlara indirger.
+// Resimler URL/kapak/sıra/açıklama, özellikler başlık/değer olarak karşılaştırılır.
+func normalizeSnapshot(snapshot datatypes.JSON) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if len(snapshot) == 0 {
+		return fields, nil
+	}
+	if err := json.Unmarshal(snapshot, &fields); err != nil {
+		return nil, err
+	}
+
+	for key := range revisionIgnoredFields {
+		delete(fields, key)
+	}
+
+	if images, ok := fields["images"].([]interface{}); ok {
+		normalized := make([]interface{}, 0, len(images))
+		for _, img := range images {
+			if m, ok := img.(map[string]interface{}); ok {
+				normalized = append(normalized, map[string]interface{}{
+					"url":      m["url"],
+					"is_cover": m["is_cover"],
+					"order":    m["order"],
+					"caption":  m["caption"],
+					"alt_text": m["alt_text"],
+				})
+			}
+		}
+		fields["images"] = normalized
+	}
+
+	if features, ok := fields["features"].([]interface{}); ok {
+		normalized := make([]interface{}, 0, len(features))
+		for _, f := range features {
+			if m, ok := f.(map[string]interface{}); ok {
+				normalized = append(normalized, map[string]interface{}{
+					"title":  m["title"],
+					"values": m["values"],
+					"order":  m["order"],
+				})
+			}
+		}
+		fields["features"] = normalized
+	}
+
+	return fields, nil
+}
+
+// DiffPropertySnapshots iki snapshot arasındaki alan bazlı farkları döner.
+// prev boşsa tüm alanlar yeni kabul edilir.
+func DiffPropertySnapshots(prev, next datatypes.JSON) ([]FieldChange, error) {
+	oldFields, err := normalizeSnapshot(prev)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := normalizeSnapshot(next)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]bool{}
+	for k := range oldFields {
+		keys[k] = true
+	}
+	for k := range newFields {
+		keys[k] = true
+	}
+
+	names := make([]string, 0, len(keys))
+	for k := range keys {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	changes := []FieldChange{}
+	for _, name := range names {
+		if !reflect.DeepEqual(oldFields[name], newFields[name]) {
+			changes = append(changes, FieldChange{Field: name, Old: oldFields[name], New: newFields[name]})
+		}
+	}
+	return changes, nil
+}
+
+// RestorePropertyRevision ilanın içeriğini revizyondaki haline döndürür.
+// Yayın durumu ve sahiplik değişmez; resim ve özellikler snapshot'takilerle değiştirilir.
+// Dosyası depolamadan silinmiş resimler geri yüklenmez, URL'leri döner.
+func RestorePropertyRevision(tx *gorm.DB, store storage.Storage, property *Property, revision *PropertyRevision) ([]string, error) {
+	var snapshot Property
+	if err := json.Unmarshal(revision.Snapshot, &snapshot); err != nil {
+		return nil, err
+	}
+
+	images, missing, err := availableSnapshotImages(store, snapshot.Images)
+	if err != nil {
+		return nil, err
+	}
+	features := snapshot.Features
+
+	restored := snapshot
+	restored.Model = property.Model
+	restored.UserID = property.UserID
+	restored.PublicationState = property.PublicationState
+	restored.PublishAt = property.PublishAt
+	restored.PublishedAt = property.PublishedAt
+	restored.ArchivedAt = property.ArchivedAt
+	restored.Images, restored.Features = nil, nil
+
+	if err := tx.Omit(clause.Associations).Save(&restored).Error; err != nil {
+		return nil, err
+	}
+
+	// Resim satırları kalıcı silinir; geçmiş revizyonlarda tutulduğu için
+	// cloudflare_id unique indeksi soft-delete edilmiş satırlarla çakışmamalı
+	if err := tx.Unscoped().Where("property_id = ?", property.ID).Delete(&PropertyImage{}).Error; err != nil {
+		return nil, err
+	}
+	for _, img := range images {
+		img.Model = gorm.Model{}
+		img.PropertyID = property.ID
+		if err := tx.Omit(clause.Associations).Create(&img).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Where("property_id = ?", property.ID).Delete(&PropertyFeature{}).Error; err != nil {
+		return nil, err
+	}
+	for _, f := range features {
+		f.Model = gorm.Model{}
+		f.PropertyID = property.ID
+		if err := tx.Omit(clause.Associations).Create(&f).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	*property = restored
+	return missing, nil
+}
+
+// availableSnapshotImages snapshot resimlerinden dosyası hâlâ depolamada olanları döner.
+// Atlanan resimlerden sonra sıra numaraları kapatılır; kapak silindiyse ilk resim kapak olur.
+func availableSnapshotImages(store storage.Storage, images []PropertyImage) ([]PropertyImage, []string, error) {
+	if store == nil {
+		return images, nil, nil
+	}
+
+	available := make([]PropertyImage, 0, len(images))
+	missing := []string{}
+	hasCover := false
+	for _, img := range images {
+		if key, ok := store.KeyFromURL(img.URL); ok {
+			if _, err := store.Stat(context.TODO(), key); errors.Is(err, storage.ErrNotFound) {
+				missing = append(missing, img.URL)
+				continue
+			} else if err != nil {
+				return nil, nil, err
+			}
+		}
+		img.Order = len(available)
+		hasCover = hasCover || img.IsCover
+		available = append(available, img)
+	}
+	if !hasCover && len(available) > 0 {
+		available[0].IsCover = true
+	}
+	return available, missing, nil
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// İlan arama dokümanı: başlık (A), açıklama (B), adres ve özel özellikler (C).
+// Türkçe ve İngilizce konfigürasyonlarıyla ayrı ayrı indekslenir ki
+// "deniz manzaralı villa" ve "sea view villa" aramaları da eşleşsin.
+const propertySearchVectorSQL = `
+UPDATE properties p SET search_vector =
+	setweight(to_tsvector('turkish', coalesce(p.title, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(p.title, '')), 'A') ||
+	setweight(to_tsvector('turkish', coalesce(p.description, '')), 'B') ||
+	setweight(to_tsvector('english', coalesce(p.description, '')), 'B') ||
+	setweight(to_tsvector('simple', coalesce(p.full_address, '')), 'C') ||
+	setweight(to_tsvector('turkish', coalesce(pf.doc, '')), 'C') ||
+	setweight(to_tsvector('english', coalesce(pf.doc, '')), 'C')
+FROM (
+	SELECT p2.id AS property_id, string_agg(f.title || ' ' || coalesce(f."values"::text, ''), ' ') AS doc
+	FROM properties p2
+	LEFT JOIN property_features f ON f.property_id = p2.id AND f.deleted_at IS NULL
+	WHERE %s
+	GROUP BY p2.id
+) pf
+WHERE p.id = pf.property_id`
+
+// PropertySearchQuerySQL kullanıcı sorgusunu iki dilde tsquery'ye çevirir, iki kez aynı parametreyi alır
+const PropertySearchQuerySQL = "(websearch_to_tsquery('turkish', ?) || websearch_to_tsquery('english', ?))"
+
+// RefreshPropertySearchVector tek bir ilanın arama dokümanını yeniden oluşturur
+func RefreshPropertySearchVector(tx *gorm.DB, propertyID uint) error {
+	return tx.Session(&gorm.Session{NewDB: true}).
+		Exec(searchVectorUpdateSQL("p2.id = ?"), propertyID).Error
+}
+
+// EnsurePropertySearchIndex GIN indeksini oluşturur ve arama dokümanı olmayan ilanları doldurur
+func EnsurePropertySearchIndex(db *gorm.DB) error {
+	if err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_properties_search_vector
+		ON properties USING GIN (search_vector)`).Error; err != nil {
+		return err
+	}
+	return db.Exec(searchVectorUpdateSQL("p2.search_vector IS NULL")).Error
+}
+
+func searchVectorUpdateSQL(condition string) string {
+	return fmt.Sprintf(propertySearchVectorSQL, condition)
+}
+
+// AfterSave ilan kaydedildiğinde arama dokümanını günceller
+func (p *Property) AfterSave(tx *gorm.DB) error {
+	return RefreshPropertySearchVector(tx, p.ID)
+}
+
+// AfterSave özellik eklenip güncellendiğinde bağlı ilanın arama dokümanını günceller
+func (f *PropertyFeature) AfterSave(tx *gorm.DB) error {
+	if f.PropertyID == 0 {
+		return nil
+	}
+	return RefreshPropertySearchVector(tx, f.PropertyID)
+}
+
+// AfterDelete silinen özellik arama dokümanından da çıkarılır
+func (f *PropertyFeature) AfterDelete(tx *gorm.DB) error {
+	if f.PropertyID == 0 {
+		return nil
+	}
+	return RefreshPropertySearchVector(tx, f.PropertyID)
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"estepage_backend/pkg/subscription"
 	"time"
 
 	"gorm.io/gorm"
@@ -28,3 +29,13 @@ type UserSubscription struct {
 	CancellationDate time.Time `json:"cancellation_date"`
 	User             User      `gorm:"foreignKey:UserID"`
 }
+
+// GetUserPlanType kullanıcının aktif aboneliğine göre plan tipini döner (abonelik yoksa Free)
+func GetUserPlanType(db *gorm.DB, userID uint) subscription.PlanType {
+	var activeSubscription UserSubscription
+	if err := db.Where("user_id = ? AND status = ?", userID, "active").
+		First(&activeSubscription).Error; err != nil {
+		return subscription.FreePlan
+	}
+	return subscription.DeterminePlanType(activeSubscription.StripePlanID)
+}
@@ -41,8 +41,18 @@ type User struct {
 	// Social Media
 	SocialLinks datatypes.JSON `json:"social_links"`
 
+	// İlan fotoğraflarına basılan filigran. WatermarkVersion her ayar değişikliğinde artar;
+	// farklı sürümle işlenmiş resimler arka planda yeniden üretilir.
+	WatermarkEnabled  bool    `json:"watermark_enabled" gorm:"default:false"`
+	WatermarkLogoURL  string  `json:"watermark_logo_url"`
+	WatermarkPosition string  `json:"watermark_position" gorm:"default:'bottom-right'"`
+	WatermarkOpacity  float64 `json:"watermark_opacity" gorm:"default:0.5"`
+	WatermarkScale    float64 `json:"watermark_scale" gorm:"default:0.2"`
+	WatermarkVersion  int     `json:"watermark_version" gorm:"default:0"`
+
 	// Sistem bilgileri
 	IsVerified     bool  `json:"is_verified" gorm:"default:false"`
+	IsAdmin        bool  `json:"-" gorm:"default:false"`
 	SubscriptionID *uint `json:"subscription_id"`
 
 	// İlişkiler
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -10,10 +12,16 @@ type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
 	JWT      JWTConfig
+	Storage  StorageConfig
+	Spam     SpamConfig
 }
 
 type ServerConfig struct {
 	Port string
+	// Proxy arkasında gerçek istemci IP'sinin okunduğu başlık (örn. CF-Connecting-IP).
+	// Başlık sadece TrustedProxies'ten gelen isteklerde dikkate alınır.
+	ProxyHeader    string
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -28,23 +36,71 @@ type JWTConfig struct {
 	Secret string
 }
 
+// StorageConfig dosya depolama ayarları. Driver: r2 (varsayılan), s3 veya local
+type StorageConfig struct {
+	Driver        string
+	PublicURL     string // Boşsa driver'a göre varsayılan kullanılır
+	Bucket        string
+	Endpoint      string // s3 driver için; r2'de hesap ID'sinden oluşturulur
+	Region        string
+	AccountID     string
+	AccessKey     string
+	SecretKey     string
+	LocalPath     string
+	SigningSecret string // Local driver'ın imzalı URL'leri için; JWT anahtarından farklı olmalı
+
+	// Herkese açık olmayan dosyalar (filigransız orijinaller) için ayrı bucket / dizin
+	PrivateBucket    string
+	PrivateLocalPath string
+}
+
+// SpamConfig herkese açık formların (lead, bülten) spam korumasının ayarları
+type SpamConfig struct {
+	TokenSecret   string // Form token'larının HMAC anahtarı; zorunlu, JWT anahtarından farklı olmalı
+	PowDifficulty int    // Proof-of-work için gereken sıfır bit sayısı; 0 ise kapalı
+	// Bu tarihe (YYYY-MM-DD) kadar token'sız eski istemciler tek başına işaretlenmez; boşsa geçiş süresi yok
+	MissingTokenGraceUntil string
+}
+
 func Load() *Config {
 	godotenv.Load() // .env dosyasını yükle
 
 	return &Config{
 		Server: ServerConfig{
-			Port: getEnv("PORT", "3000"),
+			Port:           getEnv("PORT", "3000"),
+			ProxyHeader:    getEnv("PROXY_HEADER", ""),
+			TrustedProxies: getEnvList("TRUSTED_PROXIES"),
 		},
 		Database: DatabaseConfig{
-		Host:      getEnv("DB_HOST", "localhost"),
-        Port:     getEnv("DB_PORT", "5432"),
-        User:     getEnv("DB_USER", "admin"),
-        Password: getEnv("DB_PASSWORD", "admin"),
-        DBName:   getEnv("DB_NAME", "estepage"),
+			Host:     getEnv("DB_HOST", "localhost"),
+			Port:     getEnv("DB_PORT", "5432"),
+			User:     getEnv("DB_USER", "admin"),
+			Password: getEnv("DB_PASSWORD", "admin"),
+			DBName:   getEnv("DB_NAME", "estepage"),
 		},
 		JWT: JWTConfig{
 			Secret: getEnv("JWT_SECRET", "your-secret-key"),
 		},
+		Storage: StorageConfig{
+			Driver:        getEnv("STORAGE_DRIVER", "r2"),
+			PublicURL:     getEnv("STORAGE_PUBLIC_URL", ""),
+			Bucket:        getEnv("R2_BUCKET_NAME", ""),
+			Endpoint:      getEnv("STORAGE_ENDPOINT", ""),
+			Region:        getEnv("STORAGE_REGION", "auto"),
+			AccountID:     getEnv("R2_ACCOUNT_ID", ""),
+			AccessKey:     getEnv("R2_ACCESS_KEY", ""),
+			SecretKey:     getEnv("R2_SECRET_KEY", ""),
+			LocalPath:     getEnv("STORAGE_LOCAL_PATH", "./uploads"),
+			SigningSecret: getEnv("STORAGE_SIGNING_SECRET", ""),
+
+			PrivateBucket:    getEnv("STORAGE_PRIVATE_BUCKET", ""),
+			PrivateLocalPath: getEnv("STORAGE_PRIVATE_LOCAL_PATH", "./uploads-private"),
+		},
+		Spam: SpamConfig{
+			TokenSecret:            getEnv("FORM_TOKEN_SECRET", ""),
+			PowDifficulty:          getEnvInt("FORM_POW_DIFFICULTY", 0),
+			MissingTokenGraceUntil: getEnv("FORM_TOKEN_GRACE_UNTIL", ""),
+		},
 	}
 }
 
@@ -54,3 +110,21 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList virgülle ayrılmış değerleri döner
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,109 @@
+// pkg/cron/lead_reminders.go
+package cron
+
+import (
+	"errors"
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/email"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// leadReminderBatchSize bir çalışmada gönderilecek en fazla hatırlatma sayısı
+const leadReminderBatchSize = 100
+
+var leadReminderMu sync.Mutex
+
+func InitLeadReminderCron() {
+	c := cron.New()
+
+	// 5 dakikada bir zamanı gelen takip hatırlatmalarını emlakçılara gönder
+	_, err := c.AddFunc("*/5 * * * *", func() {
+		sendLeadReminders()
+	})
+
+	if err != nil {
+		log.Printf("Could not initialize lead reminder cron: %v", err)
+		return
+	}
+
+	c.Start()
+	log.Printf("Lead reminder cron initialized successfully")
+}
+
+func sendLeadReminders() {
+	// E-posta servisi yoksa hatırlatmalar bildirilmemiş kalır, sonraki çalışmada denenir
+	if email.GlobalEmailService == nil {
+		return
+	}
+
+	// Önceki çalışma bitmediyse atla
+	if !leadReminderMu.TryLock() {
+		return
+	}
+	defer leadReminderMu.Unlock()
+
+	db := database.GetDB()
+
+	reminders, err := model.ClaimDueLeadReminders(db, time.Now(), leadReminderBatchSize)
+	if err != nil {
+		log.Printf("Error fetching due lead reminders: %v", err)
+		return
+	}
+	if len(reminders) == 0 {
+		return
+	}
+
+	users := map[uint]*model.User{}
+	sent := 0
+	for _, reminder := range reminders {
+		user, ok := users[reminder.UserID]
+		if !ok {
+			user = &model.User{}
+			if err := db.First(user, reminder.UserID).Error; err != nil {
+				log.Printf("Error fetching user %d: %v", reminder.UserID, err)
+				// Silinmiş kullanıcının hatırlatması tekrar denenmez
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					releaseLeadReminder(db, reminder.ID)
+				}
+				continue
+			}
+			users[reminder.UserID] = user
+		}
+
+		lead := reminder.Lead
+		propertyTitle := ""
+		if lead.PropertyTitle != nil {
+			propertyTitle = *lead.PropertyTitle
+		}
+
+		if err := email.GlobalEmailService.SendLeadReminderEmail(
+			user.Email,
+			user.CompanyName,
+			lead.Name,
+			lead.Email,
+			lead.Phone,
+			propertyTitle,
+			reminder.Note,
+			reminder.DueAt,
+		); err != nil {
+			log.Printf("Error sending lead reminder %d to %s: %v", reminder.ID, user.Email, err)
+			releaseLeadReminder(db, reminder.ID)
+			continue
+		}
+		sent++
+	}
+
+	log.Printf("Sent %d of %d due lead reminders", sent, len(reminders))
+}
+
+func releaseLeadReminder(db *gorm.DB, id uint) {
+	if err := model.ReleaseLeadReminder(db, id); err != nil {
+		log.Printf("Error releasing lead reminder %d: %v", id, err)
+	}
+}
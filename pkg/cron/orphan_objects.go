@@ -0,0 +1,59 @@
+// pkg/cron/orphan_objects.go
+package cron
+
+import (
+	"context"
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/utils/storage"
+	"log"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func InitOrphanObjectCron() {
+	c := cron.New()
+
+	// Her gece 03:30'da hiçbir kayda bağlı olmayan dosyaları temizle
+	_, err := c.AddFunc("30 3 * * *", func() {
+		deleteOrphanedObjects()
+	})
+
+	if err != nil {
+		log.Printf("Could not initialize orphan object cron: %v", err)
+		return
+	}
+
+	c.Start()
+	log.Printf("Orphan object cron initialized successfully")
+}
+
+func deleteOrphanedObjects() {
+	if storage.GlobalStorage == nil {
+		return
+	}
+
+	report, err := model.FindOrphanedObjects(database.GetDB(), storage.GlobalStorage, "", time.Now())
+	if err != nil {
+		log.Printf("Error finding orphaned objects: %v", err)
+		return
+	}
+
+	deleted := 0
+	var deletedBytes int64
+	for _, orphan := range report.Orphans {
+		if !orphan.Deletable {
+			continue
+		}
+		if err := storage.GlobalStorage.Delete(context.TODO(), orphan.Key); err != nil {
+			log.Printf("Error deleting orphaned object %s: %v", orphan.Key, err)
+			continue
+		}
+		deleted++
+		deletedBytes += orphan.Size
+	}
+
+	log.Printf("Orphan cleanup: scanned %d objects of %d users, deleted %d orphans (%d bytes)",
+		report.ScannedObjects, report.ScannedUsers, deleted, deletedBytes)
+}
@@ -0,0 +1,59 @@
+// pkg/cron/pending_uploads.go
+package cron
+
+import (
+	"context"
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/utils/storage"
+	"log"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func InitPendingUploadCron() {
+	c := cron.New()
+
+	// 30 dakikada bir süresi dolan, onaylanmamış yüklemeleri temizle
+	_, err := c.AddFunc("*/30 * * * *", func() {
+		cleanupExpiredUploads()
+	})
+
+	if err != nil {
+		log.Printf("Could not initialize pending upload cron: %v", err)
+		return
+	}
+
+	c.Start()
+	log.Printf("Pending upload cron initialized successfully")
+}
+
+func cleanupExpiredUploads() {
+	db := database.GetDB()
+
+	var expired []model.PendingUpload
+	if err := db.Where("expires_at < ?", time.Now()).
+		Find(&expired).Error; err != nil {
+		log.Printf("Error fetching expired uploads: %v", err)
+		return
+	}
+	if len(expired) == 0 || storage.GlobalStorage == nil {
+		return
+	}
+
+	cleaned := 0
+	for _, pending := range expired {
+		if err := storage.GlobalStorage.Delete(context.TODO(), pending.ObjectKey); err != nil {
+			log.Printf("Error deleting expired upload %s: %v", pending.ObjectKey, err)
+			continue
+		}
+		if err := db.Unscoped().Delete(&pending).Error; err != nil {
+			log.Printf("Error deleting pending upload %d: %v", pending.ID, err)
+			continue
+		}
+		cleaned++
+	}
+
+	log.Printf("Cleaned up %d expired uploads", cleaned)
+}
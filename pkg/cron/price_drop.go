@@ -0,0 +1,116 @@
+// pkg/cron/price_drop.go
+package cron
+
+import (
+	"errors"
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/email"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+func InitPriceDropCron() {
+	c := cron.New()
+
+	// 15 dakikada bir bildirilmemiş fiyat düşüşlerini abonelere gönder
+	_, err := c.AddFunc("*/15 * * * *", func() {
+		sendPriceDropNotifications()
+	})
+
+	if err != nil {
+		log.Printf("Could not initialize price drop cron: %v", err)
+		return
+	}
+
+	c.Start()
+	log.Printf("Price drop cron initialized successfully")
+}
+
+func formatPrice(amount float64, currency model.Currency) string {
+	return fmt.Sprintf("%.0f %s", amount, currency)
+}
+
+func sendPriceDropNotifications() {
+	// E-posta servisi yoksa düşüşler bildirilmemiş kalır, servis açılınca gönderilir
+	if email.GlobalEmailService == nil {
+		return
+	}
+
+	db := database.GetDB()
+
+	var drops []model.PropertyPriceHistory
+	if err := db.Where("notified_at IS NULL AND change_percent < 0").
+		Order("created_at ASC").
+		Find(&drops).Error; err != nil {
+		log.Printf("Error fetching price drops: %v", err)
+		return
+	}
+	if len(drops) == 0 {
+		return
+	}
+
+	// Aynı ilanda birden fazla düşüş varsa sadece sonuncusu gönderilir
+	latest := map[uint]model.PropertyPriceHistory{}
+	dropIDs := map[uint][]uint{}
+	for _, drop := range drops {
+		latest[drop.PropertyID] = drop
+		dropIDs[drop.PropertyID] = append(dropIDs[drop.PropertyID], drop.ID)
+	}
+
+	// Sadece işlenen ilanların düşüşleri bildirildi olarak işaretlenir;
+	// hata alınanlar bir sonraki çalışmada tekrar denenir
+	var notifiedIDs []uint
+	for propertyID, drop := range latest {
+		var property model.Property
+		if err := db.Scopes(model.PublishedProperties).Preload("User").First(&property, propertyID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// Yayında olmayan ilanlar için bildirim gönderilmez
+				notifiedIDs = append(notifiedIDs, dropIDs[propertyID]...)
+			} else {
+				log.Printf("Error fetching property %d for price drop: %v", propertyID, err)
+			}
+			continue
+		}
+
+		var subscribers []model.NewsletterSubscriber
+		if err := db.Where("user_id = ?", property.UserID).Find(&subscribers).Error; err != nil {
+			log.Printf("Error fetching subscribers for user %d: %v", property.UserID, err)
+			continue
+		}
+
+		propertyURL := model.PropertyPublicURL(property.User.Username, property.Slug)
+		for _, subscriber := range subscribers {
+			if err := email.GlobalEmailService.SendPriceDropEmail(
+				subscriber.Email,
+				subscriber.Name,
+				property.User.CompanyName,
+				property.Title,
+				propertyURL,
+				formatPrice(drop.PreviousPrice, drop.PreviousCurrency),
+				formatPrice(drop.Price, drop.Currency),
+				math.Abs(drop.ChangePercent),
+			); err != nil {
+				log.Printf("Error sending price drop email to %s: %v", subscriber.Email, err)
+			}
+		}
+
+		notifiedIDs = append(notifiedIDs, dropIDs[propertyID]...)
+		log.Printf("Sent price drop notification for property %d to %d subscribers", propertyID, len(subscribers))
+	}
+	if len(notifiedIDs) == 0 {
+		return
+	}
+
+	now := time.Now()
+	if err := db.Model(&model.PropertyPriceHistory{}).
+		Where("id IN ?", notifiedIDs).
+		Update("notified_at", now).Error; err != nil {
+		log.Printf("Error marking price drops as notified: %v", err)
+	}
+}
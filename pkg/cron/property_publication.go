@@ -0,0 +1,40 @@
+// pkg/cron/property_publication.go
+package cron
+
+import (
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"log"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func InitPropertyPublicationCron() {
+	c := cron.New()
+
+	// Her dakika planlı ilanları kontrol et
+	_, err := c.AddFunc("* * * * *", func() {
+		publishScheduledProperties()
+	})
+
+	if err != nil {
+		log.Printf("Could not initialize property publication cron: %v", err)
+		return
+	}
+
+	c.Start()
+	log.Printf("Property publication cron initialized successfully")
+}
+
+func publishScheduledProperties() {
+	published, err := model.PublishDueProperties(database.GetDB(), time.Now())
+	if err != nil {
+		log.Printf("Error publishing scheduled properties: %v", err)
+		return
+	}
+
+	if published > 0 {
+		log.Printf("Published %d scheduled properties", published)
+	}
+}
@@ -0,0 +1,159 @@
+// pkg/cron/watermark.go
+package cron
+
+import (
+	"encoding/json"
+	"estepage_backend/internal/model"
+	"estepage_backend/pkg/database"
+	"estepage_backend/pkg/utils/cloudflare"
+	imageutil "estepage_backend/pkg/utils/image"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+const (
+	// watermarkBatchSize bir çalışmada yeniden üretilecek en fazla resim sayısı
+	watermarkBatchSize = 50
+	// watermarkMaxAttempts aynı filigran sürümü için en fazla deneme; sonrasında resim atlanır
+	watermarkMaxAttempts = 5
+	// watermarkRetryDelay her başarısız denemeden sonra beklenen süre (deneme sayısıyla çarpılır)
+	watermarkRetryDelay = 10 * time.Minute
+)
+
+var watermarkMu sync.Mutex
+
+func InitWatermarkCron() {
+	c := cron.New()
+
+	// 2 dakikada bir filigranı değişen kullanıcıların fotoğraflarını yeniden üret
+	_, err := c.AddFunc("*/2 * * * *", func() {
+		rerenderWatermarks()
+	})
+
+	if err != nil {
+		log.Printf("Could not initialize watermark cron: %v", err)
+		return
+	}
+
+	c.Start()
+	log.Printf("Watermark cron initialized successfully")
+}
+
+func rerenderWatermarks() {
+	// Önceki çalışma bitmediyse atla
+	if !watermarkMu.TryLock() {
+		return
+	}
+	defer watermarkMu.Unlock()
+
+	db := database.GetDB()
+	now := time.Now()
+
+	// Başarısız resimler bekleme süresi dolana kadar ve deneme sınırından sonra atlanır;
+	// böylece işlenemeyen resimler kuyruğun başını tıkamaz
+	var images []model.PropertyImage
+	if err := db.Select("property_images.*").
+		Joins("JOIN properties ON properties.id = property_images.property_id AND properties.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = properties.user_id").
+		Where("property_images.watermark_version <> users.watermark_version").
+		Where("property_images.variants IS NOT NULL").
+		Where(`(property_images.watermark_failed_version <> users.watermark_version OR
+			(property_images.watermark_attempts < ? AND
+			(property_images.watermark_retry_at IS NULL OR property_images.watermark_retry_at <= ?)))`,
+			watermarkMaxAttempts, now).
+		Order("property_images.id ASC").
+		Limit(watermarkBatchSize).
+		Preload("Property").
+		Find(&images).Error; err != nil {
+		log.Printf("Error fetching images to re-render: %v", err)
+		return
+	}
+	if len(images) == 0 {
+		return
+	}
+
+	users := map[uint]*model.User{}
+	watermarks := map[uint]*imageutil.Watermark{}
+	watermarkErrs := map[uint]error{}
+
+	rendered := 0
+	for i := range images {
+		img := &images[i]
+		userID := img.Property.UserID
+
+		user, ok := users[userID]
+		if !ok {
+			user = &model.User{}
+			if err := db.First(user, userID).Error; err != nil {
+				log.Printf("Error fetching user %d: %v", userID, err)
+				continue
+			}
+			users[userID] = user
+
+			watermark, err := cloudflare.UserWatermark(user)
+			if err != nil {
+				log.Printf("Error loading watermark for user %d: %v", userID, err)
+				watermarkErrs[userID] = err
+			} else {
+				watermarks[userID] = watermark
+			}
+		}
+
+		if err, failed := watermarkErrs[userID]; failed {
+			recordWatermarkFailure(db, img, user.WatermarkVersion, err, now)
+			continue
+		}
+
+		variants, err := cloudflare.RerenderImage(img, watermarks[userID])
+		if err != nil {
+			log.Printf("Error re-rendering image %d: %v", img.ID, err)
+			recordWatermarkFailure(db, img, user.WatermarkVersion, err, now)
+			continue
+		}
+
+		variantsJSON, err := json.Marshal(variants)
+		if err != nil {
+			recordWatermarkFailure(db, img, user.WatermarkVersion, err, now)
+			continue
+		}
+
+		if err := db.Model(img).Updates(map[string]interface{}{
+			"variants":                 variantsJSON,
+			"watermark_version":        user.WatermarkVersion,
+			"watermark_attempts":       0,
+			"watermark_failed_version": 0,
+			"watermark_error":          "",
+			"watermark_retry_at":       nil,
+		}).Error; err != nil {
+			log.Printf("Error updating image %d: %v", img.ID, err)
+			continue
+		}
+		rendered++
+	}
+
+	log.Printf("Re-rendered watermark on %d of %d images", rendered, len(images))
+}
+
+// recordWatermarkFailure başarısız denemeyi kaydeder ve resmi bir süre kuyruktan çıkarır
+func recordWatermarkFailure(db *gorm.DB, img *model.PropertyImage, version int, cause error, now time.Time) {
+	attempts := 1
+	if img.WatermarkFailedVersion == version {
+		attempts = img.WatermarkAttempts + 1
+	}
+	if attempts >= watermarkMaxAttempts {
+		log.Printf("Giving up re-rendering image %d after %d attempts", img.ID, attempts)
+	}
+
+	if err := db.Model(img).Updates(map[string]interface{}{
+		"watermark_attempts":       attempts,
+		"watermark_failed_version": version,
+		"watermark_error":          cause.Error(),
+		"watermark_retry_at":       now.Add(time.Duration(attempts) * watermarkRetryDelay),
+	}).Error; err != nil {
+		log.Printf("Error recording watermark failure for image %d: %v", img.ID, err)
+	}
+}
@@ -24,6 +24,7 @@ type EmailData struct {
 	To      string `json:"to"`
 	Subject string `json:"subject"`
 	Html    string `json:"html"`
+	ReplyTo string `json:"reply_to,omitempty"`
 }
 
 // Template data structures
@@ -89,6 +90,36 @@ type PropertyStatsData struct {
 	StartDate        time.Time
 }
 
+type PriceDropData struct {
+	SubscriberName   string
+	CompanyName      string
+	PropertyTitle    string
+	PropertyURL      string
+	OldPrice         string
+	NewPrice         string
+	ReductionPercent float64
+}
+
+type LeadReminderData struct {
+	CompanyName   string
+	LeadName      string
+	LeadEmail     string
+	LeadPhone     string
+	PropertyTitle string
+	Note          string
+	DueAt         time.Time
+}
+
+type LeadAutoReplyData struct {
+	LeadName      string
+	AgentName     string
+	Lines         []string // Şablon metninin satırları
+	PropertyTitle string
+	PropertyImage string
+	PropertyURL   string
+	WhatsAppLink  string
+}
+
 func NewEmailService(apiKey string) (*EmailService, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("resend API key is required")
@@ -107,6 +138,11 @@ func NewEmailService(apiKey string) (*EmailService, error) {
 }
 
 func (s *EmailService) sendTemplateEmail(to, subject, templateName string, data interface{}) error {
+	return s.sendTemplateEmailWithReplyTo(to, "", subject, templateName, data)
+}
+
+// sendTemplateEmailWithReplyTo yanıtların replyTo adresine gitmesi gereken e-postalar için
+func (s *EmailService) sendTemplateEmailWithReplyTo(to, replyTo, subject, templateName string, data interface{}) error {
 	var body bytes.Buffer
 	if err := s.templates.ExecuteTemplate(&body, templateName, data); err != nil {
 		return fmt.Errorf("template execution error: %v", err)
@@ -117,6 +153,7 @@ func (s *EmailService) sendTemplateEmail(to, subject, templateName string, data
 		To:      to,
 		Subject: subject,
 		Html:    body.String(),
+		ReplyTo: replyTo,
 	}
 
 	jsonData, err := json.Marshal(emailData)
@@ -277,3 +314,44 @@ func (s *EmailService) SendPropertyStats(
 	subject := fmt.Sprintf("Your %s Property Statistics 📊", strings.Title(period))
 	return s.sendTemplateEmail(email, subject, "property_stats.html", data)
 }
+
+func (s *EmailService) SendPriceDropEmail(
+	email, subscriberName, companyName, propertyTitle, propertyURL, oldPrice, newPrice string,
+	reductionPercent float64,
+) error {
+	data := PriceDropData{
+		SubscriberName:   subscriberName,
+		CompanyName:      companyName,
+		PropertyTitle:    propertyTitle,
+		PropertyURL:      propertyURL,
+		OldPrice:         oldPrice,
+		NewPrice:         newPrice,
+		ReductionPercent: reductionPercent,
+	}
+	subject := fmt.Sprintf("Price Reduced: %s 🏷️", propertyTitle)
+	return s.sendTemplateEmail(email, subject, "price_drop.html", data)
+}
+
+func (s *EmailService) SendLeadReminderEmail(
+	agentEmail, companyName, leadName, leadEmail, leadPhone, propertyTitle, note string,
+	dueAt time.Time,
+) error {
+	data := LeadReminderData{
+		CompanyName:   companyName,
+		LeadName:      leadName,
+		LeadEmail:     leadEmail,
+		LeadPhone:     leadPhone,
+		PropertyTitle: propertyTitle,
+		Note:          note,
+		DueAt:         dueAt,
+	}
+	subject := fmt.Sprintf("Follow-up Reminder: %s ⏰", leadName)
+	return s.sendTemplateEmail(agentEmail, subject, "lead_reminder.html", data)
+}
+
+// SendLeadAutoReplyEmail lead gönderen ziyaretçiye emlakçının otomatik yanıtını gönderir.
+// Ziyaretçinin yanıtları emlakçıya (replyTo) gider.
+func (s *EmailService) SendLeadAutoReplyEmail(leadEmail, replyTo, subject, body string, data LeadAutoReplyData) error {
+	data.Lines = strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+	return s.sendTemplateEmailWithReplyTo(leadEmail, replyTo, subject, "lead_auto_reply.html", data)
+}
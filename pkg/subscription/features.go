@@ -20,15 +20,19 @@ const (
 )
 
 type PlanLimits struct {
-	MaxListings      int
-	MaxImagesPerList int
-	AllowedFeatures  map[Feature]bool
+	MaxListings           int
+	MaxImagesPerList      int
+	MaxAttachmentsPerList int   // Kat planı, doküman ve video/sanal tur linkleri toplamı
+	MaxAttachmentSize     int64 // Tek bir dosya eki için byte cinsinden
+	AllowedFeatures       map[Feature]bool
 }
 
 var PlanFeatures = map[PlanType]PlanLimits{
 	FreePlan: {
-		MaxListings:      1,
-		MaxImagesPerList: 5,
+		MaxListings:           1,
+		MaxImagesPerList:      5,
+		MaxAttachmentsPerList: 2,
+		MaxAttachmentSize:     5 * 1024 * 1024,
 		AllowedFeatures: map[Feature]bool{
 			LeadForm:        false,
 			NewsletterForm:  false,
@@ -38,8 +42,10 @@ var PlanFeatures = map[PlanType]PlanLimits{
 		},
 	},
 	ProPlan: {
-		MaxListings:      25,
-		MaxImagesPerList: 16,
+		MaxListings:           25,
+		MaxImagesPerList:      16,
+		MaxAttachmentsPerList: 10,
+		MaxAttachmentSize:     20 * 1024 * 1024,
 		AllowedFeatures: map[Feature]bool{
 			LeadForm:        true,
 			NewsletterForm:  true,
@@ -49,8 +55,10 @@ var PlanFeatures = map[PlanType]PlanLimits{
 		},
 	},
 	ElitePlan: {
-		MaxListings:      100,
-		MaxImagesPerList: 16,
+		MaxListings:           100,
+		MaxImagesPerList:      16,
+		MaxAttachmentsPerList: 20,
+		MaxAttachmentSize:     50 * 1024 * 1024,
 		AllowedFeatures: map[Feature]bool{
 			LeadForm:        true,
 			NewsletterForm:  true,
@@ -95,6 +103,20 @@ func GetPlanMaxImages(planType PlanType) int {
 	return PlanFeatures[planType].MaxImagesPerList
 }
 
+// GetPlanMaxAttachmentSize direkt plan tipinden maksimum ek dosya boyutunu döndürür
+func GetPlanMaxAttachmentSize(planType PlanType) int64 {
+	return PlanFeatures[planType].MaxAttachmentSize
+}
+
+// MaxAttachmentSizeAnyPlan planlar arasındaki en büyük ek dosya boyutu (sunucu gövde limiti için)
+func MaxAttachmentSizeAnyPlan() int64 {
+	var size int64
+	for _, limits := range PlanFeatures {
+		size = max(size, limits.MaxAttachmentSize)
+	}
+	return size
+}
+
 // GetPlanNameFromStripeID stripe plan ID'sinden insan tarafından okunabilir plan adını döndürür
 func GetPlanNameFromStripeID(stripePlanID string) string {
 	planType := DeterminePlanType(stripePlanID)
@@ -0,0 +1,45 @@
+// pkg/utils/antispam/disposable.go
+package antispam
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed disposable_domains.txt
+var disposableDomainList string
+
+var disposableDomains = parseDomainList(disposableDomainList)
+
+func parseDomainList(list string) map[string]bool {
+	domains := map[string]bool{}
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[line] = true
+	}
+	return domains
+}
+
+// IsDisposableEmail e-posta tek kullanımlık bir servise (veya alt alan adına) aitse true döner
+func IsDisposableEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(email[at+1:])), ".")
+	for domain != "" {
+		if disposableDomains[domain] {
+			return true
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+// pkg/utils/antispam/score.go
+package antispam
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Bu puan ve üzeri gönderimler spam olarak işaretlenir
+const SpamThreshold = 50
+
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+`)
+
+// Submission herkese açık bir formdan gelen gönderim
+type Submission struct {
+	Name     string
+	Email    string
+	Message  string
+	Honeypot string // Gizli alan; gerçek kullanıcılar boş bırakır
+	Token    string
+	PowNonce string
+
+	TargetLimited bool // Hedef (ilan/emlakçı) başına gönderim limiti aşıldı
+}
+
+// Result spam değerlendirmesinin sonucu
+type Result struct {
+	Score   int      `json:"score"`
+	Reasons []string `json:"reasons"`
+	Flagged bool     `json:"flagged"`
+}
+
+func (r *Result) add(points int, reason string) {
+	r.Score += points
+	r.Reasons = append(r.Reasons, reason)
+}
+
+// Evaluate gönderimi puanlar. Token'sız, bozuk/tekrar kullanılan token'lı, çok hızlı,
+// honeypot'u dolu ve hedef limitini aşan gönderimler işaretlenir. Token'sız eski istemciler
+// sadece yapılandırılan geçiş süresi boyunca tek başına işaretlenmez.
+func Evaluate(s Submission) Result {
+	result := Result{Reasons: []string{}}
+
+	if strings.TrimSpace(s.Honeypot) != "" {
+		result.add(100, "honeypot")
+	}
+
+	switch status := VerifyToken(s.Token); status {
+	case TokenValid:
+	case TokenMissing:
+		if MissingTokenAllowed(time.Now()) {
+			result.add(30, "missing_token")
+		} else {
+			result.add(SpamThreshold, "missing_token")
+		}
+	case TokenTooFast:
+		result.add(60, "too_fast")
+	default:
+		result.add(60, string(status)+"_token")
+	}
+
+	if s.TargetLimited {
+		result.add(SpamThreshold, "target_rate_limit")
+	}
+
+	if PowRequired() && !VerifyProofOfWork(s.Token, s.PowNonce) {
+		result.add(60, "invalid_proof_of_work")
+	}
+
+	if linkPattern.MatchString(s.Name) {
+		result.add(40, "link_in_name")
+	}
+
+	switch links := len(linkPattern.FindAllString(s.Message, -1)); {
+	case links >= 3:
+		result.add(30, "many_links")
+	case links > 0:
+		result.add(10, "links")
+	}
+
+	result.Flagged = result.Score >= SpamThreshold
+	return result
+}
@@ -0,0 +1,212 @@
+// pkg/utils/antispam/token.go
+package antispam
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"estepage_backend/pkg/config"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Form bundan daha hızlı gönderilirse bot kabul edilir
+	MinFillTime = 3 * time.Second
+	// Token'ın geçerlilik süresi; sayfa uzun süre açık kalırsa yeni token alınmalı
+	MaxTokenAge = 2 * time.Hour
+	// Proof-of-work zorluğunun üst sınırı (tarayıcıda makul sürede çözülebilmeli)
+	maxPowDifficulty = 24
+)
+
+var (
+	tokenSecret            []byte
+	powDifficulty          int
+	missingTokenGraceUntil time.Time
+
+	// Kullanılmış token'lar süreleri dolana kadar tutulur (tekrar gönderim engeli).
+	// Liste bellekte tutulur: yeniden başlatmada sıfırlanır ve birden fazla instance
+	// arasında paylaşılmaz. Bu durumda bir token her instance'ta bir kez daha kullanılabilir;
+	// IP rate limiti ve proof-of-work bu açığı sınırlar.
+	usedTokens   = map[string]time.Time{}
+	usedTokensMu sync.Mutex
+)
+
+// TokenStatus form token'ının doğrulama sonucu
+type TokenStatus string
+
+const (
+	TokenValid   TokenStatus = "valid"
+	TokenMissing TokenStatus = "missing"
+	TokenInvalid TokenStatus = "invalid"
+	TokenExpired TokenStatus = "expired"
+	TokenReused  TokenStatus = "reused"
+	TokenTooFast TokenStatus = "too_fast"
+)
+
+// Challenge formu açan istemciye verilen token ve (açıksa) proof-of-work görevi.
+// İstemci sha256(token + ":" + pow_nonce) değeri en az Difficulty sıfır bitle
+// başlayan bir pow_nonce bulup formla birlikte göndermelidir.
+type Challenge struct {
+	Token          string `json:"token"`
+	Difficulty     int    `json:"pow_difficulty"`
+	MinFillSeconds int    `json:"min_fill_seconds"`
+	ExpiresAt      int64  `json:"expires_at"`
+}
+
+// Init token anahtarını, proof-of-work zorluğunu ve token'sız istemciler için geçiş süresini ayarlar
+func Init(cfg config.SpamConfig) error {
+	if cfg.TokenSecret == "" {
+		return errors.New("FORM_TOKEN_SECRET is not set")
+	}
+
+	missingTokenGraceUntil = time.Time{}
+	if cfg.MissingTokenGraceUntil != "" {
+		until, err := time.Parse("2006-01-02", cfg.MissingTokenGraceUntil)
+		if err != nil {
+			return fmt.Errorf("invalid FORM_TOKEN_GRACE_UNTIL: %v", err)
+		}
+		missingTokenGraceUntil = until.AddDate(0, 0, 1) // Belirtilen gün dahil
+	}
+
+	tokenSecret = []byte(cfg.TokenSecret)
+	powDifficulty = cfg.PowDifficulty
+	if powDifficulty < 0 {
+		powDifficulty = 0
+	}
+	if powDifficulty > maxPowDifficulty {
+		powDifficulty = maxPowDifficulty
+	}
+	return nil
+}
+
+// MissingTokenAllowed token'sız gönderimler için geçiş süresi devam ediyor mu
+func MissingTokenAllowed(now time.Time) bool {
+	return now.Before(missingTokenGraceUntil)
+}
+
+// PowRequired proof-of-work zorunlu mu
+func PowRequired() bool {
+	return powDifficulty > 0
+}
+
+// NewChallenge yeni bir imzalı form token'ı üretir
+func NewChallenge() (Challenge, error) {
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return Challenge{}, err
+	}
+
+	issuedAt := time.Now()
+	payload := fmt.Sprintf("%d.%s", issuedAt.Unix(), hex.EncodeToString(nonce))
+	token := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + sign(payload)
+
+	return Challenge{
+		Token:          token,
+		Difficulty:     powDifficulty,
+		MinFillSeconds: int(MinFillTime / time.Second),
+		ExpiresAt:      issuedAt.Add(MaxTokenAge).Unix(),
+	}, nil
+}
+
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, tokenSecret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyToken token'ın imzasını, yaşını ve daha önce kullanılıp kullanılmadığını kontrol eder.
+// Geçerli token kullanılmış olarak işaretlenir.
+func VerifyToken(token string) TokenStatus {
+	if token == "" {
+		return TokenMissing
+	}
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return TokenInvalid
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return TokenInvalid
+	}
+	payload := string(raw)
+	if !hmac.Equal([]byte(signature), []byte(sign(payload))) {
+		return TokenInvalid
+	}
+
+	issuedAtStr, nonce, ok := strings.Cut(payload, ".")
+	if !ok {
+		return TokenInvalid
+	}
+	issuedAtUnix, err := strconv.ParseInt(issuedAtStr, 10, 64)
+	if err != nil {
+		return TokenInvalid
+	}
+
+	issuedAt := time.Unix(issuedAtUnix, 0)
+	age := time.Since(issuedAt)
+	if age > MaxTokenAge {
+		return TokenExpired
+	}
+	// Token saniye hassasiyetinde; yuvarlama payı bırakılır
+	if age < MinFillTime-time.Second {
+		return TokenTooFast
+	}
+
+	if !markTokenUsed(nonce, issuedAt.Add(MaxTokenAge)) {
+		return TokenReused
+	}
+	return TokenValid
+}
+
+func markTokenUsed(nonce string, expiresAt time.Time) bool {
+	usedTokensMu.Lock()
+	defer usedTokensMu.Unlock()
+
+	now := time.Now()
+	if _, used := usedTokens[nonce]; used {
+		return false
+	}
+
+	// Süresi dolanlar ara ara temizlenir
+	if len(usedTokens)%256 == 0 {
+		for key, expiry := range usedTokens {
+			if now.After(expiry) {
+				delete(usedTokens, key)
+			}
+		}
+	}
+
+	usedTokens[nonce] = expiresAt
+	return true
+}
+
+// VerifyProofOfWork istemcinin bulduğu nonce'un token için yeterli zorlukta olup olmadığını kontrol eder
+func VerifyProofOfWork(token, nonce string) bool {
+	if !PowRequired() {
+		return true
+	}
+	if token == "" || nonce == "" || len(nonce) > 64 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(token + ":" + nonce))
+	zeros := 0
+	for _, b := range sum {
+		if b == 0 {
+			zeros += 8
+			continue
+		}
+		zeros += bits.LeadingZeros8(b)
+		break
+	}
+	return zeros >= powDifficulty
+}
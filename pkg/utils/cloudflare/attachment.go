@@ -0,0 +1,138 @@
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"estepage_backend/internal/model"
+	imageutil "estepage_backend/pkg/utils/image"
+	"estepage_backend/pkg/utils/storage"
+
+	"github.com/google/uuid"
+	"github.com/gosimple/slug"
+)
+
+// floorPlanVariant resim olarak yüklenen kat planlarının tek çıktısı
+var floorPlanVariant = []imageutil.VariantSpec{{Name: "floor_plan", Width: 2400}}
+
+func attachmentKey(username, propertySlug, ext string) string {
+	uniqueID := fmt.Sprintf("%d-%s", time.Now().UnixNano(), uuid.New().String())
+	return path.Join("users", slug.Make(username), "properties", slug.Make(propertySlug), "attachments", uniqueID+ext)
+}
+
+// attachmentStore herkese açık ekler için public bucket'ı, gizli ekler (tapu vb.) için
+// özel depolamayı döner. Gizli eklerin herkese açık, imzasız bir adresi olmaz.
+func attachmentStore(public bool) (storage.Storage, error) {
+	if public {
+		return getStorage()
+	}
+	return getPrivateStorage()
+}
+
+// UploadAttachment ek dosyayı görünürlüğüne göre ilgili depolamaya yükler ve anahtarını döner.
+// Resimler metadata'sı temizlenip JPEG'e çevrilir; PDF'ler olduğu gibi saklanır. Dönen
+// contentType/size saklanan dosyaya aittir.
+func UploadAttachment(data []byte, contentType, username, propertySlug string, public bool) (key, storedType string, size int64, err error) {
+	store, err := attachmentStore(public)
+	if err != nil {
+		return "", "", 0, err
+	}
+
+	ext := ".pdf"
+	if contentType != "application/pdf" {
+		processed, err := imageutil.Process(data, floorPlanVariant, imageutil.Options{})
+		if err != nil {
+			return "", "", 0, err
+		}
+		variant := processed.Find("floor_plan", imageutil.FormatJPEG)
+		if variant == nil {
+			return "", "", 0, fmt.Errorf("could not process floor plan")
+		}
+		data, contentType, ext = variant.Data, variant.ContentType, variant.Ext
+	}
+
+	key = attachmentKey(username, propertySlug, ext)
+	if err := store.Put(context.TODO(), key, contentType, data); err != nil {
+		return "", "", 0, err
+	}
+	return key, contentType, int64(len(data)), nil
+}
+
+// MoveAttachment görünürlüğü değişen eki diğer depolamaya yeni bir anahtarla kopyalar.
+// Eski dosya, kayıt güncellendikten sonra DeleteAttachment ile kaldırılmalıdır; böylece
+// gizlenen dosyanın eski herkese açık adresi çalışmaz.
+func MoveAttachment(key, contentType, username, propertySlug string, toPublic bool) (string, error) {
+	source, err := attachmentStore(!toPublic)
+	if err != nil {
+		return "", err
+	}
+	target, err := attachmentStore(toPublic)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := source.Get(context.TODO(), key)
+	if err != nil {
+		return "", err
+	}
+
+	newKey := attachmentKey(username, propertySlug, path.Ext(key))
+	if err := target.Put(context.TODO(), newKey, contentType, data); err != nil {
+		return "", err
+	}
+	return newKey, nil
+}
+
+// DeleteAttachment ek dosyasını bulunduğu depolamadan siler
+func DeleteAttachment(key string, public bool) error {
+	store, err := attachmentStore(public)
+	if err != nil {
+		return err
+	}
+	return store.Delete(context.TODO(), key)
+}
+
+// SignedAttachmentURL gizli ek için süreli indirme linki üretir
+func SignedAttachmentURL(key string, expires time.Duration) (string, error) {
+	private, err := getPrivateStorage()
+	if err != nil {
+		return "", err
+	}
+	return private.SignedURL(context.TODO(), key, http.MethodGet, expires)
+}
+
+// MoveLegacyPrivateAttachments gizli olduğu halde (eski sürümlerde) herkese açık bucket'ta
+// saklanan ekleri aynı anahtarla özel depolamaya taşır. Zaten taşınmış olanlar atlanır.
+func MoveLegacyPrivateAttachments(attachments []model.PropertyAttachment) (int, error) {
+	store, err := getStorage()
+	if err != nil {
+		return 0, err
+	}
+	private, err := getPrivateStorage()
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, attachment := range attachments {
+		data, err := store.Get(context.TODO(), attachment.ObjectKey)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return moved, fmt.Errorf("could not read %s: %v", attachment.ObjectKey, err)
+		}
+		if err := private.Put(context.TODO(), attachment.ObjectKey, attachment.ContentType, data); err != nil {
+			return moved, fmt.Errorf("could not move %s: %v", attachment.ObjectKey, err)
+		}
+		if err := store.Delete(context.TODO(), attachment.ObjectKey); err != nil {
+			return moved, fmt.Errorf("could not delete %s: %v", attachment.ObjectKey, err)
+		}
+		moved++
+	}
+	return moved, nil
+}
@@ -3,55 +3,51 @@ package cloudflare
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"mime/multipart"
-	"os"
-	"path/filepath"
+	"path"
 	"strings"
 	"time"
 
 	"estepage_backend/internal/model"
 	"estepage_backend/pkg/database"
+	imageutil "estepage_backend/pkg/utils/image"
 	"estepage_backend/pkg/utils/jwt"
+	"estepage_backend/pkg/utils/storage"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/gosimple/slug"
 )
 
 // Utility functions
-func getObjectKeyFromURL(url string) string {
-	return strings.TrimPrefix(url, "https://cdn.estapage.com/")
-}
-
 func GetFileNameFromURL(url string) string {
 	parts := strings.Split(url, "/")
 	return parts[len(parts)-1]
 }
 
-func getS3Client() (*s3.Client, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			os.Getenv("R2_ACCESS_KEY"),
-			os.Getenv("R2_SECRET_KEY"),
-			"",
-		)),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("unable to load SDK config: %v", err)
+// IsHostedURL URL'in yapılandırılmış depolamaya ait olup olmadığını döner
+func IsHostedURL(url string) bool {
+	if storage.GlobalStorage == nil {
+		return false
 	}
+	_, ok := storage.GlobalStorage.KeyFromURL(url)
+	return ok
+}
 
-	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		o.BaseEndpoint = aws.String(fmt.Sprintf("https://%s.r2.cloudflarestorage.com", os.Getenv("R2_ACCOUNT_ID")))
-		o.UsePathStyle = true
-		o.Region = "auto"
-	})
+func getStorage() (storage.Storage, error) {
+	if storage.GlobalStorage == nil {
+		return nil, fmt.Errorf("storage is not initialized")
+	}
+	return storage.GlobalStorage, nil
+}
 
-	return client, nil
+func getPrivateStorage() (storage.Storage, error) {
+	if storage.PrivateStorage == nil {
+		return nil, fmt.Errorf("private storage is not initialized")
+	}
+	return storage.PrivateStorage, nil
 }
 
 // Types
@@ -59,11 +55,20 @@ type UploadImageConfig struct {
 	File         *multipart.FileHeader
 	Username     string
 	PropertySlug string
+	Watermark    *imageutil.Watermark // Opsiyonel, bkz. UserWatermark
 }
 
 type UploadResult struct {
 	URL          string
+	ThumbnailURL string
 	CloudflareID string
+	Width        int
+	Height       int
+	Size         int64
+	Variants     []model.ImageVariant
+
+	PerceptualHash string
+	Placeholder    imageutil.Placeholder
 }
 
 type UploadAvatarConfig struct {
@@ -71,96 +76,192 @@ type UploadAvatarConfig struct {
 	Username string
 }
 
+// PropertyUploadKey doğrudan yükleme için işlenmemiş dosyanın anahtarı
+func PropertyUploadKey(username, propertySlug, ext string) string {
+	uniqueID := fmt.Sprintf("%d-%s", time.Now().UnixNano(), uuid.New().String())
+	return path.Join("users", slug.Make(username), "properties", slug.Make(propertySlug), "uploads", uniqueID+ext)
+}
+
 // Core functions
 func DeleteImage(fullURL string) error {
-	objectKey := getObjectKeyFromURL(fullURL)
-
-	client, err := getS3Client()
+	store, err := getStorage()
 	if err != nil {
 		return err
 	}
 
-	input := &s3.DeleteObjectInput{
-		Bucket: aws.String(os.Getenv("R2_BUCKET_NAME")),
-		Key:    aws.String(objectKey),
+	// Depolamaya ait olmayan (harici) URL'ler için silinecek dosya yok
+	objectKey, ok := store.KeyFromURL(fullURL)
+	if !ok {
+		return nil
+	}
+
+	return store.Delete(context.TODO(), objectKey)
+}
+
+// DeleteImageObjects resmin herkese açık tüm dosyalarını ve özel depolamadaki
+// filigransız kopyasını siler
+func DeleteImageObjects(img *model.PropertyImage) error {
+	for _, objectURL := range img.ObjectURLs() {
+		if err := DeleteImage(objectURL); err != nil {
+			return err
+		}
 	}
 
-	_, err = client.DeleteObject(context.TODO(), input)
+	store, err := getStorage()
 	if err != nil {
-		return fmt.Errorf("could not delete file from R2: %v", err)
+		return err
+	}
+	originalKey, ok := img.OriginalKey(store)
+	if !ok {
+		return nil
+	}
+	private, err := getPrivateStorage()
+	if err != nil {
+		return err
 	}
+	return private.Delete(context.TODO(), originalKey)
+}
 
-	return nil
+func readFile(file *multipart.FileHeader) ([]byte, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("could not open file: %v", err)
+	}
+	defer src.Close()
+
+	return io.ReadAll(src)
 }
 
+// UploadImage resmi işler (yön düzeltme, metadata temizleme, boyutlandırma) ve
+// tüm varyantları depolamaya yükler. URL tam boy JPEG, ThumbnailURL küçük JPEG'dir.
 func UploadImage(config UploadImageConfig) (UploadResult, error) {
-	safeUsername := slug.Make(config.Username)
-	safePropertySlug := slug.Make(config.PropertySlug)
+	data, err := readFile(config.File)
+	if err != nil {
+		return UploadResult{}, err
+	}
 
-	ext := filepath.Ext(config.File.Filename)
-	uniqueID := fmt.Sprintf("%d-%s", time.Now().UnixNano(), uuid.New().String())
-	uniqueFilename := uniqueID + ext
+	return UploadImageData(data, config.Username, config.PropertySlug, config.Watermark)
+}
 
-	objectKey := filepath.Join("users", safeUsername, "properties", safePropertySlug, "images", uniqueFilename)
+// UploadImageData UploadImage ile aynı işlemi dosya içeriği üzerinden yapar
+// (örn. doğrudan depolamaya yüklenmiş bir dosyanın onaylanması).
+// Filigransız kopya yeniden işleme için özel depolamaya (PropertyImage.OriginalKey) kaydedilir.
+func UploadImageData(data []byte, username, propertySlug string, watermark *imageutil.Watermark) (UploadResult, error) {
+	safeUsername := slug.Make(username)
+	safePropertySlug := slug.Make(propertySlug)
 
-	client, err := getS3Client()
+	processed, err := imageutil.Process(data, imageutil.PropertyVariants, imageutil.Options{
+		Watermark:    watermark,
+		KeepOriginal: true,
+	})
 	if err != nil {
 		return UploadResult{}, err
 	}
 
-	src, err := config.File.Open()
+	store, err := getStorage()
 	if err != nil {
-		return UploadResult{}, fmt.Errorf("could not open file: %v", err)
+		return UploadResult{}, err
+	}
+	private, err := getPrivateStorage()
+	if err != nil {
+		return UploadResult{}, err
 	}
-	defer src.Close()
 
-	input := &s3.PutObjectInput{
-		Bucket: aws.String(os.Getenv("R2_BUCKET_NAME")),
-		Key:    aws.String(objectKey),
-		Body:   src,
+	uniqueID := fmt.Sprintf("%d-%s", time.Now().UnixNano(), uuid.New().String())
+	result := UploadResult{
+		CloudflareID: uniqueID,
+		Width:        processed.Width,
+		Height:       processed.Height,
+		Size:         int64(len(data)),
+
+		PerceptualHash: model.FormatPerceptualHash(processed.Hash),
+		Placeholder:    processed.Placeholder,
 	}
 
-	_, err = client.PutObject(context.TODO(), input)
-	if err != nil {
-		return UploadResult{}, fmt.Errorf("could not upload file to R2: %v", err)
+	imageDir := path.Join("users", safeUsername, "properties", safePropertySlug, "images")
+	originalKey := path.Join(imageDir, uniqueID+model.OriginalSuffix)
+	if err := private.Put(context.TODO(), originalKey, processed.Original.ContentType, processed.Original.Data); err != nil {
+		return UploadResult{}, err
 	}
 
-	return UploadResult{
-		URL:          fmt.Sprintf("https://cdn.estapage.com/%s", objectKey),
-		CloudflareID: uniqueID,
-	}, nil
-}
+	var uploaded []string
+	rollback := func() {
+		if delErr := private.Delete(context.TODO(), originalKey); delErr != nil {
+			log.Printf("Error deleting partial upload %s: %v", originalKey, delErr)
+		}
+		for _, objectKey := range uploaded {
+			if delErr := store.Delete(context.TODO(), objectKey); delErr != nil {
+				log.Printf("Error deleting partial upload %s: %v", objectKey, delErr)
+			}
+		}
+	}
 
-func UploadAvatar(config UploadAvatarConfig) (string, error) {
-	ext := filepath.Ext(config.File.Filename)
-	uniqueID := fmt.Sprintf("%d-%s", time.Now().UnixNano(), uuid.New().String())
-	uniqueFilename := uniqueID + ext
+	for _, variant := range processed.Variants {
+		filename := fmt.Sprintf("%s-%s%s", uniqueID, variant.Name, variant.Ext)
+		objectKey := path.Join(imageDir, filename)
+
+		if err := store.Put(context.TODO(), objectKey, variant.ContentType, variant.Data); err != nil {
+			// Yarım kalan yüklemeyi geri al
+			rollback()
+			return UploadResult{}, err
+		}
+		uploaded = append(uploaded, objectKey)
+
+		result.Variants = append(result.Variants, model.ImageVariant{
+			Name:   variant.Name,
+			Format: variant.Format,
+			URL:    store.URL(objectKey),
+			Width:  variant.Width,
+			Height: variant.Height,
+			Size:   int64(len(variant.Data)),
+		})
+	}
 
-	objectKey := filepath.Join("users", config.Username, "profile", uniqueFilename)
+	for _, variant := range result.Variants {
+		if variant.Format != imageutil.FormatJPEG {
+			continue
+		}
+		switch variant.Name {
+		case "full":
+			result.URL = variant.URL
+		case "thumbnail":
+			result.ThumbnailURL = variant.URL
+		}
+	}
+
+	return result, nil
+}
 
-	client, err := getS3Client()
+// UploadAvatar profil fotoğrafını işler ve JPEG olarak yükler
+func UploadAvatar(config UploadAvatarConfig) (string, error) {
+	data, err := readFile(config.File)
 	if err != nil {
 		return "", err
 	}
 
-	src, err := config.File.Open()
+	processed, err := imageutil.Process(data, imageutil.AvatarVariants, imageutil.Options{})
 	if err != nil {
-		return "", fmt.Errorf("could not open file: %v", err)
+		return "", err
 	}
-	defer src.Close()
 
-	input := &s3.PutObjectInput{
-		Bucket: aws.String(os.Getenv("R2_BUCKET_NAME")),
-		Key:    aws.String(objectKey),
-		Body:   src,
+	variant := processed.Find("avatar", imageutil.FormatJPEG)
+	if variant == nil {
+		return "", fmt.Errorf("could not process avatar")
 	}
 
-	_, err = client.PutObject(context.TODO(), input)
+	uniqueID := fmt.Sprintf("%d-%s", time.Now().UnixNano(), uuid.New().String())
+	objectKey := path.Join("users", config.Username, "profile", uniqueID+variant.Ext)
+
+	store, err := getStorage()
 	if err != nil {
-		return "", fmt.Errorf("could not upload avatar to R2: %v", err)
+		return "", err
+	}
+
+	if err := store.Put(context.TODO(), objectKey, variant.ContentType, variant.Data); err != nil {
+		return "", err
 	}
 
-	return fmt.Sprintf("https://cdn.estapage.com/%s", objectKey), nil
+	return store.URL(objectKey), nil
 }
 
 func UploadAvatarHandler(c *fiber.Ctx) error {
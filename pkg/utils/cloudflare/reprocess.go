@@ -0,0 +1,56 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+
+	"estepage_backend/internal/model"
+	imageutil "estepage_backend/pkg/utils/image"
+)
+
+// ReprocessImage mevcut bir resmi depolamadaki kaynağından yeniden işler.
+// İşlenmiş resimler filigransız kopyadan aynı anahtarlara yeniden yazılır. Varyantı olmayan
+// eski resimler yüklenen dosyadan yeni anahtarlarla işlenir; eski dosya revizyonlar için
+// yerinde bırakılır (referanssız kalırsa depolama temizliğinde raporlanır).
+func ReprocessImage(img *model.PropertyImage, username, propertySlug string, watermark *imageutil.Watermark) (UploadResult, error) {
+	if len(img.Variants) > 0 {
+		variants, processed, err := rerenderImage(img, watermark)
+		if err != nil {
+			return UploadResult{}, err
+		}
+
+		result := UploadResult{
+			URL:          img.URL,
+			ThumbnailURL: img.ThumbnailURL,
+			CloudflareID: img.CloudflareID,
+			Width:        img.Width,
+			Height:       img.Height,
+			Size:         img.Size,
+			Variants:     variants,
+
+			PerceptualHash: model.FormatPerceptualHash(processed.Hash),
+			Placeholder:    processed.Placeholder,
+		}
+		if result.Width == 0 || result.Height == 0 {
+			result.Width, result.Height = processed.Width, processed.Height
+		}
+		return result, nil
+	}
+
+	store, err := getStorage()
+	if err != nil {
+		return UploadResult{}, err
+	}
+
+	key, ok := store.KeyFromURL(img.URL)
+	if !ok {
+		return UploadResult{}, fmt.Errorf("image %d is not hosted in storage", img.ID)
+	}
+
+	source, err := store.Get(context.TODO(), key)
+	if err != nil {
+		return UploadResult{}, err
+	}
+
+	return UploadImageData(source, username, propertySlug, watermark)
+}
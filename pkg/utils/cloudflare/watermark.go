@@ -0,0 +1,191 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"estepage_backend/internal/model"
+	imageutil "estepage_backend/pkg/utils/image"
+	"estepage_backend/pkg/utils/storage"
+
+	"github.com/google/uuid"
+	"github.com/gosimple/slug"
+)
+
+// UserWatermark kullanıcının filigran ayarlarını yükler; filigran kapalıysa nil döner
+func UserWatermark(user *model.User) (*imageutil.Watermark, error) {
+	if !user.WatermarkEnabled || user.WatermarkLogoURL == "" {
+		return nil, nil
+	}
+
+	store, err := getStorage()
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := store.KeyFromURL(user.WatermarkLogoURL)
+	if !ok {
+		return nil, fmt.Errorf("watermark logo is not a storage URL")
+	}
+
+	logo, err := store.Get(context.TODO(), key)
+	if err != nil {
+		return nil, fmt.Errorf("could not load watermark logo: %v", err)
+	}
+
+	return imageutil.NewWatermark(logo, user.WatermarkPosition, user.WatermarkOpacity, user.WatermarkScale)
+}
+
+// UploadWatermarkLogo logoyu PNG'ye çevirip yükler
+func UploadWatermarkLogo(data []byte, username string) (string, error) {
+	logo, err := imageutil.NormalizeLogo(data)
+	if err != nil {
+		return "", err
+	}
+
+	store, err := getStorage()
+	if err != nil {
+		return "", err
+	}
+
+	uniqueID := fmt.Sprintf("%d-%s", time.Now().UnixNano(), uuid.New().String())
+	objectKey := path.Join("users", slug.Make(username), "watermark", uniqueID+".png")
+
+	if err := store.Put(context.TODO(), objectKey, "image/png", logo); err != nil {
+		return "", err
+	}
+	return store.URL(objectKey), nil
+}
+
+// RerenderImage resmin varyantlarını filigransız kopyadan yeni filigranla yeniden üretir.
+// Dosyalar aynı anahtarlara yazılır, böylece URL'ler (ve revizyonlar) geçerli kalır.
+func RerenderImage(img *model.PropertyImage, watermark *imageutil.Watermark) ([]model.ImageVariant, error) {
+	variants, _, err := rerenderImage(img, watermark)
+	return variants, err
+}
+
+// rerenderImage RerenderImage ile aynıdır; ayrıca pipeline çıktısını (hash, yer tutucular) döner
+func rerenderImage(img *model.PropertyImage, watermark *imageutil.Watermark) ([]model.ImageVariant, *imageutil.Processed, error) {
+	store, err := getStorage()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var variants []model.ImageVariant
+	if len(img.Variants) > 0 {
+		if err := json.Unmarshal(img.Variants, &variants); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(variants) == 0 {
+		return nil, nil, fmt.Errorf("image %d has not been processed", img.ID)
+	}
+
+	private, err := getPrivateStorage()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	originalKey, ok := img.OriginalKey(store)
+	if !ok {
+		return nil, nil, fmt.Errorf("image %d is not hosted in storage", img.ID)
+	}
+
+	source, err := private.Get(context.TODO(), originalKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		source, err = moveLegacyOriginal(store, private, originalKey)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		// Filigran özelliğinden önce işlenen resimlerde kopya yok; tam boy JPEG
+		// henüz filigransız olduğu için kopya olarak saklanır
+		if img.WatermarkVersion != 0 {
+			return nil, nil, fmt.Errorf("original of image %d is missing", img.ID)
+		}
+		fullKey, ok := store.KeyFromURL(img.URL)
+		if !ok {
+			return nil, nil, fmt.Errorf("image %d is not hosted in storage", img.ID)
+		}
+		if source, err = store.Get(context.TODO(), fullKey); err != nil {
+			return nil, nil, err
+		}
+		if err := private.Put(context.TODO(), originalKey, "image/jpeg", source); err != nil {
+			return nil, nil, err
+		}
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	processed, err := imageutil.Process(source, imageutil.PropertyVariants, imageutil.Options{Watermark: watermark})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range variants {
+		encoded := processed.Find(variants[i].Name, variants[i].Format)
+		if encoded == nil {
+			continue
+		}
+		key, ok := store.KeyFromURL(variants[i].URL)
+		if !ok {
+			continue
+		}
+		if err := store.Put(context.TODO(), key, encoded.ContentType, encoded.Data); err != nil {
+			return nil, nil, err
+		}
+		variants[i].Width = encoded.Width
+		variants[i].Height = encoded.Height
+		variants[i].Size = int64(len(encoded.Data))
+	}
+
+	return variants, processed, nil
+}
+
+// moveLegacyOriginal herkese açık bucket'ta kalmış filigransız kopyayı özel depolamaya taşır
+func moveLegacyOriginal(store, private storage.Storage, key string) ([]byte, error) {
+	data, err := store.Get(context.TODO(), key)
+	if err != nil {
+		return nil, err
+	}
+	if err := private.Put(context.TODO(), key, "image/jpeg", data); err != nil {
+		return nil, err
+	}
+	if err := store.Delete(context.TODO(), key); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// MoveLegacyOriginals herkese açık bucket'ta varyantların yanında duran eski filigransız
+// kopyaları özel depolamaya taşır. Taşınacak dosya kalmadığında sadece listeleme yapar.
+func MoveLegacyOriginals() (int, error) {
+	store, err := getStorage()
+	if err != nil {
+		return 0, err
+	}
+	private, err := getPrivateStorage()
+	if err != nil {
+		return 0, err
+	}
+
+	objects, err := store.List(context.TODO(), "users/")
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, obj := range objects {
+		if !strings.HasSuffix(obj.Key, model.OriginalSuffix) || !strings.Contains(obj.Key, "/images/") {
+			continue
+		}
+		if _, err := moveLegacyOriginal(store, private, obj.Key); err != nil {
+			return moved, fmt.Errorf("could not move %s: %v", obj.Key, err)
+		}
+		moved++
+	}
+	return moved, nil
+}
@@ -0,0 +1,67 @@
+package currency
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BaseCurrency fiyat karşılaştırmalarında kullanılan ortak para birimi
+const BaseCurrency = "USD"
+
+// RatesToUSD 1 birim para biriminin yaklaşık USD karşılığı.
+// Arama filtrelerinde fiyatları aynı tabana çekmek için kullanılır, muhasebe için değil.
+var RatesToUSD = map[string]float64{
+	"USD": 1,
+	"EUR": 1.08,
+	"TRY": 0.029,
+	"GBP": 1.27,
+	"JPY": 0.0067,
+	"AUD": 0.66,
+	"CAD": 0.73,
+}
+
+// IsSupported para biriminin kur tablosunda olup olmadığını döner
+func IsSupported(code string) bool {
+	_, ok := RatesToUSD[strings.ToUpper(code)]
+	return ok
+}
+
+// ToUSD verilen tutarı USD'ye çevirir
+func ToUSD(amount float64, code string) (float64, error) {
+	rate, ok := RatesToUSD[strings.ToUpper(code)]
+	if !ok {
+		return 0, fmt.Errorf("unsupported currency: %s", code)
+	}
+	return amount * rate, nil
+}
+
+// FromUSD USD tutarını verilen para birimine çevirir
+func FromUSD(amount float64, code string) (float64, error) {
+	rate, ok := RatesToUSD[strings.ToUpper(code)]
+	if !ok {
+		return 0, fmt.Errorf("unsupported currency: %s", code)
+	}
+	return amount / rate, nil
+}
+
+// SQLToUSD fiyat kolonunu USD'ye çeviren bir SQL ifadesi üretir.
+// Değerler sabit tablodan geldiği için ifade parametre gerektirmez.
+func SQLToUSD(priceColumn, currencyColumn string) string {
+	codes := make([]string, 0, len(RatesToUSD))
+	for code := range RatesToUSD {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	var b strings.Builder
+	b.WriteString("(")
+	b.WriteString(priceColumn)
+	b.WriteString(" * CASE ")
+	b.WriteString(currencyColumn)
+	for _, code := range codes {
+		fmt.Fprintf(&b, " WHEN '%s' THEN %g", code, RatesToUSD[code])
+	}
+	b.WriteString(" ELSE 1 END)")
+	return b.String()
+}
@@ -0,0 +1,274 @@
+// Package filter liste endpoint'leri için küçük bir filtre/sıralama dili sağlar.
+//
+// Örnek: status:in(new,contacted) created_at:>2026-01-01 name:~ahmet sort:-created_at
+//
+// Desteklenen operatörler:
+//
+//	alan:değer        eşittir
+//	alan:!değer       eşit değildir
+//	alan:>değer       büyüktür (>=, <, <= de desteklenir)
+//	alan:in(a,b)      listedekilerden biri
+//	alan:!in(a,b)     listedekilerin hiçbiri
+//	alan:~metin       metni içerir (sadece string alanlar)
+//
+// Boşluk içeren değerler çift tırnak ile yazılır: name:~"ahmet yılmaz"
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type FieldType int
+
+const (
+	String FieldType = iota
+	Int
+	Bool
+	Time
+	Enum
+)
+
+// Field filtrelenebilir bir alanın SQL kolonu ve tipi
+type Field struct {
+	Column string
+	Type   FieldType
+	Values []string // Enum alanlar için izin verilen değerler
+}
+
+// Schema bir endpoint'te filtrelenebilecek alanlar
+type Schema map[string]Field
+
+// Condition doğrulanmış tek bir filtre koşulu
+type Condition struct {
+	Field  string
+	Op     string
+	Values []interface{}
+}
+
+// Expression ayrıştırılmış filtre ifadesi. Sort boşsa endpoint'in varsayılanı kullanılır.
+type Expression struct {
+	Conditions []Condition
+	Sort       string
+}
+
+// Error kullanıcıya dönecek ayrıştırma hatası
+type Error struct {
+	Message string
+	Token   string
+	Allowed []string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Response hatayı API'nin standart hata formatına çevirir
+func (e *Error) Response() fiber.Map {
+	resp := fiber.Map{
+		"error": e.Message,
+		"param": "filter",
+	}
+	if e.Token != "" {
+		resp["token"] = e.Token
+	}
+	if len(e.Allowed) > 0 {
+		resp["allowed"] = e.Allowed
+	}
+	return resp
+}
+
+func (s Schema) fieldNames() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tokenize ifadeyi boşluklardan böler, çift tırnak içindeki boşlukları korur
+func tokenize(input string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	if inQuotes {
+		return nil, &Error{Message: "Unterminated quote in filter"}
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+// Parse ifadeyi şemaya göre doğrular. Sıralama alanının doğrulaması çağırana bırakılır.
+func Parse(input string, schema Schema) (*Expression, error) {
+	expr := &Expression{}
+
+	tokens, err := tokenize(strings.TrimSpace(input))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range tokens {
+		name, raw, ok := strings.Cut(token, ":")
+		if !ok || name == "" || raw == "" {
+			return nil, &Error{
+				Message: "Filter terms must look like field:value",
+				Token:   token,
+			}
+		}
+
+		if name == "sort" {
+			expr.Sort = raw
+			continue
+		}
+
+		field, ok := schema[name]
+		if !ok {
+			return nil, &Error{
+				Message: fmt.Sprintf("Unknown filter field: %s", name),
+				Token:   token,
+				Allowed: schema.fieldNames(),
+			}
+		}
+
+		cond, err := parseCondition(name, raw, field)
+		if err != nil {
+			return nil, &Error{Message: err.Error(), Token: token, Allowed: field.Values}
+		}
+		expr.Conditions = append(expr.Conditions, cond)
+	}
+
+	return expr, nil
+}
+
+func parseCondition(name, raw string, field Field) (Condition, error) {
+	cond := Condition{Field: name}
+
+	switch {
+	case strings.HasPrefix(raw, "!in(") && strings.HasSuffix(raw, ")"):
+		cond.Op, raw = "NOT IN", raw[4:len(raw)-1]
+	case strings.HasPrefix(raw, "in(") && strings.HasSuffix(raw, ")"):
+		cond.Op, raw = "IN", raw[3:len(raw)-1]
+	case strings.HasPrefix(raw, ">="):
+		cond.Op, raw = ">=", raw[2:]
+	case strings.HasPrefix(raw, "<="):
+		cond.Op, raw = "<=", raw[2:]
+	case strings.HasPrefix(raw, ">"):
+		cond.Op, raw = ">", raw[1:]
+	case strings.HasPrefix(raw, "<"):
+		cond.Op, raw = "<", raw[1:]
+	case strings.HasPrefix(raw, "!"):
+		cond.Op, raw = "<>", raw[1:]
+	case strings.HasPrefix(raw, "~"):
+		cond.Op, raw = "ILIKE", raw[1:]
+	default:
+		cond.Op = "="
+	}
+
+	switch cond.Op {
+	case ">", ">=", "<", "<=":
+		if field.Type != Int && field.Type != Time {
+			return cond, fmt.Errorf("Operator %s is only allowed on numeric and date fields", cond.Op)
+		}
+	case "ILIKE":
+		if field.Type != String {
+			return cond, fmt.Errorf("Operator ~ is only allowed on text fields")
+		}
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(raw)
+		cond.Values = []interface{}{"%" + escaped + "%"}
+		return cond, nil
+	}
+
+	rawValues := []string{raw}
+	if cond.Op == "IN" || cond.Op == "NOT IN" {
+		rawValues = strings.Split(raw, ",")
+	}
+
+	for _, rv := range rawValues {
+		v, err := convertValue(strings.TrimSpace(rv), field)
+		if err != nil {
+			return cond, err
+		}
+		cond.Values = append(cond.Values, v)
+	}
+	return cond, nil
+}
+
+func convertValue(raw string, field Field) (interface{}, error) {
+	if raw == "" {
+		return nil, fmt.Errorf("Empty filter value")
+	}
+
+	switch field.Type {
+	case Int:
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Value %q must be an integer", raw)
+		}
+		return v, nil
+	case Bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("Value %q must be true or false", raw)
+		}
+		return v, nil
+	case Time:
+		if t, err := time.Parse("2006-01-02", raw); err == nil {
+			return t, nil
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("Value %q must be a date (YYYY-MM-DD) or RFC3339 timestamp", raw)
+		}
+		return t, nil
+	case Enum:
+		for _, allowed := range field.Values {
+			if raw == allowed {
+				return raw, nil
+			}
+		}
+		return nil, fmt.Errorf("Invalid value %q", raw)
+	default:
+		return raw, nil
+	}
+}
+
+// Apply koşulları parametreli WHERE ifadeleri olarak sorguya ekler.
+// Kolon adları sadece şemadan geldiği için kullanıcı girdisi SQL'e karışmaz.
+func (e *Expression) Apply(db *gorm.DB, schema Schema) *gorm.DB {
+	for _, cond := range e.Conditions {
+		column := schema[cond.Field].Column
+		switch cond.Op {
+		case "IN", "NOT IN":
+			db = db.Where(column+" "+cond.Op+" ?", cond.Values)
+		case "ILIKE":
+			db = db.Where(column+" ILIKE ?", cond.Values[0])
+		default:
+			db = db.Where(column+" "+cond.Op+" ?", cond.Values[0])
+		}
+	}
+	return db
+}
@@ -0,0 +1,63 @@
+package image
+
+import (
+	"image"
+	"math/bits"
+)
+
+// DuplicateHashThreshold bu Hamming mesafesi ve altındaki hash'ler aynı fotoğraf sayılır
+// (yeniden boyutlandırma, sıkıştırma ve küçük renk düzeltmelerine dayanıklı)
+const DuplicateHashThreshold = 6
+
+// PerceptualHash 64 bitlik fark hash'i (dHash) hesaplar: resim 9x8 gri tonlamaya
+// küçültülür ve her satırda yan yana piksellerin parlaklık farkı bit olarak kaydedilir.
+func PerceptualHash(src *image.RGBA) uint64 {
+	const w, h = 9, 8
+	var gray [h][w]uint32
+
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if sw == 0 || sh == 0 {
+		return 0
+	}
+
+	for y := 0; y < h; y++ {
+		sy0, sy1 := y*sh/h, (y+1)*sh/h
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for x := 0; x < w; x++ {
+			sx0, sx1 := x*sw/w, (x+1)*sw/w
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+
+			var sum, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				i := src.PixOffset(sx0, sy)
+				for sx := sx0; sx < sx1; sx++ {
+					// ITU-R BT.601 luma
+					sum += (299*uint32(src.Pix[i]) + 587*uint32(src.Pix[i+1]) + 114*uint32(src.Pix[i+2])) / 1000
+					i += 4
+					n++
+				}
+			}
+			gray[y][x] = sum / n
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if gray[y][x] < gray[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HammingDistance iki hash arasındaki farklı bit sayısı
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
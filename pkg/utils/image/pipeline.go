@@ -0,0 +1,145 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+
+	"github.com/chai2010/webp"
+)
+
+const (
+	FormatWebP = "webp"
+	FormatJPEG = "jpeg"
+
+	webpQuality = 80
+	jpegQuality = 82
+)
+
+// VariantSpec üretilecek bir genişlik
+type VariantSpec struct {
+	Name  string
+	Width int
+}
+
+// PropertyVariants ilan resimleri için üretilen boyutlar
+var PropertyVariants = []VariantSpec{
+	{Name: "thumbnail", Width: 320},
+	{Name: "card", Width: 800},
+	{Name: "full", Width: 1920},
+}
+
+// AvatarVariants profil fotoğrafı için üretilen boyutlar
+var AvatarVariants = []VariantSpec{
+	{Name: "avatar", Width: 512},
+}
+
+// EncodedVariant encode edilmiş tek bir çıktı (örn. card/webp)
+type EncodedVariant struct {
+	Name        string
+	Format      string
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// OriginalMaxWidth saklanan filigransız kopyanın en fazla genişliği
+const OriginalMaxWidth = 3840
+
+// Options pipeline ayarları
+type Options struct {
+	Watermark    *Watermark // nil değilse tüm varyantlara basılır
+	KeepOriginal bool       // Filigransız, yönü düzeltilmiş JPEG kopyayı da üret (yeniden işleme için)
+}
+
+// Processed pipeline çıktısı; Width/Height orijinalin döndürülmüş boyutlarıdır
+type Processed struct {
+	Width       int
+	Height      int
+	Hash        uint64 // Bkz. PerceptualHash
+	Placeholder Placeholder
+	Variants    []EncodedVariant
+	Original    *EncodedVariant // Sadece Options.KeepOriginal ile
+}
+
+// Find verilen isim ve formattaki çıktıyı döner
+func (p *Processed) Find(name, format string) *EncodedVariant {
+	for i := range p.Variants {
+		if p.Variants[i].Name == name && p.Variants[i].Format == format {
+			return &p.Variants[i]
+		}
+	}
+	return nil
+}
+
+// Process resmi decode eder, EXIF yönüne göre döndürür ve her boyut için WebP + JPEG üretir
+// (varsa filigranla).
+// Çıktılar sıfırdan encode edildiği için EXIF/GPS dahil hiçbir metadata taşınmaz.
+func Process(data []byte, specs []VariantSpec, opts Options) (*Processed, error) {
+	img, format, err := decodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+	if !AllowedImageTypes["image/"+format] {
+		return nil, fmt.Errorf("unsupported image format: %s", format)
+	}
+
+	oriented := toRGBA(img)
+	if format == "jpeg" {
+		oriented = applyOrientation(oriented, jpegOrientation(data))
+	}
+
+	result := &Processed{
+		Width:  oriented.Bounds().Dx(),
+		Height: oriented.Bounds().Dy(),
+		Hash:   PerceptualHash(oriented),
+	}
+
+	placeholder, err := NewPlaceholder(oriented)
+	if err != nil {
+		return nil, err
+	}
+	result.Placeholder = placeholder
+
+	if opts.KeepOriginal {
+		original := resizeToWidth(oriented, OriginalMaxWidth)
+		buf := new(bytes.Buffer)
+		if err := jpeg.Encode(buf, flatten(original), &jpeg.Options{Quality: 92}); err != nil {
+			return nil, fmt.Errorf("could not encode original: %v", err)
+		}
+		result.Original = &EncodedVariant{
+			Name: "original", Format: FormatJPEG, ContentType: "image/jpeg", Ext: ".jpg",
+			Width: original.Bounds().Dx(), Height: original.Bounds().Dy(), Data: buf.Bytes(),
+		}
+	}
+
+	for _, spec := range specs {
+		resized := resizeToWidth(oriented, spec.Width)
+		if opts.Watermark != nil {
+			resized = opts.Watermark.apply(resized)
+		}
+		w, h := resized.Bounds().Dx(), resized.Bounds().Dy()
+
+		webpBuf := new(bytes.Buffer)
+		if err := webp.Encode(webpBuf, resized, &webp.Options{Quality: webpQuality}); err != nil {
+			return nil, fmt.Errorf("could not encode %s webp: %v", spec.Name, err)
+		}
+		result.Variants = append(result.Variants, EncodedVariant{
+			Name: spec.Name, Format: FormatWebP, ContentType: "image/webp", Ext: ".webp",
+			Width: w, Height: h, Data: webpBuf.Bytes(),
+		})
+
+		jpegBuf := new(bytes.Buffer)
+		if err := jpeg.Encode(jpegBuf, flatten(resized), &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("could not encode %s jpeg: %v", spec.Name, err)
+		}
+		result.Variants = append(result.Variants, EncodedVariant{
+			Name: spec.Name, Format: FormatJPEG, ContentType: "image/jpeg", Ext: ".jpg",
+			Width: w, Height: h, Data: jpegBuf.Bytes(),
+		})
+	}
+
+	return result, nil
+}
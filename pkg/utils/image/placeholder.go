@@ -0,0 +1,191 @@
+package image
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"math"
+	"strings"
+)
+
+const (
+	// placeholderSampleWidth BlurHash ve baskın renk bu genişliğe küçültülmüş kopyadan hesaplanır
+	placeholderSampleWidth = 32
+	// lqipWidth base64 önizleme resminin genişliği
+	lqipWidth   = 16
+	lqipQuality = 50
+)
+
+// Placeholder resim yüklenene kadar gösterilecek yer tutucu bilgileri
+type Placeholder struct {
+	BlurHash      string // https://blurha.sh
+	LQIP          string // data:image/jpeg;base64,... biçiminde küçük önizleme
+	DominantColor string // #rrggbb
+}
+
+// NewPlaceholder yönü düzeltilmiş (filigransız) resimden yer tutucuları üretir
+func NewPlaceholder(src *image.RGBA) (Placeholder, error) {
+	sample := flatten(resizeToWidth(src, placeholderSampleWidth))
+
+	// Dikey resimlerde dikey bileşen sayısı daha fazla olsun
+	xComponents, yComponents := 4, 3
+	if sample.Bounds().Dy() > sample.Bounds().Dx() {
+		xComponents, yComponents = 3, 4
+	}
+
+	lqip, err := encodeLQIP(src)
+	if err != nil {
+		return Placeholder{}, err
+	}
+
+	return Placeholder{
+		BlurHash:      encodeBlurHash(sample, xComponents, yComponents),
+		LQIP:          lqip,
+		DominantColor: dominantColor(sample),
+	}, nil
+}
+
+func encodeLQIP(src *image.RGBA) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, flatten(resizeToWidth(src, lqipWidth)), &jpeg.Options{Quality: lqipQuality}); err != nil {
+		return "", fmt.Errorf("could not encode lqip: %v", err)
+	}
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// dominantColor renkleri kanal başına 16 seviyeye gruplayıp en kalabalık grubun ortalamasını döner
+func dominantColor(src *image.RGBA) string {
+	type bucket struct {
+		r, g, b, n int
+	}
+	buckets := map[int]*bucket{}
+
+	var best *bucket
+	for i := 0; i+3 < len(src.Pix); i += 4 {
+		r, g, b := int(src.Pix[i]), int(src.Pix[i+1]), int(src.Pix[i+2])
+		key := (r>>4)<<8 | (g>>4)<<4 | b>>4
+
+		bk := buckets[key]
+		if bk == nil {
+			bk = &bucket{}
+			buckets[key] = bk
+		}
+		bk.r += r
+		bk.g += g
+		bk.b += b
+		bk.n++
+
+		if best == nil || bk.n > best.n {
+			best = bk
+		}
+	}
+
+	if best == nil {
+		return "#ffffff"
+	}
+	return fmt.Sprintf("#%02x%02x%02x", best.r/best.n, best.g/best.n, best.b/best.n)
+}
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+func encodeBase83(sb *strings.Builder, value, length int) {
+	for i := 1; i <= length; i++ {
+		digit := (value / int(math.Pow(83, float64(length-i)))) % 83
+		sb.WriteByte(base83Chars[digit])
+	}
+}
+
+func sRGBToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
+
+// encodeBlurHash resmin kosinüs dönüşümü bileşenlerini BlurHash metnine çevirir
+func encodeBlurHash(src *image.RGBA, xComponents, yComponents int) string {
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+
+	// Pikselleri bir kere doğrusal renk uzayına çevir
+	linear := make([][3]float64, width*height)
+	for y := 0; y < height; y++ {
+		i := src.PixOffset(src.Bounds().Min.X, src.Bounds().Min.Y+y)
+		for x := 0; x < width; x++ {
+			linear[y*width+x] = [3]float64{
+				sRGBToLinear(src.Pix[i]),
+				sRGBToLinear(src.Pix[i+1]),
+				sRGBToLinear(src.Pix[i+2]),
+			}
+			i += 4
+		}
+	}
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				cy := math.Cos(math.Pi * float64(j) * float64(y) / float64(height))
+				for x := 0; x < width; x++ {
+					basis := cy * math.Cos(math.Pi*float64(i)*float64(x)/float64(width))
+					px := linear[y*width+x]
+					factor[0] += basis * px[0]
+					factor[1] += basis * px[1]
+					factor[2] += basis * px[2]
+				}
+			}
+
+			scale := normalisation / float64(width*height)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	sb := &strings.Builder{}
+	encodeBase83(sb, (xComponents-1)+(yComponents-1)*9, 1)
+
+	dc, ac := factors[0], factors[1:]
+
+	maximumValue := 1.0
+	if len(ac) > 0 {
+		actualMax := 0.0
+		for _, f := range ac {
+			actualMax = math.Max(actualMax, math.Max(math.Abs(f[0]), math.Max(math.Abs(f[1]), math.Abs(f[2]))))
+		}
+		quantisedMax := int(math.Max(0, math.Min(82, math.Floor(actualMax*166-0.5))))
+		maximumValue = float64(quantisedMax+1) / 166
+		encodeBase83(sb, quantisedMax, 1)
+	} else {
+		encodeBase83(sb, 0, 1)
+	}
+
+	encodeBase83(sb, linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4)
+
+	quantise := func(v float64) int {
+		return int(math.Max(0, math.Min(18, math.Floor(signPow(v/maximumValue, 0.5)*9+9.5))))
+	}
+	for _, f := range ac {
+		encodeBase83(sb, quantise(f[0])*19*19+quantise(f[1])*19+quantise(f[2]), 2)
+	}
+
+	return sb.String()
+}
@@ -6,6 +6,7 @@ import (
 	"image"
 	"image/jpeg"
 	"image/png"
+	"io"
 	"mime/multipart"
 
 	"github.com/chai2010/webp"
@@ -13,6 +14,10 @@ import (
 
 const (
 	MaxImageSize = 10 * 1024 * 1024 // 10MB
+
+	// MaxImagePixels decode edilecek resmin en fazla piksel sayısı. Küçük bir dosya
+	// (decompression bomb) decode edilirken gigabaytlarca bellek ayırtabilir.
+	MaxImagePixels = 50 * 1000 * 1000
 )
 
 var (
@@ -23,6 +28,24 @@ var (
 	}
 )
 
+// decodeImage boyutları kontrol ettikten sonra resmi decode eder
+func decodeImage(data []byte) (image.Image, string, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("could not decode image: %v", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return nil, "", fmt.Errorf("image dimensions %dx%d exceed the %d megapixel limit",
+			cfg.Width, cfg.Height, MaxImagePixels/1000000)
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("could not decode image: %v", err)
+	}
+	return img, format, nil
+}
+
 func ProcessImage(file *multipart.FileHeader) (*bytes.Buffer, string, error) {
 	// Dosyayı aç
 	src, err := file.Open()
@@ -31,10 +54,15 @@ func ProcessImage(file *multipart.FileHeader) (*bytes.Buffer, string, error) {
 	}
 	defer src.Close()
 
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not read file: %v", err)
+	}
+
 	// Resmi decode et
-	img, format, err := image.Decode(src)
+	img, format, err := decodeImage(data)
 	if err != nil {
-		return nil, "", fmt.Errorf("could not decode image: %v", err)
+		return nil, "", err
 	}
 
 	// Buffer oluştur
@@ -0,0 +1,76 @@
+package image
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// resizeToWidth resmi oranını koruyarak verilen genişliğe küçültür; büyütme yapılmaz.
+// Alan ortalaması (box filter) kullanılır; telefon fotoğraflarını küçültürken yeterince keskin kalır.
+func resizeToWidth(src *image.RGBA, width int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if width <= 0 || width >= sw {
+		return src
+	}
+
+	height := sh * width / sw
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		sy0 := y * sh / height
+		sy1 := (y + 1) * sh / height
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for x := 0; x < width; x++ {
+			sx0 := x * sw / width
+			sx1 := (x + 1) * sw / width
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+
+			var r, g, b, a, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				i := src.PixOffset(sx0, sy)
+				for sx := sx0; sx < sx1; sx++ {
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					b += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+
+			di := dst.PixOffset(x, y)
+			dst.Pix[di] = uint8(r / n)
+			dst.Pix[di+1] = uint8(g / n)
+			dst.Pix[di+2] = uint8(b / n)
+			dst.Pix[di+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// flatten şeffaf alanları beyaza çevirir (JPEG alfa kanalı desteklemez)
+func flatten(src *image.RGBA) *image.RGBA {
+	opaque := true
+	for i := 3; i < len(src.Pix); i += 4 {
+		if src.Pix[i] != 0xFF {
+			opaque = false
+			break
+		}
+	}
+	if opaque {
+		return src
+	}
+
+	dst := image.NewRGBA(src.Bounds())
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Over)
+	return dst
+}
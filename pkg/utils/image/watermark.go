@@ -0,0 +1,110 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+)
+
+const (
+	WatermarkTopLeft     = "top-left"
+	WatermarkTopRight    = "top-right"
+	WatermarkBottomLeft  = "bottom-left"
+	WatermarkBottomRight = "bottom-right"
+	WatermarkCenter      = "center"
+
+	MaxWatermarkLogoSize = 2 * 1024 * 1024 // 2MB
+)
+
+var WatermarkPositions = map[string]bool{
+	WatermarkTopLeft:     true,
+	WatermarkTopRight:    true,
+	WatermarkBottomLeft:  true,
+	WatermarkBottomRight: true,
+	WatermarkCenter:      true,
+}
+
+// Watermark public varyantlara basılacak logo
+type Watermark struct {
+	Logo     *image.RGBA
+	Position string
+	Opacity  float64 // 0-1
+	Scale    float64 // Logonun resim genişliğine oranı
+}
+
+// NewWatermark logo dosyasını decode eder
+func NewWatermark(logo []byte, position string, opacity, scale float64) (*Watermark, error) {
+	img, _, err := decodeImage(logo)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode watermark logo: %v", err)
+	}
+	if !WatermarkPositions[position] {
+		position = WatermarkBottomRight
+	}
+	return &Watermark{
+		Logo:     toRGBA(img),
+		Position: position,
+		Opacity:  opacity,
+		Scale:    scale,
+	}, nil
+}
+
+// NormalizeLogo yüklenen logoyu şeffaflığı koruyarak metadata'sız PNG'ye çevirir
+func NormalizeLogo(data []byte) ([]byte, error) {
+	img, format, err := decodeImage(data)
+	if err != nil {
+		return nil, err
+	}
+	if !AllowedImageTypes["image/"+format] {
+		return nil, fmt.Errorf("unsupported image format: %s", format)
+	}
+
+	logo := resizeToWidth(toRGBA(img), 1024)
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, logo); err != nil {
+		return nil, fmt.Errorf("could not encode logo: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// apply logoyu resmin bir kopyasına basar; kaynak resim değiştirilmez
+func (w *Watermark) apply(src *image.RGBA) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	copy(dst.Pix, src.Pix)
+
+	logoWidth := int(float64(bounds.Dx()) * w.Scale)
+	if logoWidth < 1 || w.Opacity <= 0 {
+		return dst
+	}
+	logo := resizeToWidth(w.Logo, logoWidth)
+	lw, lh := logo.Bounds().Dx(), logo.Bounds().Dy()
+
+	margin := bounds.Dx() * 3 / 100
+	var x, y int
+	switch w.Position {
+	case WatermarkTopLeft:
+		x, y = margin, margin
+	case WatermarkTopRight:
+		x, y = bounds.Dx()-lw-margin, margin
+	case WatermarkBottomLeft:
+		x, y = margin, bounds.Dy()-lh-margin
+	case WatermarkCenter:
+		x, y = (bounds.Dx()-lw)/2, (bounds.Dy()-lh)/2
+	default:
+		x, y = bounds.Dx()-lw-margin, bounds.Dy()-lh-margin
+	}
+
+	opacity := w.Opacity
+	if opacity > 1 {
+		opacity = 1
+	}
+	mask := image.NewUniform(color.Alpha{A: uint8(opacity * 255)})
+	target := image.Rect(x, y, x+lw, y+lh).Intersect(bounds)
+	sp := logo.Bounds().Min.Add(target.Min.Sub(image.Pt(x, y)))
+	draw.DrawMask(dst, target, logo, sp, mask, image.Point{}, draw.Over)
+	return dst
+}
@@ -0,0 +1,369 @@
+// Package importer CSV ve XML ilan dosyalarını (Kyero/OpenImmo benzeri) ortak kayıtlara çevirir.
+// Alan adları PropertyInput'un JSON isimlerine eşlenir; doğrulama çağırana bırakılır.
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	FormatCSV = "csv"
+	FormatXML = "xml"
+
+	// MaxRows tek bir import dosyasındaki en fazla ilan sayısı
+	MaxRows = 1000
+
+	// Eşlemede kullanılan ek alanlar (PropertyInput'ta karşılığı yok)
+	FieldReference = "reference" // Kaynak sistemdeki ilan referansı
+	FieldAreaM2    = "area_m2"   // Metrekare; sq ft'e çevrilir
+	FieldCountry   = "country"   // Ülke kodu ya da adı
+	FieldState     = "state"     // Eyalet/il kodu ya da adı
+	FieldFeatures  = "features"  // Liste halindeki özellikler (XML)
+)
+
+var ErrNoRecords = errors.New("no listings found in file")
+
+// aliases normalize edilmiş kolon/XML yolu -> PropertyInput alanı
+var aliases = map[string]string{
+	"title":    "title",
+	"name":     "title",
+	"headline": "title",
+
+	"description":                   "description",
+	"desc":                          "description",
+	"desc_en":                       "description",
+	"freitexte_objektbeschreibung":  "description",
+	"freitexte_objekttitel":         "title",
+	"verwaltung_techn_objektnr_ext": FieldReference,
+
+	"price":                        "price",
+	"preis":                        "price",
+	"preise_kaufpreis":             "price",
+	"preise_kaltmiete":             "price",
+	"currency":                     "currency",
+	"waehrung":                     "currency",
+	"preise_waehrung_iso_waehrung": "currency",
+
+	"type":          "type",
+	"property_type": "type",
+	"status":        "status",
+	"price_freq":    "status",
+
+	"country_code":          "country_code",
+	"country":               FieldCountry,
+	"land":                  FieldCountry,
+	"geo_land_iso_land":     FieldCountry,
+	"state_code":            "state_code",
+	"state":                 FieldState,
+	"province":              FieldState,
+	"region":                FieldState,
+	"bundesland":            FieldState,
+	"geo_bundesland":        FieldState,
+	"city":                  "city",
+	"town":                  "city",
+	"ort":                   "city",
+	"geo_ort":               "city",
+	"district":              "district",
+	"location_detail":       "district",
+	"geo_regionaler_zusatz": "district",
+	"full_address":          "full_address",
+	"address":               "full_address",
+	"street":                "full_address",
+	"geo_strasse":           "full_address",
+
+	"latitude":                       "latitude",
+	"lat":                            "latitude",
+	"location_latitude":              "latitude",
+	"geo_geokoordinaten_breitengrad": "latitude",
+	"longitude":                      "longitude",
+	"lng":                            "longitude",
+	"lon":                            "longitude",
+	"location_longitude":             "longitude",
+	"geo_geokoordinaten_laengengrad": "longitude",
+
+	"bedrooms":                     "bedrooms",
+	"beds":                         "bedrooms",
+	"flaechen_anzahl_schlafzimmer": "bedrooms",
+	"bathrooms":                    "bathrooms",
+	"baths":                        "bathrooms",
+	"flaechen_anzahl_badezimmer":   "bathrooms",
+	"garage_spaces":                "garage_spaces",
+	"garages":                      "garage_spaces",
+	"parking":                      "garage_spaces",
+	"area_sq_ft":                   "area_sq_ft",
+	"area_sqft":                    "area_sq_ft",
+	"sqft":                         "area_sq_ft",
+	"area_m2":                      FieldAreaM2,
+	"built_area":                   FieldAreaM2,
+	"surface_area_built":           FieldAreaM2,
+	"flaechen_wohnflaeche":         FieldAreaM2,
+	"year_built":                   "year_built",
+	"build_year":                   "year_built",
+	"zustand_angaben_baujahr":      "year_built",
+	"swimming_pool":                "swimming_pool",
+	"pool":                         "swimming_pool",
+	"garden":                       "garden",
+	"air_conditioning":             "air_conditioning",
+	"central_heating":              "central_heating",
+	"security_system":              "security_system",
+
+	"images":                     "images",
+	"image":                      "images",
+	"images_image_url":           "images",
+	"anhaenge_anhang_daten_pfad": "images",
+	"features_feature":           FieldFeatures,
+
+	"id":        FieldReference,
+	"ref":       FieldReference,
+	"reference": FieldReference,
+}
+
+// XML'de ilan kaydı olarak kabul edilen elemanlar
+var recordElements = map[string]bool{
+	"property":  true,
+	"immobilie": true,
+	"listing":   true,
+}
+
+// Feature eşlenemeyen CSV kolonlarından ya da XML özellik listesinden gelen özellik
+type Feature struct {
+	Title  string
+	Values []string
+}
+
+// Record dosyadaki tek bir ilan
+type Record struct {
+	Row      int                 // 1'den başlayan kayıt numarası
+	Fields   map[string][]string // PropertyInput alanı -> değer(ler)
+	Features []Feature
+}
+
+// Get alanın ilk değerini döner
+func (r Record) Get(field string) string {
+	if values := r.Fields[field]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (r *Record) add(field, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	r.Fields[field] = append(r.Fields[field], value)
+}
+
+// normalizeKey "Year Built" ya da "surface-area.built" gibi isimleri year_built biçimine çevirir
+func normalizeKey(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	key = strings.NewReplacer(" ", "_", "-", "_", ".", "_", "/", "_").Replace(key)
+	return strings.Trim(key, "_")
+}
+
+// DetectFormat dosya adından formatı tahmin eder
+func DetectFormat(fileName string) string {
+	if strings.HasSuffix(strings.ToLower(fileName), ".xml") {
+		return FormatXML
+	}
+	return FormatCSV
+}
+
+// Parse verilen formattaki dosyayı kayıtlara çevirir
+func Parse(format string, r io.Reader) ([]Record, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(r)
+	case FormatXML:
+		return ParseXML(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+}
+
+// ParseCSV başlık satırı olan bir CSV dosyasını okur. Ayraç (virgül/noktalı virgül) otomatik seçilir.
+// Eşlenemeyen kolonlar özellik (PropertyFeature) olarak döner; "feature:" önekli kolonlar da öyle.
+func ParseCSV(r io.Reader) ([]Record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	content := strings.TrimPrefix(string(data), "\uFEFF") // UTF-8 BOM
+
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	firstLine, _, _ := strings.Cut(content, "\n")
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("could not read CSV header: %v", err)
+	}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read CSV row %d: %v", len(records)+1, err)
+		}
+		if len(records) >= MaxRows {
+			return nil, fmt.Errorf("file contains more than %d listings", MaxRows)
+		}
+
+		record := Record{Row: len(records) + 1, Fields: map[string][]string{}}
+		for i, value := range row {
+			if i >= len(header) || strings.TrimSpace(value) == "" {
+				continue
+			}
+			column := strings.TrimSpace(header[i])
+
+			if title, ok := strings.CutPrefix(column, "feature:"); ok {
+				record.Features = append(record.Features, Feature{Title: strings.TrimSpace(title), Values: []string{strings.TrimSpace(value)}})
+				continue
+			}
+
+			field, ok := aliases[normalizeKey(column)]
+			if !ok {
+				record.Features = append(record.Features, Feature{Title: column, Values: []string{strings.TrimSpace(value)}})
+				continue
+			}
+
+			// Çoklu değerler (resimler) | ya da ; ile ayrılabilir
+			if field == "images" {
+				for _, url := range strings.FieldsFunc(value, func(r rune) bool { return r == '|' || r == ';' }) {
+					record.add(field, url)
+				}
+				continue
+			}
+			record.add(field, value)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) == 0 {
+		return nil, ErrNoRecords
+	}
+	return records, nil
+}
+
+// ParseXML <property>, <immobilie> ya da <listing> elemanlarını kayıt olarak okur.
+// İç içe elemanlar yol olarak eşlenir (örn. location/latitude -> location_latitude);
+// önce tam yol, sonra sadece eleman adı alias tablosunda aranır. Eşlenemeyen elemanlar atlanır.
+func ParseXML(r io.Reader) ([]Record, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+
+	var (
+		records []Record
+		current *Record
+		path    []string
+		text    strings.Builder
+		leaf    bool
+	)
+
+	lookup := func(segments []string) (string, bool) {
+		if field, ok := aliases[normalizeKey(strings.Join(segments, "_"))]; ok {
+			return field, true
+		}
+		field, ok := aliases[normalizeKey(segments[len(segments)-1])]
+		return field, ok
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid XML: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if current == nil {
+				if recordElements[name] {
+					if len(records) >= MaxRows {
+						return nil, fmt.Errorf("file contains more than %d listings", MaxRows)
+					}
+					current = &Record{Row: len(records) + 1, Fields: map[string][]string{}}
+					path = path[:0]
+				}
+				continue
+			}
+
+			path = append(path, name)
+			text.Reset()
+			leaf = true
+
+			// Öznitelikler sadece tam yol ile eşlenir (örn. geo/land@iso_land -> geo_land_iso_land)
+			for _, attr := range t.Attr {
+				key := normalizeKey(strings.Join(path, "_") + "_" + attr.Name.Local)
+				if field, ok := aliases[key]; ok {
+					current.add(field, attr.Value)
+				}
+			}
+
+		case xml.CharData:
+			if current != nil && leaf {
+				text.Write(t)
+			}
+
+		case xml.EndElement:
+			if current == nil {
+				continue
+			}
+			if len(path) == 0 {
+				// Kayıt elemanı kapandı
+				records = append(records, *current)
+				current = nil
+				continue
+			}
+
+			if leaf {
+				if field, ok := lookup(path); ok {
+					if field == FieldFeatures {
+						current.Features = appendListFeature(current.Features, text.String())
+					} else {
+						current.add(field, text.String())
+					}
+				}
+			}
+			path = path[:len(path)-1]
+			leaf = false
+			text.Reset()
+		}
+	}
+
+	if len(records) == 0 {
+		return nil, ErrNoRecords
+	}
+	return records, nil
+}
+
+// appendListFeature XML özellik listesini tek bir "Features" özelliğinde toplar
+func appendListFeature(features []Feature, value string) []Feature {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return features
+	}
+	for i := range features {
+		if features[i].Title == "Features" {
+			features[i].Values = append(features[i].Values, value)
+			return features
+		}
+	}
+	return append(features, Feature{Title: "Features", Values: []string{value}})
+}
@@ -0,0 +1,95 @@
+// pkg/utils/location/geo.go
+package location
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+const EarthRadiusKm = 6371.0
+
+// ValidCoordinates enlem/boylamın geçerli aralıkta olup olmadığını kontrol eder
+func ValidCoordinates(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+func parseCoordinates(lat, lng string) (float64, float64, bool) {
+	la, err := strconv.ParseFloat(lat, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	lo, err := strconv.ParseFloat(lng, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return la, lo, true
+}
+
+// Centroid konum için yaklaşık merkez koordinatını döner.
+// Önce şehir, bulunamazsa eyalet/il, o da yoksa ülke merkezi kullanılır.
+// Eyalet kodları ülkeler arasında tekrarlandığı için şehir, ülkenin eyaletleri içinde aranır.
+func Centroid(countryCode, stateCode, city string) (float64, float64, bool) {
+	var matchedStates []State
+	if stateCode != "" {
+		for _, s := range states {
+			if s.CountryCode == countryCode && s.StateCode == stateCode {
+				matchedStates = append(matchedStates, s)
+			}
+		}
+	}
+
+	if city != "" {
+		for _, s := range matchedStates {
+			for _, c := range cities {
+				if c.StateID == s.ID && strings.EqualFold(c.Name, city) {
+					if lat, lng, ok := parseCoordinates(c.Latitude, c.Longitude); ok {
+						return lat, lng, true
+					}
+				}
+			}
+		}
+	}
+
+	for _, s := range matchedStates {
+		if lat, lng, ok := parseCoordinates(s.Latitude, s.Longitude); ok {
+			return lat, lng, true
+		}
+	}
+
+	for _, c := range countries {
+		if c.ISO2 == countryCode {
+			return parseCoordinates(c.Latitude, c.Longitude)
+		}
+	}
+
+	return 0, 0, false
+}
+
+// HaversineKm iki nokta arasındaki büyük daire mesafesini km cinsinden hesaplar
+func HaversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLng := (lng2 - lng1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return EarthRadiusKm * 2 * math.Asin(math.Sqrt(math.Min(1, a)))
+}
+
+// HaversineSQL verilen noktaya km cinsinden mesafeyi hesaplayan SQL ifadesi.
+// Parametreler sırasıyla: lat, lat, lng. Yuvarlama hatası ASIN'i tanım aralığı dışına
+// çıkarmasın diye argüman 1 ile sınırlanır (antipodal noktalar).
+func HaversineSQL(latColumn, lngColumn string) string {
+	return "(6371 * 2 * ASIN(LEAST(1, SQRT(" +
+		"POWER(SIN(RADIANS(" + latColumn + " - ?) / 2), 2) + " +
+		"COS(RADIANS(?)) * COS(RADIANS(" + latColumn + ")) * " +
+		"POWER(SIN(RADIANS(" + lngColumn + " - ?) / 2), 2)))))"
+}
+
+// BoundingBox bir nokta etrafında yarıçapı kapsayan kaba bir kutu döner,
+// indeks dostu ön filtre olarak kullanılır
+func BoundingBox(lat, lng, radiusKm float64) (minLat, minLng, maxLat, maxLng float64) {
+	dLat := radiusKm / EarthRadiusKm * 180 / math.Pi
+	dLng := dLat / math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	return math.Max(lat-dLat, -90), math.Max(lng-dLng, -180),
+		math.Min(lat+dLat, 90), math.Min(lng+dLng, 180)
+}
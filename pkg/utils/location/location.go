@@ -4,6 +4,7 @@ package location
 import (
 	"encoding/json"
 	"os"
+	"strings"
 )
 
 type Country struct {
@@ -16,6 +17,8 @@ type Country struct {
 	Currency     string `json:"currency"`
 	CurrencyName string `json:"currency_name"`
 	Region       string `json:"region"`
+	Latitude     string `json:"latitude"`
+	Longitude    string `json:"longitude"`
 }
 
 type State struct {
@@ -24,6 +27,8 @@ type State struct {
 	CountryID   uint   `json:"country_id"`
 	CountryCode string `json:"country_code"` // TR, US gibi
 	StateCode   string `json:"state_code"`   // 34, CA gibi
+	Latitude    string `json:"latitude"`
+	Longitude   string `json:"longitude"`
 }
 
 type City struct {
@@ -31,6 +36,25 @@ type City struct {
 	Name      string `json:"name"`
 	StateID   uint   `json:"state_id"`
 	StateCode string `json:"state_code"`
+	Latitude  string `json:"latitude"`
+	Longitude string `json:"longitude"`
+}
+
+// UnmarshalJSON veri dosyasındaki phone_code alanını PhoneCode'a okur
+func (c *Country) UnmarshalJSON(data []byte) error {
+	type plain Country
+	aux := struct {
+		*plain
+		PhoneCodeValue string `json:"phone_code"`
+	}{plain: (*plain)(c)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if c.PhoneCode == "" {
+		c.PhoneCode = aux.PhoneCodeValue
+	}
+	return nil
 }
 
 var (
@@ -93,3 +117,55 @@ func GetCitiesByState(stateCode string) []City {
 	}
 	return stateCities
 }
+
+// FindCountry ülkeyi ISO2, ISO3 kodu ya da adıyla bulur
+func FindCountry(value string) (Country, bool) {
+	value = strings.TrimSpace(value)
+	for _, country := range countries {
+		if strings.EqualFold(country.ISO2, value) ||
+			strings.EqualFold(country.ISO3, value) ||
+			strings.EqualFold(country.Name, value) {
+			return country, true
+		}
+	}
+	return Country{}, false
+}
+
+// CallingCode ülkenin telefon kodu (örn. TR için "90"); bilinmiyorsa boş döner
+func CallingCode(countryCode string) string {
+	if countryCode == "" {
+		return ""
+	}
+	country, ok := FindCountry(countryCode)
+	if !ok {
+		return ""
+	}
+	return strings.TrimPrefix(country.PhoneCode, "+")
+}
+
+// CallingCodeOf E.164 biçimindeki numaranın ülke telefon kodunu bulur (en uzun eşleşen kod)
+func CallingCodeOf(e164 string) string {
+	number := strings.TrimPrefix(e164, "+")
+	best := ""
+	for _, country := range countries {
+		code := strings.TrimPrefix(country.PhoneCode, "+")
+		if code != "" && len(code) > len(best) && strings.HasPrefix(number, code) {
+			best = code
+		}
+	}
+	return best
+}
+
+// FindState ülke içindeki eyaleti/ili koduyla ya da adıyla bulur
+func FindState(countryCode, value string) (State, bool) {
+	value = strings.TrimSpace(value)
+	for _, state := range states {
+		if state.CountryCode != countryCode {
+			continue
+		}
+		if strings.EqualFold(state.StateCode, value) || strings.EqualFold(state.Name, value) {
+			return state, true
+		}
+	}
+	return State{}, false
+}
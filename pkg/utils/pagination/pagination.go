@@ -0,0 +1,167 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor son dönen kaydın sıralama değeri ve ID'si. Null, sıralama değerinin NULL olduğunu belirtir.
+type Cursor struct {
+	Value string `json:"v"`
+	Null  bool   `json:"n,omitempty"`
+	ID    uint   `json:"id"`
+}
+
+// EncodeCursor cursor'ı URL güvenli bir stringe çevirir; value nil ise NULL değerli cursor üretir
+func EncodeCursor(value *string, id uint) string {
+	cursor := Cursor{Null: value == nil, ID: id}
+	if value != nil {
+		cursor.Value = *value
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor boş string için nil döner
+func DecodeCursor(raw string) (*Cursor, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// ParseLimit limit parametresini varsayılan ve üst sınırla birlikte okur
+func ParseLimit(raw string) int {
+	return parseLimit(raw, DefaultLimit)
+}
+
+func parseLimit(raw string, defaultLimit int) int {
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit <= 0 {
+		return defaultLimit
+	}
+	if limit > MaxLimit {
+		return MaxLimit
+	}
+	return limit
+}
+
+// Keyset bir sıralama ifadesi üzerinden cursor tabanlı sayfalama tanımlar.
+// Expr bir kolon ya da hesaplanmış ifade olabilir; IDColumn eşitlikleri kırmak için kullanılır.
+// NULL değerler en büyük kabul edilir: artan sıralamada sona, azalan sıralamada başa düşer.
+type Keyset struct {
+	Expr     string
+	Args     []interface{}
+	IDColumn string
+	Desc     bool
+}
+
+// OrderBy sıralama ifadesini ve ID'yi aynı yönde sıralayan clause'u döner
+func (k Keyset) OrderBy() clause.OrderBy {
+	dir, nulls := "ASC", "NULLS LAST"
+	if k.Desc {
+		dir, nulls = "DESC", "NULLS FIRST"
+	}
+	return clause.OrderBy{
+		Expression: clause.Expr{
+			SQL:                "(" + k.Expr + ") " + dir + " " + nulls + ", " + k.IDColumn + " " + dir,
+			Vars:               k.Args,
+			WithoutParentheses: true,
+		},
+	}
+}
+
+// Apply cursor koşulunu, sıralamayı ve limit+1 kaydı sorguya ekler.
+// Fazladan gelen kayıt bir sonraki sayfanın olup olmadığını anlamak içindir.
+func (k Keyset) Apply(db *gorm.DB, cursor *Cursor, limit int) *gorm.DB {
+	op := ">"
+	if k.Desc {
+		op = "<"
+	}
+
+	if cursor != nil {
+		expr := "(" + k.Expr + ")"
+		args := []interface{}{}
+		var condition string
+		switch {
+		case cursor.Null && k.Desc:
+			// NULL'lar başta; kalan NULL'lar ve NULL olmayan tüm değerler
+			condition = "(" + expr + " IS NOT NULL OR (" + expr + " IS NULL AND " + k.IDColumn + " " + op + " ?))"
+			args = append(args, k.Args...)
+			args = append(args, k.Args...)
+			args = append(args, cursor.ID)
+		case cursor.Null:
+			// NULL'lar sonda; sadece kalan NULL'lar
+			condition = "(" + expr + " IS NULL AND " + k.IDColumn + " " + op + " ?)"
+			args = append(args, k.Args...)
+			args = append(args, cursor.ID)
+		case k.Desc:
+			condition = "(" + expr + " " + op + " ? OR (" + expr + " = ? AND " + k.IDColumn + " " + op + " ?))"
+			args = append(args, k.Args...)
+			args = append(args, cursor.Value)
+			args = append(args, k.Args...)
+			args = append(args, cursor.Value, cursor.ID)
+		default:
+			condition = "(" + expr + " " + op + " ? OR " + expr + " IS NULL OR (" + expr + " = ? AND " + k.IDColumn + " " + op + " ?))"
+			args = append(args, k.Args...)
+			args = append(args, cursor.Value)
+			args = append(args, k.Args...)
+			args = append(args, k.Args...)
+			args = append(args, cursor.Value, cursor.ID)
+		}
+		db = db.Where(condition, args...)
+	}
+
+	return db.Clauses(k.OrderBy()).Limit(limit + 1)
+}
+
+// SelectRow ID'yi ve sıralama değerini text olarak seçer; ifadenin argümanları da bağlanır
+func (k Keyset) SelectRow(db *gorm.DB) *gorm.DB {
+	return db.Select(k.IDColumn+" AS id, ("+k.Expr+")::text AS sort_value", k.Args...)
+}
+
+// Row keyset sorgularında ID ve sıralama değerini taşır; SortValue NULL ise nil'dir
+type Row struct {
+	ID        uint
+	SortValue *string
+}
+
+// Trim limit+1 sonucu keser, bir sonraki sayfa varsa cursor'ı döner
+func Trim(rows []Row, limit int) ([]Row, string) {
+	if len(rows) <= limit {
+		return rows, ""
+	}
+	last := rows[limit-1]
+	return rows[:limit], EncodeCursor(last.SortValue, last.ID)
+}
+
+// IDs satırlardaki ID'leri sırasıyla döner
+func IDs(rows []Row) []uint {
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	return ids
+}
@@ -0,0 +1,246 @@
+package pagination
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	ModeCursor = "cursor"
+	ModePage   = "page"
+)
+
+// Options bir liste endpoint'inin izin verdiği sıralama ve alanları tanımlar
+type Options struct {
+	IDColumn     string            // Örn: "leads.id"
+	SortKeys     map[string]string // Public isim -> SQL kolonu
+	DefaultSort  string            // Örn: "-created_at" (- azalan sıralama)
+	Fields       []string          // fields parametresiyle seçilebilecek JSON alanları
+	DefaultLimit int
+}
+
+// Params istekten okunan sayfalama parametreleri
+type Params struct {
+	Mode   string
+	Limit  int
+	Page   int
+	Cursor *Cursor
+	Sort   string
+	Keyset Keyset
+	Fields []string
+}
+
+// Meta yanıtta dönen sayfalama bilgisi
+type Meta struct {
+	Mode       string `json:"mode"`
+	Sort       string `json:"sort"`
+	Limit      int    `json:"limit"`
+	Total      int64  `json:"total"`
+	Page       int    `json:"page,omitempty"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// ParamError hatalı sayfalama parametresi; izin verilen değerleri de taşır
+type ParamError struct {
+	Param   string
+	Message string
+	Allowed []string
+}
+
+func (e *ParamError) Error() string {
+	return e.Message
+}
+
+// Response hatayı API'nin standart hata formatına çevirir
+func (e *ParamError) Response() fiber.Map {
+	resp := fiber.Map{
+		"error": e.Message,
+		"param": e.Param,
+	}
+	if len(e.Allowed) > 0 {
+		resp["allowed"] = e.Allowed
+	}
+	return resp
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseSort "-created_at" gibi bir değeri whitelist üzerinden keyset'e çevirir
+func ParseSort(raw string, opts Options) (Keyset, error) {
+	name := strings.TrimPrefix(raw, "-")
+	column, ok := opts.SortKeys[name]
+	if !ok {
+		return Keyset{}, &ParamError{
+			Param:   "sort",
+			Message: fmt.Sprintf("Invalid sort field: %s", name),
+			Allowed: sortedKeys(opts.SortKeys),
+		}
+	}
+	return Keyset{
+		Expr:     column,
+		IDColumn: opts.IDColumn,
+		Desc:     strings.HasPrefix(raw, "-"),
+	}, nil
+}
+
+// Parse limit, page/cursor, sort ve fields parametrelerini okur.
+// page verilirse sayfa numarası modu, verilmezse cursor modu kullanılır.
+func Parse(c *fiber.Ctx, opts Options) (*Params, error) {
+	defaultLimit := opts.DefaultLimit
+	if defaultLimit == 0 {
+		defaultLimit = DefaultLimit
+	}
+
+	p := &Params{
+		Mode:  ModeCursor,
+		Limit: parseLimit(c.Query("limit"), defaultLimit),
+		Sort:  c.Query("sort", opts.DefaultSort),
+	}
+
+	keyset, err := ParseSort(p.Sort, opts)
+	if err != nil {
+		return nil, err
+	}
+	p.Keyset = keyset
+
+	if raw := c.Query("page"); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			return nil, &ParamError{Param: "page", Message: "page must be a positive integer"}
+		}
+		if c.Query("cursor") != "" {
+			return nil, &ParamError{Param: "page", Message: "page and cursor cannot be used together"}
+		}
+		p.Mode, p.Page = ModePage, page
+	} else {
+		cursor, err := DecodeCursor(c.Query("cursor"))
+		if err != nil {
+			return nil, &ParamError{Param: "cursor", Message: "Invalid cursor"}
+		}
+		p.Cursor = cursor
+	}
+
+	if raw := c.Query("fields"); raw != "" && len(opts.Fields) > 0 {
+		allowed := map[string]bool{}
+		for _, f := range opts.Fields {
+			allowed[f] = true
+		}
+		for _, f := range strings.Split(raw, ",") {
+			f = strings.TrimSpace(f)
+			if f == "" {
+				continue
+			}
+			if !allowed[f] {
+				return nil, &ParamError{
+					Param:   "fields",
+					Message: fmt.Sprintf("Invalid field: %s", f),
+					Allowed: opts.Fields,
+				}
+			}
+			p.Fields = append(p.Fields, f)
+		}
+	}
+
+	return p, nil
+}
+
+// SetSort sıralamayı sonradan (örn. filtre ifadesindeki sort: teriminden) değiştirir
+func (p *Params) SetSort(raw string, opts Options) error {
+	keyset, err := ParseSort(raw, opts)
+	if err != nil {
+		return err
+	}
+	p.Sort, p.Keyset = raw, keyset
+	return nil
+}
+
+// Paginate filtrelenmiş sorgu üzerinden toplam sayıyı ve sayfadaki ID'leri sırasıyla döner.
+// Kayıtların kendisi Ordered ile ayrıca yüklenir; böylece preload'lar sayfalamayı etkilemez.
+func (p *Params) Paginate(query *gorm.DB) ([]uint, Meta, error) {
+	base := query.Session(&gorm.Session{})
+	meta := Meta{Mode: p.Mode, Sort: p.Sort, Limit: p.Limit}
+
+	if err := base.Count(&meta.Total).Error; err != nil {
+		return nil, meta, err
+	}
+
+	if p.Mode == ModePage {
+		var ids []uint
+		if err := base.Clauses(p.Keyset.OrderBy()).
+			Offset((p.Page-1)*p.Limit).
+			Limit(p.Limit).
+			Pluck(p.Keyset.IDColumn, &ids).Error; err != nil {
+			return nil, meta, err
+		}
+		meta.Page = p.Page
+		meta.TotalPages = int((meta.Total + int64(p.Limit) - 1) / int64(p.Limit))
+		meta.HasMore = p.Page < meta.TotalPages
+		return ids, meta, nil
+	}
+
+	var rows []Row
+	if err := p.Keyset.Apply(
+		p.Keyset.SelectRow(base),
+		p.Cursor, p.Limit,
+	).Scan(&rows).Error; err != nil {
+		return nil, meta, err
+	}
+
+	rows, meta.NextCursor = Trim(rows, p.Limit)
+	meta.HasMore = meta.NextCursor != ""
+	return IDs(rows), meta, nil
+}
+
+// Ordered sayfadaki kayıtları Paginate ile aynı sırada yükleyecek sorguyu hazırlar
+func (p *Params) Ordered(db *gorm.DB, ids []uint) *gorm.DB {
+	return db.Where(p.Keyset.IDColumn+" IN ?", ids).Clauses(p.Keyset.OrderBy())
+}
+
+// Project fields parametresi verilmişse kayıtları sadece istenen JSON alanlarına indirger.
+// ID alanları her zaman korunur.
+func (p *Params) Project(items interface{}) (interface{}, error) {
+	if len(p.Fields) == 0 {
+		return items, nil
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, err
+	}
+
+	keep := map[string]bool{"ID": true, "id": true}
+	for _, f := range p.Fields {
+		keep[f] = true
+	}
+
+	projected := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		projected[i] = map[string]interface{}{}
+		for k, v := range row {
+			if keep[k] {
+				projected[i][k] = v
+			}
+		}
+	}
+	return projected, nil
+}
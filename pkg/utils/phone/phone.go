@@ -0,0 +1,91 @@
+// pkg/utils/phone/phone.go
+package phone
+
+import (
+	"strings"
+	"unicode"
+)
+
+// E.164 numaraları ülke kodu dahil en fazla 15 hanedir
+const (
+	minDigits = 8
+	maxDigits = 15
+)
+
+// trunkPrefixes yurtiçi ön eki 0 olmayan ülke kodları. Boş değer ön ek olmadığını gösterir;
+// örn. İtalya'da baştaki 0 numaranın parçasıdır ve uluslararası biçimde de kalır.
+var trunkPrefixes = map[string]string{
+	"7":   "8", // Rusya, Kazakistan
+	"30":  "",  // Yunanistan
+	"34":  "",  // İspanya
+	"39":  "",  // İtalya
+	"45":  "",  // Danimarka
+	"47":  "",  // Norveç
+	"48":  "",  // Polonya
+	"65":  "",  // Singapur
+	"351": "",  // Portekiz
+	"352": "",  // Lüksemburg
+	"354": "",  // İzlanda
+	"356": "",  // Malta
+	"357": "",  // Kıbrıs
+	"371": "",  // Letonya
+	"372": "",  // Estonya
+	"377": "",  // Monako
+	"378": "",  // San Marino
+	"420": "",  // Çekya
+	"421": "",  // Slovakya
+	"852": "",  // Hong Kong
+	"965": "",  // Kuveyt
+	"973": "",  // Bahreyn
+	"974": "",  // Katar
+}
+
+// trunkPrefix ülke kodu için yurtiçi aramalarda numaranın başına eklenen ön ek (varsayılan 0)
+func trunkPrefix(callingCode string) string {
+	if prefix, ok := trunkPrefixes[callingCode]; ok {
+		return prefix
+	}
+	return "0"
+}
+
+// NormalizeE164 numarayı +905321234567 biçimine çevirir. Ülke kodu olmadan girilen numaralarda
+// defaultCallingCode (örn. "90") kullanılır ve ülkenin yurtiçi ön eki (çoğunlukla 0) atılır.
+// Numara çözülemezse false döner.
+func NormalizeE164(raw, defaultCallingCode string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+
+	international := strings.HasPrefix(raw, "+")
+	var digits strings.Builder
+	for _, r := range raw {
+		if unicode.IsLetter(r) {
+			// "ext", "dahili" gibi eklerden sonrası numaraya dahil değil
+			break
+		}
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	number := digits.String()
+
+	switch {
+	case international:
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case defaultCallingCode != "":
+		if prefix := trunkPrefix(defaultCallingCode); prefix != "" {
+			number = strings.TrimPrefix(number, prefix)
+		}
+		// Kuzey Amerika numaralarında ülke kodu çoğunlukla başa yazılır (1 555 ...)
+		if defaultCallingCode == "1" && len(number) == 11 && strings.HasPrefix(number, "1") {
+			number = number[1:]
+		}
+		number = defaultCallingCode + number
+	default:
+		return "", false
+	}
+
+	if len(number) < minDigits || len(number) > maxDigits || number[0] == '0' {
+		return "", false
+	}
+	return "+" + number, true
+}
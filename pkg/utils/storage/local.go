@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// LocalRoutePrefix local driver'ın dosyaları sunduğu route
+	LocalRoutePrefix = "/storage"
+	// LocalPrivateRoutePrefix özel depolamadaki dosyaların sadece imzalı URL ile sunulduğu route
+	LocalPrivateRoutePrefix = "/storage-private"
+)
+
+// LocalStorage dosyaları diskte tutar; geliştirme ortamı ve bulut erişimi olmayan kurulumlar için.
+// Dosyalar API üzerinden LocalRoutePrefix altında sunulur.
+type LocalStorage struct {
+	root      string
+	publicURL string
+	secret    []byte
+}
+
+func NewLocalStorage(root, publicURL, secret string) (*LocalStorage, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create storage directory: %v", err)
+	}
+	return &LocalStorage{
+		root:      root,
+		publicURL: publicURL,
+		secret:    []byte(secret),
+	}, nil
+}
+
+// path anahtarı kök dizin altındaki dosya yoluna çevirir; dizin dışına çıkan anahtarlar reddedilir
+func (l *LocalStorage) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid storage key: %s", key)
+	}
+	return filepath.Join(l.root, filepath.FromSlash(cleaned)), nil
+}
+
+func (l *LocalStorage) Put(ctx context.Context, key, contentType string, data []byte) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return fmt.Errorf("could not create directory: %v", err)
+	}
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		return fmt.Errorf("could not write file: %v", err)
+	}
+	return nil
+}
+
+func (l *LocalStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Delete S3 ile aynı şekilde olmayan dosyalar için hata dönmez
+func (l *LocalStorage) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not delete file: %v", err)
+	}
+	return nil
+}
+
+func (l *LocalStorage) Stat(ctx context.Context, key string) (Object, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+	info, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Object{}, ErrNotFound
+	}
+	if err != nil {
+		return Object{}, err
+	}
+	return Object{Key: key, Size: info.Size(), LastModified: info.ModTime()}, nil
+}
+
+// sign method, anahtar ve (yüklemelerde) içerik türü ile boyutu imzalar
+func (l *LocalStorage) sign(method, key, contentType string, size, expires int64) string {
+	mac := hmac.New(sha256.New, l.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%d\n%d", method, key, contentType, size, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (l *LocalStorage) SignedURL(ctx context.Context, key, method string, expires time.Duration) (string, error) {
+	if method != http.MethodGet {
+		return "", fmt.Errorf("unsupported signed URL method: %s", method)
+	}
+	if _, err := l.path(key); err != nil {
+		return "", err
+	}
+
+	exp := time.Now().Add(expires).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(exp, 10))
+	query.Set("signature", l.sign(method, key, "", 0, exp))
+	return l.URL(key) + "?" + query.Encode(), nil
+}
+
+func (l *LocalStorage) SignedUploadURL(ctx context.Context, key, contentType string, size int64, expires time.Duration) (string, error) {
+	if _, err := l.path(key); err != nil {
+		return "", err
+	}
+
+	exp := time.Now().Add(expires).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(exp, 10))
+	query.Set("size", strconv.FormatInt(size, 10))
+	query.Set("signature", l.sign(http.MethodPut, key, contentType, size, exp))
+	return l.URL(key) + "?" + query.Encode(), nil
+}
+
+// verifyUpload imzalı yükleme URL'ini ve gönderilen içeriğin türü ile boyutunu doğrular
+func (l *LocalStorage) verifyUpload(c *fiber.Ctx, key string) bool {
+	exp, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	size, err := strconv.ParseInt(c.Query("size"), 10, 64)
+	if err != nil || int64(len(c.Body())) != size {
+		return false
+	}
+	expected := l.sign(http.MethodPut, key, c.Get(fiber.HeaderContentType), size, exp)
+	return hmac.Equal([]byte(c.Query("signature")), []byte(expected))
+}
+
+func (l *LocalStorage) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+
+	err := filepath.WalkDir(l.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(l.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			// Prefix ile ilgisi olmayan dizinlere girme
+			if key != "." && !strings.HasPrefix(key+"/", prefix) && !strings.HasPrefix(prefix, key+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{
+			Key:          key,
+			Size:         info.Size(),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not list storage objects: %v", err)
+	}
+	return objects, nil
+}
+
+func (l *LocalStorage) URL(key string) string {
+	return publicURL(l.publicURL, key)
+}
+
+func (l *LocalStorage) KeyFromURL(url string) (string, bool) {
+	return keyFromPublicURL(l.publicURL, url)
+}
+
+// ServeObject dosyaları herkese açık sunar (R2'nin CDN domaini gibi)
+func (l *LocalStorage) ServeObject(c *fiber.Ctx) error {
+	p, err := l.path(c.Params("*"))
+	if err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	if _, err := os.Stat(p); err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	return c.SendFile(p)
+}
+
+// ServeSignedObject dosyayı sadece süresi geçmemiş imzalı GET URL'i ile sunar (özel depolama için)
+func (l *LocalStorage) ServeSignedObject(c *fiber.Ctx) error {
+	key := c.Params("*")
+	exp, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+	expected := l.sign(http.MethodGet, key, "", 0, exp)
+	if !hmac.Equal([]byte(c.Query("signature")), []byte(expected)) {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+	return l.ServeObject(c)
+}
+
+// UploadObject imzalı PUT URL'lerine yapılan yüklemeleri kabul eder
+func (l *LocalStorage) UploadObject(c *fiber.Ctx) error {
+	key := c.Params("*")
+	if !l.verifyUpload(c, key) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Invalid or expired signature",
+		})
+	}
+
+	if err := l.Put(c.Context(), key, c.Get(fiber.HeaderContentType), c.Body()); err != nil {
+		log.Printf("Error storing uploaded file %s: %v", key, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not store file",
+		})
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
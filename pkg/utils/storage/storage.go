@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"estepage_backend/pkg/config"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Object depolamadaki bir dosyanın özeti
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Storage dosya depolama arayüzü. Anahtarlar "users/<username>/..." biçiminde, / ile ayrılmış yollardır.
+type Storage interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	// Stat dosyayı okumadan boyutunu döner; dosya yoksa ErrNotFound
+	Stat(ctx context.Context, key string) (Object, error)
+	// SignedURL süreli imzalı bir indirme (GET) URL'i üretir
+	SignedURL(ctx context.Context, key, method string, expires time.Duration) (string, error)
+	// SignedUploadURL süreli imzalı bir PUT URL'i üretir; yükleme tam olarak size byte
+	// ve contentType ile yapılmalıdır
+	SignedUploadURL(ctx context.Context, key, contentType string, size int64, expires time.Duration) (string, error)
+	List(ctx context.Context, prefix string) ([]Object, error)
+
+	// URL dosyanın herkese açık adresini döner
+	URL(key string) string
+	// KeyFromURL herkese açık adresten anahtarı çıkarır; URL bu depolamaya ait değilse false döner
+	KeyFromURL(url string) (string, bool)
+}
+
+var GlobalStorage Storage
+
+// PrivateStorage herkese açık sunulmayan dosyalar (örn. filigransız orijinaller, gizli ekler)
+// için depolama. İstemcilere sadece süreli imzalı URL'ler verilir.
+var PrivateStorage Storage
+
+// Init config'deki driver'a göre GlobalStorage ve PrivateStorage'ı oluşturur
+func Init(cfg config.StorageConfig) error {
+	var (
+		s       Storage
+		private Storage
+		err     error
+	)
+
+	switch cfg.Driver {
+	case "r2", "s3", "":
+		if cfg.PrivateBucket == "" || cfg.PrivateBucket == cfg.Bucket {
+			return fmt.Errorf("STORAGE_PRIVATE_BUCKET must be set to a separate, non-public bucket")
+		}
+		if s, err = NewS3Storage(cfg); err != nil {
+			return err
+		}
+		privateCfg := cfg
+		privateCfg.Bucket = cfg.PrivateBucket
+		private, err = NewS3Storage(privateCfg)
+	case "local":
+		if cfg.SigningSecret == "" {
+			return fmt.Errorf("STORAGE_SIGNING_SECRET must be set for the local driver")
+		}
+		if cfg.PublicURL == "" {
+			port := os.Getenv("PORT")
+			if port == "" {
+				port = "3000"
+			}
+			cfg.PublicURL = fmt.Sprintf("http://localhost:%s%s", port, LocalRoutePrefix)
+		}
+		if s, err = NewLocalStorage(cfg.LocalPath, cfg.PublicURL, cfg.SigningSecret); err != nil {
+			return err
+		}
+		// Sunulan dizinin dışında olmalı; dosyaları sadece imzalı URL'lerle
+		// LocalPrivateRoutePrefix altında sunulur
+		if isSubdir(cfg.LocalPath, cfg.PrivateLocalPath) {
+			return fmt.Errorf("STORAGE_PRIVATE_LOCAL_PATH must be outside STORAGE_LOCAL_PATH")
+		}
+		privateURL := strings.TrimSuffix(strings.TrimSuffix(cfg.PublicURL, "/"), LocalRoutePrefix) + LocalPrivateRoutePrefix
+		private, err = NewLocalStorage(cfg.PrivateLocalPath, privateURL, cfg.SigningSecret)
+	default:
+		return fmt.Errorf("unknown storage driver: %s", cfg.Driver)
+	}
+	if err != nil {
+		return err
+	}
+
+	GlobalStorage = s
+	PrivateStorage = private
+	return nil
+}
+
+// isSubdir dir, root'un kendisi ya da altında mı
+func isSubdir(root, dir string) bool {
+	rootAbs, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	dirAbs, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(rootAbs, dirAbs)
+	return err == nil && (rel == "." || !strings.HasPrefix(rel, ".."))
+}
+
+func publicURL(base, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
+
+func keyFromPublicURL(base, url string) (string, bool) {
+	prefix := strings.TrimSuffix(base, "/") + "/"
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	key := strings.TrimPrefix(url, prefix)
+	if i := strings.IndexAny(key, "?#"); i >= 0 {
+		key = key[:i]
+	}
+	return key, key != ""
+}
@@ -0,0 +1,42 @@
+package validation
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+var (
+	ErrAttachmentType = errors.New("invalid file type. Allowed types: PDF, JPG, PNG, WEBP")
+	ErrPDFRequired    = errors.New("documents must be PDF files")
+	ErrInvalidLink    = errors.New("link must be a valid https URL")
+)
+
+// IsPDF dosyanın PDF imzasıyla başlayıp başlamadığını kontrol eder
+func IsPDF(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("%PDF-"))
+}
+
+// DetectAttachmentType dosya içeriğine (magic bytes) göre content type döner; PDF ve resimler kabul edilir
+func DetectAttachmentType(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", ErrFileRequired
+	}
+	if IsPDF(data) {
+		return "application/pdf", nil
+	}
+	if contentType := http.DetectContentType(data); allowedImageContentTypes[contentType] {
+		return contentType, nil
+	}
+	return "", ErrAttachmentType
+}
+
+// ValidateLink video ve sanal tur linklerini kontrol eder
+func ValidateLink(link string) error {
+	u, err := url.Parse(link)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return ErrInvalidLink
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package validation
 import (
 	"errors"
 	"mime/multipart"
+	"net/http"
 	"path/filepath"
 	"strings"
 )
@@ -11,6 +12,7 @@ var (
 	ErrFileSize     = errors.New("file size exceeds limit of 10MB")
 	ErrFileType     = errors.New("invalid file type. Allowed types: JPG, PNG, WEBP")
 	ErrFileRequired = errors.New("no file provided")
+	ErrFileContent  = errors.New("file content is not a valid JPG, PNG or WEBP image")
 )
 
 const MaxImageSize = 10 * 1024 * 1024 // 10MB
@@ -22,6 +24,12 @@ var AllowedImageTypes = map[string]bool{
 	".webp": true,
 }
 
+var allowedImageContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
 func ValidateImage(file *multipart.FileHeader) error {
 	if file == nil {
 		return ErrFileRequired
@@ -40,3 +48,20 @@ func ValidateImage(file *multipart.FileHeader) error {
 
 	return nil
 }
+
+// ValidateImageData doğrudan yüklenen dosyaları boyut ve magic byte'lara göre kontrol eder
+func ValidateImageData(data []byte) error {
+	if len(data) == 0 {
+		return ErrFileRequired
+	}
+
+	if len(data) > MaxImageSize {
+		return ErrFileSize
+	}
+
+	if !allowedImageContentTypes[http.DetectContentType(data)] {
+		return ErrFileContent
+	}
+
+	return nil
+}